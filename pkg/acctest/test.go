@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
 	"strings"
@@ -219,6 +220,50 @@ func VerifyDeleted(id string, check CheckFun) func(*terraform.State) error {
 	}
 }
 
+// ConfiguredProvider returns a new provider configured from ARTIFACTORY_URL and ARTIFACTORY_ACCESS_TOKEN, or their
+// JFROG_ variants, for tests calling the resources or the API directly. Unlike PreCheck, it doesn't change the
+// configuration of the instance.
+func ConfiguredProvider(t *testing.T) *schema.Provider {
+	p := provider.Provider()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"url":          GetArtifactoryUrl(t),
+		"access_token": GetEnvVarWithFallback(t, "ARTIFACTORY_ACCESS_TOKEN", "JFROG_ACCESS_TOKEN"),
+	}))
+	if diags.HasError() {
+		t.Fatalf("failed to configure provider: %v", diags)
+	}
+	return p
+}
+
+// CheckRepoDestroy verifies through the API that the repositories of the given resources no longer exist after
+// destroy. Unlike VerifyDeleted, it doesn't depend on the shared Provider configured by PreCheck.
+func CheckRepoDestroy(t *testing.T, fqrns ...string) func(*terraform.State) error {
+	return func(s *terraform.State) error {
+		restyClient := ConfiguredProvider(t).Meta().(*resty.Client)
+
+		var remaining []string
+		for _, fqrn := range fqrns {
+			rs, ok := s.RootModule().Resources[fqrn]
+			if !ok {
+				return fmt.Errorf("error: Resource id [%s] not found", fqrn)
+			}
+
+			resp, err := CheckRepo(rs.Primary.ID, restyClient.R())
+			if err == nil {
+				remaining = append(remaining, rs.Primary.ID)
+				continue
+			}
+			if resp == nil || (resp.StatusCode() != http.StatusNotFound && resp.StatusCode() != http.StatusBadRequest) {
+				return err
+			}
+		}
+		if len(remaining) > 0 {
+			return fmt.Errorf("error: %s still exists", strings.Join(remaining, ", "))
+		}
+		return nil
+	}
+}
+
 func CheckRepo(id string, request *resty.Request) (*resty.Response, error) {
 	return repository.CheckRepo(id, request.AddRetryCondition(client.NeverRetry))
 }
@@ -379,6 +424,19 @@ func GetTestResty(t *testing.T) *resty.Client {
 	return restyClient
 }
 
+// NewMockClient returns a client talking to an in-process server backed by handler, for unit tests that need canned
+// Artifactory responses. Retries are disabled so error paths don't back off.
+func NewMockClient(t *testing.T, handler http.Handler) *resty.Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	restyClient, err := client.Build(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return restyClient.SetRetryCount(0)
+}
+
 func CompositeCheckDestroy(funcs ...func(state *terraform.State) error) func(state *terraform.State) error {
 	return func(state *terraform.State) error {
 		var errors []error
@@ -0,0 +1,79 @@
+package datasource
+
+import (
+	"context"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-shared/util"
+)
+
+func ArtifactoryProviderConfig() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceProviderConfigRead,
+
+		Description: "Exposes the non-sensitive effective settings of the provider, for debugging purposes.",
+
+		Schema: map[string]*schema.Schema{
+			"url": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Base URL the provider sends requests to.",
+			},
+			"version": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Artifactory version detected from the instance.",
+			},
+			"revision": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Artifactory revision detected from the instance.",
+			},
+			"edition": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "License type of the instance, e.g. 'Enterprise'. Empty when the credentials can't read the license.",
+			},
+		},
+	}
+}
+
+type ArtifactoryVersion struct {
+	Version  string `json:"version"`
+	Revision string `json:"revision"`
+}
+
+func dataSourceProviderConfigRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	restyClient := m.(*resty.Client)
+
+	version := ArtifactoryVersion{}
+	_, err := restyClient.R().SetResult(&version).Get("artifactory/api/system/version")
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// reading the license requires admin permissions, which the configured credentials may not have. It's already read
+	// when the provider checks the license.
+	edition, err := repository.GetCachedLicenseType(restyClient)
+	if err != nil {
+		tflog.Warn(ctx, "failed to read the license, edition is left empty", map[string]interface{}{"error": err.Error()})
+	}
+
+	d.SetId(restyClient.BaseURL)
+
+	setValue := util.MkLens(d)
+	setValue("url", restyClient.BaseURL)
+	setValue("version", version.Version)
+	setValue("revision", version.Revision)
+	errors := setValue("edition", edition)
+
+	if errors != nil && len(errors) > 0 {
+		return diag.Errorf("failed to pack provider config %q", errors)
+	}
+
+	return nil
+}
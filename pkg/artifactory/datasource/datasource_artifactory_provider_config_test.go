@@ -0,0 +1,68 @@
+package datasource_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/datasource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProviderConfigReadsDetectedVersion(t *testing.T) {
+	testCases := map[string]struct {
+		licenseStatus   int
+		license         string
+		expectedEdition string
+	}{
+		"single license": {http.StatusOK, `{"type":"Enterprise Plus"}`, "Enterprise Plus"},
+		"HA licenses":    {http.StatusOK, `{"licenses":[{"type":"Enterprise"}]}`, "Enterprise"},
+		"not an admin":   {http.StatusForbidden, `{"errors":[{"status":403}]}`, ""},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case "/artifactory/api/system/version":
+					_, _ = w.Write([]byte(`{"version":"7.38.8","revision":"73808900"}`))
+				case "/artifactory/api/system/license":
+					w.WriteHeader(testCase.licenseStatus)
+					_, _ = w.Write([]byte(testCase.license))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+
+			dataSource := datasource.ArtifactoryProviderConfig()
+			d := schema.TestResourceDataRaw(t, dataSource.Schema, map[string]interface{}{})
+			diags := dataSource.ReadContext(context.Background(), d, restyClient)
+
+			assert.False(t, diags.HasError(), "unexpected error: %v", diags)
+			assert.Equal(t, "7.38.8", d.Get("version"))
+			assert.Equal(t, "73808900", d.Get("revision"))
+			assert.Equal(t, testCase.expectedEdition, d.Get("edition"))
+			assert.Equal(t, restyClient.BaseURL, d.Get("url"))
+		})
+	}
+}
+
+func TestAccDataSourceProviderConfig(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { acctest.PreCheck(t) },
+		ProviderFactories: acctest.ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `data "artifactory_provider_config" "config" {}`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.artifactory_provider_config.config", "version"),
+					resource.TestCheckResourceAttrSet("data.artifactory_provider_config.config", "url"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,87 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+)
+
+func ArtifactoryRepositoryConfigDrift() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceRepositoryConfigDriftRead,
+
+		Description: "Compares the configuration of a repository against a golden JSON document and lists the fields that differ.",
+
+		Schema: map[string]*schema.Schema{
+			"key": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: repository.RepoKeyValidator,
+				Description:  "The key of the repository to compare.",
+			},
+			"golden_json": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringIsJSON),
+				Description:      "The expected repository configuration as a JSON object, in the format of the repository configuration API. Only the fields it contains are compared.",
+			},
+			"drift": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"field": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"expected": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"actual": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceRepositoryConfigDriftRead(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	key := d.Get("key").(string)
+
+	var golden map[string]interface{}
+	if err := json.Unmarshal([]byte(d.Get("golden_json").(string)), &golden); err != nil {
+		return diag.Errorf("golden_json must be a JSON object: %s", err)
+	}
+
+	var actual map[string]interface{}
+	_, err := m.(*resty.Client).R().SetResult(&actual).Get(repository.RepositoriesEndpoint + key)
+	if err != nil {
+		return diag.Errorf("failed to read configuration of repository %s: %s", key, err)
+	}
+
+	drift := []interface{}{}
+	for _, field := range repository.DiffConfig(golden, actual) {
+		drift = append(drift, map[string]interface{}{
+			"field":    field.Field,
+			"expected": field.Expected,
+			"actual":   field.Actual,
+		})
+	}
+
+	d.SetId(fmt.Sprintf("repository-config-drift-%s", key))
+	if err := d.Set("drift", drift); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package datasource_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/datasource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryConfigDrift(t *testing.T) {
+	restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/artifactory/api/repositories/npm-virtual", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"key": "npm-virtual",
+			"rclass": "virtual",
+			"packageType": "npm",
+			"description": "changed out-of-band",
+			"repositories": ["npm-remote", "npm-local"],
+			"externalDependencies": {"enabled": true, "patterns": ["**"]},
+			"artifactoryRequestsCanRetrieveRemoteArtifacts": false
+		}`))
+	}))
+
+	dataSource := datasource.ArtifactoryRepositoryConfigDrift()
+	d := schema.TestResourceDataRaw(t, dataSource.Schema, map[string]interface{}{
+		"key": "npm-virtual",
+		"golden_json": `{
+			"packageType": "npm",
+			"description": "managed by the platform team",
+			"repositories": ["npm-local", "npm-remote"],
+			"externalDependencies": {"enabled": false, "patterns": ["**"]},
+			"defaultDeploymentRepo": "npm-local"
+		}`,
+	})
+	diags := dataSource.ReadContext(context.Background(), d, restyClient)
+
+	assert.False(t, diags.HasError(), "unexpected error: %v", diags)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"field": "defaultDeploymentRepo", "expected": `"npm-local"`, "actual": ""},
+		map[string]interface{}{"field": "description", "expected": `"managed by the platform team"`, "actual": `"changed out-of-band"`},
+		map[string]interface{}{"field": "externalDependencies.enabled", "expected": "false", "actual": "true"},
+		map[string]interface{}{"field": "repositories", "expected": `["npm-local","npm-remote"]`, "actual": `["npm-remote","npm-local"]`},
+	}, d.Get("drift"))
+}
@@ -0,0 +1,100 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+)
+
+func ArtifactoryVirtualRepositories() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceVirtualRepositoriesRead,
+
+		Description: "Lists the virtual repositories with the number of member repositories each aggregates.",
+
+		Schema: map[string]*schema.Schema{
+			"package_type": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only list virtual repositories of this package type. Lists all virtual repositories when unset.",
+			},
+			"project_key": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: repository.ValidateProjectKey,
+				Description:      "Only list virtual repositories assigned to this project. Lists the repositories of all projects when unset.",
+			},
+			"keys": {
+				Type:        schema.TypeList,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Computed:    true,
+				Description: "The keys of the listed virtual repositories, in the order of `repositories`.",
+			},
+			"repositories": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"key": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"package_type": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"description": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"url": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"member_count": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceVirtualRepositoriesRead(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	packageType := d.Get("package_type").(string)
+	projectKey := d.Get("project_key").(string)
+
+	summaries, err := repository.ListVirtualRepositories(m.(*resty.Client), packageType, projectKey)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	repositories := make([]interface{}, 0, len(summaries))
+	keys := make([]string, 0, len(summaries))
+	for _, summary := range summaries {
+		repositories = append(repositories, map[string]interface{}{
+			"key":          summary.Key,
+			"package_type": summary.PackageType,
+			"description":  summary.Description,
+			"url":          summary.Url,
+			"member_count": len(summary.Repositories),
+		})
+		keys = append(keys, summary.Key)
+	}
+
+	d.SetId(fmt.Sprintf("virtual-repositories-%s-%s", packageType, projectKey))
+	if err := d.Set("repositories", repositories); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("keys", keys); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
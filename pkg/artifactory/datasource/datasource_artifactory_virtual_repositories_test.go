@@ -0,0 +1,141 @@
+package datasource_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/datasource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVirtualRepositoriesMemberCounts(t *testing.T) {
+	configs := map[string]string{
+		"npm-virtual":   `{"key":"npm-virtual","packageType":"npm","repositories":["npm-local","npm-remote"]}`,
+		"maven-virtual": `{"key":"maven-virtual","packageType":"maven","repositories":[]}`,
+	}
+	restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/artifactory/api/repositories" {
+			assert.Equal(t, "virtual", r.URL.Query().Get("type"))
+			_, _ = w.Write([]byte(`[{"key":"npm-virtual","packageType":"npm"},{"key":"maven-virtual","packageType":"maven"}]`))
+			return
+		}
+		config, ok := configs[strings.TrimPrefix(r.URL.Path, "/artifactory/api/repositories/")]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(config))
+	}))
+
+	dataSource := datasource.ArtifactoryVirtualRepositories()
+	d := schema.TestResourceDataRaw(t, dataSource.Schema, map[string]interface{}{})
+	diags := dataSource.ReadContext(context.Background(), d, restyClient)
+
+	assert.False(t, diags.HasError(), "unexpected error: %v", diags)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"key": "npm-virtual", "package_type": "npm", "description": "", "url": "", "member_count": 2},
+		map[string]interface{}{"key": "maven-virtual", "package_type": "maven", "description": "", "url": "", "member_count": 0},
+	}, d.Get("repositories"))
+}
+
+func TestVirtualRepositoriesFilters(t *testing.T) {
+	testCases := map[string]struct {
+		config        map[string]interface{}
+		expectedQuery map[string]string
+		expectedKeys  []interface{}
+	}{
+		"no filter":    {map[string]interface{}{}, map[string]string{"type": "virtual", "packageType": "", "project": ""}, []interface{}{"npm-virtual", "proj-npm-virtual", "proj-maven-virtual"}},
+		"package type": {map[string]interface{}{"package_type": "npm"}, map[string]string{"type": "virtual", "packageType": "npm", "project": ""}, []interface{}{"npm-virtual", "proj-npm-virtual"}},
+		"project":      {map[string]interface{}{"project_key": "proj"}, map[string]string{"type": "virtual", "packageType": "", "project": "proj"}, []interface{}{"proj-npm-virtual", "proj-maven-virtual"}},
+		"both":         {map[string]interface{}{"package_type": "npm", "project_key": "proj"}, map[string]string{"type": "virtual", "packageType": "npm", "project": "proj"}, []interface{}{"proj-npm-virtual"}},
+	}
+	listed := []map[string]string{
+		{"key": "npm-virtual", "packageType": "npm", "project": ""},
+		{"key": "proj-npm-virtual", "packageType": "npm", "project": "proj"},
+		{"key": "proj-maven-virtual", "packageType": "maven", "project": "proj"},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if r.URL.Path != "/artifactory/api/repositories" {
+					_, _ = w.Write([]byte(`{"repositories":["member"]}`))
+					return
+				}
+				query := r.URL.Query()
+				for param, expected := range testCase.expectedQuery {
+					assert.Equal(t, expected, query.Get(param), "query parameter %s", param)
+				}
+				// the mock filters like Artifactory does, the list isn't paginated
+				var summaries []string
+				for _, repo := range listed {
+					if (query.Get("packageType") == "" || query.Get("packageType") == repo["packageType"]) &&
+						(query.Get("project") == "" || query.Get("project") == repo["project"]) {
+						summaries = append(summaries, fmt.Sprintf(`{"key":%q,"type":"VIRTUAL","packageType":%q,"description":"%s repository","url":"https://example.com/artifactory/%s"}`,
+							repo["key"], repo["packageType"], repo["key"], repo["key"]))
+					}
+				}
+				_, _ = w.Write([]byte("[" + strings.Join(summaries, ",") + "]"))
+			}))
+
+			dataSource := datasource.ArtifactoryVirtualRepositories()
+			d := schema.TestResourceDataRaw(t, dataSource.Schema, testCase.config)
+			diags := dataSource.ReadContext(context.Background(), d, restyClient)
+
+			assert.False(t, diags.HasError(), "unexpected error: %v", diags)
+			assert.Equal(t, testCase.expectedKeys, d.Get("keys"))
+			assert.Equal(t, testCase.expectedKeys[0].(string)+" repository", d.Get("repositories.0.description"))
+			assert.Equal(t, "https://example.com/artifactory/"+testCase.expectedKeys[0].(string), d.Get("repositories.0.url"))
+		})
+	}
+}
+
+func TestAccDataSourceVirtualRepositories(t *testing.T) {
+	_, _, name := acctest.MkNames("virtual-npm-repo", "artifactory_virtual_npm_repository")
+	config := acctest.ExecuteTemplate("TestAccDataSourceVirtualRepositories", `
+		resource "artifactory_local_npm_repository" "{{ .name }}-local" {
+			key = "{{ .name }}-local"
+		}
+
+		resource "artifactory_remote_npm_repository" "{{ .name }}-remote" {
+			key = "{{ .name }}-remote"
+			url = "https://registry.npmjs.org/"
+		}
+
+		resource "artifactory_virtual_npm_repository" "{{ .name }}" {
+			key          = "{{ .name }}"
+			repositories = [
+				artifactory_local_npm_repository.{{ .name }}-local.key,
+				artifactory_remote_npm_repository.{{ .name }}-remote.key,
+			]
+		}
+
+		data "artifactory_virtual_repositories" "npm" {
+			package_type = "npm"
+			depends_on   = [artifactory_virtual_npm_repository.{{ .name }}]
+		}
+	`, map[string]interface{}{"name": name})
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { acctest.PreCheck(t) },
+		ProviderFactories: acctest.ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.TestCheckTypeSetElemNestedAttrs("data.artifactory_virtual_repositories.npm", "repositories.*", map[string]string{
+					"key":          name,
+					"package_type": "npm",
+					"member_count": "2",
+				}),
+			},
+		},
+	})
+}
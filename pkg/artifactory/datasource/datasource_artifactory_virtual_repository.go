@@ -0,0 +1,69 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+)
+
+func ArtifactoryVirtualRepository() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceVirtualRepositoryRead,
+
+		Description: "Reads the configuration document of a virtual repository as stored by Artifactory, e.g. to back it up or audit it.",
+
+		Schema: map[string]*schema.Schema{
+			"key": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: repository.RepoKeyValidator,
+				Description:  "The key of the virtual repository.",
+			},
+			"package_type": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The package type of the virtual repository.",
+			},
+			"config_json": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The full configuration of the virtual repository returned by Artifactory, as a JSON object with sorted fields.",
+			},
+		},
+	}
+}
+
+func dataSourceVirtualRepositoryRead(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	key := d.Get("key").(string)
+
+	var config map[string]interface{}
+	_, err := m.(*resty.Client).R().SetResult(&config).Get(repository.RepositoriesEndpoint + key)
+	if err != nil {
+		return diag.Errorf("failed to read configuration of repository %s: %s", key, err)
+	}
+	// only virtual repositories, the configuration of remote repositories has credentials
+	if rclass, _ := config["rclass"].(string); rclass != "virtual" {
+		return diag.Errorf("repository %s is a %s repository, not a virtual repository", key, rclass)
+	}
+
+	// maps are encoded with sorted keys, the document doesn't change unless the configuration does
+	encoded, err := json.Marshal(config)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(key)
+	packageType, _ := config["packageType"].(string)
+	if err := d.Set("package_type", packageType); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("config_json", string(encoded)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
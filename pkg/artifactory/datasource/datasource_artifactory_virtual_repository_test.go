@@ -0,0 +1,59 @@
+package datasource_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/datasource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVirtualRepositoryConfigJSON(t *testing.T) {
+	restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/artifactory/api/repositories/npm-virtual":
+			_, _ = w.Write([]byte(`{
+				"key": "npm-virtual",
+				"rclass": "virtual",
+				"packageType": "npm",
+				"repositories": ["npm-local", "npm-remote"],
+				"externalDependencies": {"enabled": true, "patterns": ["**"]},
+				"virtualRetrievalCachePeriodSecs": 7200
+			}`))
+		case "/artifactory/api/repositories/npm-remote":
+			_, _ = w.Write([]byte(`{"key":"npm-remote","rclass":"remote","packageType":"npm","password":"secret"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	dataSource := datasource.ArtifactoryVirtualRepository()
+
+	d := schema.TestResourceDataRaw(t, dataSource.Schema, map[string]interface{}{"key": "npm-virtual"})
+	diags := dataSource.ReadContext(context.Background(), d, restyClient)
+	assert.False(t, diags.HasError(), "unexpected error: %v", diags)
+	assert.Equal(t, "npm-virtual", d.Id())
+	assert.Equal(t, "npm", d.Get("package_type"))
+
+	var config map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(d.Get("config_json").(string)), &config), "config_json must be valid JSON")
+	assert.Equal(t, map[string]interface{}{
+		"key":                             "npm-virtual",
+		"rclass":                          "virtual",
+		"packageType":                     "npm",
+		"repositories":                    []interface{}{"npm-local", "npm-remote"},
+		"externalDependencies":            map[string]interface{}{"enabled": true, "patterns": []interface{}{"**"}},
+		"virtualRetrievalCachePeriodSecs": float64(7200),
+	}, config)
+
+	// the configuration of other repositories may have credentials
+	d = schema.TestResourceDataRaw(t, dataSource.Schema, map[string]interface{}{"key": "npm-remote"})
+	diags = dataSource.ReadContext(context.Background(), d, restyClient)
+	assert.True(t, diags.HasError() && strings.Contains(diags[0].Summary, "npm-remote is a remote repository"), "expected the remote repository to be refused, got %v", diags)
+	assert.Empty(t, d.Get("config_json"))
+}
@@ -0,0 +1,42 @@
+package provider
+
+import (
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// limitedTransport bounds the number of requests in flight through the wrapped transport. Every attempt holds a slot
+// only while it is on the wire, so resty retries and their backoff don't starve other requests.
+type limitedTransport struct {
+	transport http.RoundTripper
+	slots     chan struct{}
+}
+
+func (t *limitedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	select {
+	case t.slots <- struct{}{}:
+	case <-request.Context().Done():
+		return nil, request.Context().Err()
+	}
+	defer func() { <-t.slots }()
+
+	return t.transport.RoundTrip(request)
+}
+
+// LimitConcurrentRequests makes the client send at most maxConcurrentRequests requests at a time, shared by all the
+// resources using it. 0 leaves the client unlimited.
+func LimitConcurrentRequests(restyClient *resty.Client, maxConcurrentRequests int) *resty.Client {
+	if maxConcurrentRequests <= 0 {
+		return restyClient
+	}
+
+	transport := restyClient.GetClient().Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return restyClient.SetTransport(&limitedTransport{
+		transport: transport,
+		slots:     make(chan struct{}, maxConcurrentRequests),
+	})
+}
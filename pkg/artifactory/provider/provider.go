@@ -3,7 +3,10 @@ package provider
 import (
 	"context"
 	"fmt"
+	"math"
+	"strings"
 
+	"github.com/go-resty/resty/v2"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
@@ -29,6 +32,13 @@ var productId = "terraform-provider-artifactory/" + Version
 // Provider Artifactory provider that supports configuration via Access Token
 // Supported resources are repos, users, groups, replications, and permissions
 func Provider() *schema.Provider {
+	return ProviderWithMiddlewares()
+}
+
+// ProviderWithMiddlewares is the Provider wrapping the transport of its Artifactory client with middlewares, the first
+// one being the outermost. A custom build of the provider can serve it, e.g. with RecordTimings pushing to a metrics
+// backend.
+func ProviderWithMiddlewares(middlewares ...TransportMiddleware) *schema.Provider {
 	resourceMap := map[string]*schema.Resource{
 		"artifactory_keypair":                     security.ResourceArtifactoryKeyPair(),
 		"artifactory_local_nuget_repository":      local.ResourceArtifactoryLocalNugetRepository(),
@@ -58,6 +68,18 @@ func Provider() *schema.Provider {
 		"artifactory_virtual_go_repository":       virtual.ResourceArtifactoryVirtualGoRepository(),
 		"artifactory_virtual_rpm_repository":      virtual.ResourceArtifactoryVirtualRpmRepository(),
 		"artifactory_virtual_helm_repository":     virtual.ResourceArtifactoryVirtualHelmRepository(),
+		"artifactory_virtual_conan_repository":    virtual.ResourceArtifactoryVirtualConanRepository(),
+		"artifactory_virtual_gems_repository":     virtual.ResourceArtifactoryVirtualGemsRepository(),
+		"artifactory_virtual_pypi_repository":     virtual.ResourceArtifactoryVirtualPypiRepository(),
+		"artifactory_virtual_cran_repository":     virtual.ResourceArtifactoryVirtualCranRepository(),
+		"artifactory_virtual_chef_repository":     virtual.ResourceArtifactoryVirtualChefRepository(),
+		"artifactory_virtual_p2_repository":       virtual.ResourceArtifactoryVirtualP2Repository(),
+		"artifactory_virtual_puppet_repository":   virtual.ResourceArtifactoryVirtualPuppetRepository(),
+		"artifactory_virtual_pub_repository":      virtual.ResourceArtifactoryVirtualPubRepository(),
+		"artifactory_virtual_gitlfs_repository":   virtual.ResourceArtifactoryVirtualGitlfsRepository(),
+		"artifactory_virtual_npm_repository":      virtual.ResourceArtifactoryVirtualNpmRepository(),
+		"artifactory_virtual_composer_repository": virtual.ResourceArtifactoryVirtualComposerRepository(),
+		"artifactory_virtual_docker_repository":   virtual.ResourceArtifactoryVirtualDockerRepository(),
 		"artifactory_group":                       security.ResourceArtifactoryGroup(),
 		"artifactory_user":                        user.ResourceArtifactoryUser(),
 		"artifactory_unmanaged_user":              user.ResourceArtifactoryUser(), // alias of artifactory_user
@@ -142,12 +164,90 @@ func Provider() *schema.Provider {
 				DefaultFunc: schema.MultiEnvDefaultFunc([]string{"ARTIFACTORY_ACCESS_TOKEN", "JFROG_ACCESS_TOKEN"}, nil),
 				Description: "This is a access token that can be given to you by your admin under `Identity and Access`. If not set, the 'api_key' attribute value will be used.",
 			},
+			"ca_certificate": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "CA certificate trusted on top of the system certificates, e.g. of an internal CA. Either PEM or the path of a PEM file.",
+			},
+			"client_certificate": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				RequiredWith: []string{"client_key"},
+				Description:  "Client certificate presented for mutual TLS, either PEM or the path of a PEM file. Requires `client_key`.",
+			},
+			"client_key": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Sensitive:    true,
+				RequiredWith: []string{"client_certificate"},
+				Description:  "Private key of `client_certificate`, either PEM or the path of a PEM file.",
+			},
 			"check_license": {
 				Type:        schema.TypeBool,
 				Optional:    true,
 				Default:     true,
 				Description: "Toggle for pre-flight checking of Artifactory Pro and Enterprise license. Default to `true`.",
 			},
+			"skip_license_check": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "When set, the license isn't read at configure time, like with `check_license = false`, and no resource is gated on the license tier. Default to `false`.",
+			},
+			"max_concurrent_requests": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Maximum number of requests sent to Artifactory at the same time, shared by all resources. `0` means unlimited. Default to `0`.",
+			},
+			"max_member_repositories": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Maximum number of members in `repositories` of a virtual repository, checked at plan time to codify the limits of the instance. `0` means unlimited. Default to `0`.",
+			},
+			"max_patterns_length": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Maximum combined length, in characters, of `includes_pattern` and `excludes_pattern` of a virtual repository, checked at plan time to codify the limits of the instance. `0` means unlimited. Default to `0`.",
+			},
+			"max_retrieval_cache_period_seconds": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      virtual.DefaultMaxRetrievalCachePeriodSecs,
+				ValidateFunc: validation.IntBetween(1, math.MaxInt32),
+				Description:  "Maximum `retrieval_cache_period_seconds` of a virtual repository, checked at plan time to catch typos. Default to `31536000`, one year.",
+			},
+			"uncached_members_warning_threshold": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      virtual.DefaultUncachedMembersWarningThreshold,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Number of members above which a virtual repository with `retrieval_cache_period_seconds = 0` is warned about, as it resolves all metadata requests against its members. `0` disables the warning. Default to `5`.",
+			},
+			"default_retrieval_cache_period_seconds": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          virtual.DefaultRetrievalCachePeriodSecs,
+				ValidateDiagFunc: virtual.ValidateRetrievalCachePeriodSecs,
+				Description:      "`retrieval_cache_period_seconds` of the virtual repositories caching metadata that don't set it. Default to `7200`.",
+			},
+			"default_requests_can_retrieve_remote_artifacts": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "`artifactory_requests_can_retrieve_remote_artifacts` of the virtual repositories that don't set it, unless their package type has its own default, e.g. `true` for docker. Default to `false`.",
+			},
+			"retryable_errors": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString, ValidateFunc: validation.StringIsNotEmpty},
+				Description: "Errors on which repository operations are retried, besides the ones retried by default. Each entry is either an HTTP status code of 400 or more, e.g. `409`, or a regular expression matched against the body of failed responses.",
+			},
 		},
 
 		ResourcesMap: util.AddTelemetry(productId, resourceMap),
@@ -155,8 +255,12 @@ func Provider() *schema.Provider {
 		DataSourcesMap: util.AddTelemetry(
 			productId,
 			map[string]*schema.Resource{
-				"artifactory_file":     datasource.ArtifactoryFile(),
-				"artifactory_fileinfo": datasource.ArtifactoryFileInfo(),
+				"artifactory_file":                    datasource.ArtifactoryFile(),
+				"artifactory_fileinfo":                datasource.ArtifactoryFileInfo(),
+				"artifactory_provider_config":         datasource.ArtifactoryProviderConfig(),
+				"artifactory_repository_config_drift": datasource.ArtifactoryRepositoryConfigDrift(),
+				"artifactory_virtual_repository":      datasource.ArtifactoryVirtualRepository(),
+				"artifactory_virtual_repositories":    datasource.ArtifactoryVirtualRepositories(),
 			},
 		),
 	}
@@ -167,14 +271,14 @@ func Provider() *schema.Provider {
 		if terraformVersion == "" {
 			terraformVersion = "0.11+compatible"
 		}
-		return providerConfigure(ctx, d, terraformVersion)
+		return providerConfigure(ctx, d, terraformVersion, middlewares...)
 	}
 
 	return p
 }
 
 // Creates the client for artifactory, will prefer token auth over basic auth if both set
-func providerConfigure(ctx context.Context, d *schema.ResourceData, terraformVersion string) (interface{}, diag.Diagnostics) {
+func providerConfigure(ctx context.Context, d *schema.ResourceData, terraformVersion string, middlewares ...TransportMiddleware) (interface{}, diag.Diagnostics) {
 	tflog.Debug(ctx, "providerConfigure")
 
 	URL, ok := d.GetOk("url")
@@ -186,6 +290,10 @@ func providerConfigure(ctx context.Context, d *schema.ResourceData, terraformVer
 	if err != nil {
 		return nil, diag.FromErr(err)
 	}
+	restyBase, err = ConfigureTLS(restyBase, d.Get("ca_certificate").(string), d.Get("client_certificate").(string), d.Get("client_key").(string))
+	if err != nil {
+		return nil, diag.Errorf("invalid TLS configuration: %s", err)
+	}
 	apiKey := d.Get("api_key").(string)
 	accessToken := d.Get("access_token").(string)
 
@@ -193,10 +301,35 @@ func providerConfigure(ctx context.Context, d *schema.ResourceData, terraformVer
 	if err != nil {
 		return nil, diag.FromErr(err)
 	}
+	if scope := repository.TokenScope(restyBase); scope != "" {
+		tflog.Debug(ctx, "access token scope detected", map[string]interface{}{"scope": scope})
+	}
+
+	// the middlewares only see the requests on the wire, not those waiting for a concurrency slot. The TLS configuration
+	// is done above, WrapTransport must come after it
+	restyBase = WrapTransport(restyBase, middlewares...)
+	restyBase = LimitConcurrentRequests(restyBase, d.Get("max_concurrent_requests").(int))
 
-	checkLicense := d.Get("check_license").(bool)
+	restyBase, err = RetryOnErrors(restyBase, util.CastToStringArr(d.Get("retryable_errors").([]interface{})))
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
+
+	defaultRetrievalCachePeriodSecs := d.Get("default_retrieval_cache_period_seconds").(int)
+	defaultRequestsCanRetrieveRemoteArtifacts := d.Get("default_requests_can_retrieve_remote_artifacts").(bool)
+	uncachedMembersWarningThreshold := d.Get("uncached_members_warning_threshold").(int)
+	repository.SetProviderSettings(restyBase, repository.ProviderSettings{
+		MaxMemberRepositories:                     d.Get("max_member_repositories").(int),
+		MaxPatternsLength:                         d.Get("max_patterns_length").(int),
+		MaxRetrievalCachePeriodSecs:               d.Get("max_retrieval_cache_period_seconds").(int),
+		DefaultRetrievalCachePeriodSecs:           &defaultRetrievalCachePeriodSecs,
+		DefaultRequestsCanRetrieveRemoteArtifacts: &defaultRequestsCanRetrieveRemoteArtifacts,
+		UncachedMembersWarningThreshold:           &uncachedMembersWarningThreshold,
+	})
+
+	checkLicense := d.Get("check_license").(bool) && !d.Get("skip_license_check").(bool)
 	if checkLicense {
-		licenseErr := util.CheckArtifactoryLicense(restyBase, "Enterprise", "Commercial", "Edge")
+		licenseErr := checkArtifactoryLicense(restyBase, "Enterprise", "Commercial", "Edge")
 		if licenseErr != nil {
 			return nil, licenseErr
 		}
@@ -207,3 +340,19 @@ func providerConfigure(ctx context.Context, d *schema.ResourceData, terraformVer
 
 	return restyBase, nil
 }
+
+// checkArtifactoryLicense fails unless the instance license is one of licenseTypes. The license type stays cached for
+// resources checking it for features that need a higher tier.
+func checkArtifactoryLicense(restyClient *resty.Client, licenseTypes ...string) diag.Diagnostics {
+	licenseType, err := repository.GetCachedLicenseType(restyClient)
+	if err != nil {
+		return diag.Errorf("Failed to check for license. If your usage doesn't require admin permission, you can set `skip_license_check` attribute to `true` to skip this check. %s", err)
+	}
+
+	for _, supportedType := range licenseTypes {
+		if strings.Contains(licenseType, supportedType) {
+			return nil
+		}
+	}
+	return diag.Errorf("Artifactory requires %s license to work with Terraform! If your usage doesn't require a license, you can set `skip_license_check` attribute to `true` to skip this check.", strings.Join(licenseTypes, " or "))
+}
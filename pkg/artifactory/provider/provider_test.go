@@ -1,9 +1,33 @@
 package provider_test
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/go-resty/resty/v2"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/provider"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
 )
 
 func TestProvider(t *testing.T) {
@@ -15,3 +39,452 @@ func TestProvider(t *testing.T) {
 func TestProvider_impl(t *testing.T) {
 	var _ = provider.Provider()
 }
+
+func TestLimitConcurrentRequests(t *testing.T) {
+	const maxConcurrentRequests = 3
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			observed := atomic.LoadInt32(&maxInFlight)
+			if current <= observed || atomic.CompareAndSwapInt32(&maxInFlight, observed, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	restyClient := provider.LimitConcurrentRequests(resty.New().SetHostURL(server.URL), maxConcurrentRequests)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4*maxConcurrentRequests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := restyClient.R().Get("/"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight > maxConcurrentRequests {
+		t.Fatalf("expected at most %d requests in flight, got %d", maxConcurrentRequests, maxInFlight)
+	}
+	if maxInFlight < 2 {
+		t.Fatalf("expected requests to run concurrently, got %d in flight at most", maxInFlight)
+	}
+}
+
+func TestProviderCachesLicenseType(t *testing.T) {
+	testCases := map[string]struct {
+		license           string
+		expectedConfigure bool
+		expectedFederated bool
+	}{
+		"enterprise":    {`{"type":"Enterprise Plus"}`, true, true},
+		"ha enterprise": {`{"licenses":[{"type":"Enterprise"}]}`, true, true},
+		"pro":           {`{"type":"Commercial"}`, true, false},
+		"oss":           {`{"type":"OSS"}`, false, false},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			licenseReads := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/"+repository.LicenseEndpoint {
+					licenseReads++
+					w.Header().Set("Content-Type", "application/json")
+					_, _ = w.Write([]byte(testCase.license))
+				}
+			}))
+			defer server.Close()
+
+			p := provider.Provider()
+			diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+				"url":          server.URL,
+				"access_token": "token",
+			}))
+			if diags.HasError() != !testCase.expectedConfigure {
+				t.Fatalf("expected configure to succeed: %t, got %v", testCase.expectedConfigure, diags)
+			}
+			if !testCase.expectedConfigure {
+				return
+			}
+			if licenseReads != 1 {
+				t.Fatalf("expected the license to be read once, got %d", licenseReads)
+			}
+
+			err := repository.RequireLicense(p.Meta().(*resty.Client), "federated repositories", "Enterprise")
+			if (err == nil) != testCase.expectedFederated {
+				t.Fatalf("expected federated repositories to be supported: %t, got %v", testCase.expectedFederated, err)
+			}
+		})
+	}
+}
+
+func TestProviderSkipLicenseCheck(t *testing.T) {
+	testCases := map[string]map[string]interface{}{
+		"skip_license_check": {"skip_license_check": true},
+		"check_license":      {"check_license": false},
+	}
+
+	for name, config := range testCases {
+		t.Run(name, func(t *testing.T) {
+			licenseReads := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/"+repository.LicenseEndpoint {
+					licenseReads++
+					w.Header().Set("Content-Type", "application/json")
+					_, _ = w.Write([]byte(`{"type":"OSS"}`))
+				}
+			}))
+			defer server.Close()
+
+			config["url"] = server.URL
+			config["access_token"] = "token"
+			p := provider.Provider()
+			if diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(config)); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if licenseReads != 0 {
+				t.Fatalf("expected the license not to be read, got %d reads", licenseReads)
+			}
+			if err := repository.RequireLicense(p.Meta().(*resty.Client), "federated repositories", "Enterprise"); err != nil {
+				t.Fatalf("expected nothing to be gated, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRequireLicenseWithoutLicenseCheck(t *testing.T) {
+	if err := repository.RequireLicense(resty.New(), "federated repositories", "Enterprise"); err != nil {
+		t.Fatalf("expected no error when the license wasn't read, got %v", err)
+	}
+}
+
+func TestRetryOnErrors(t *testing.T) {
+	testCases := map[string]struct {
+		retryableErrors  []string
+		path             string
+		expectedAttempts int32
+	}{
+		"status code":       {[]string{"409"}, "/" + repository.RepositoriesEndpoint + "foo", 3},
+		"message":           {[]string{"metadata .* locked"}, "/" + repository.RepositoriesEndpoint + "foo", 3},
+		"not configured":    {[]string{"503"}, "/" + repository.RepositoriesEndpoint + "foo", 1},
+		"not a repository":  {[]string{"409"}, "/artifactory/api/security/users/foo", 1},
+		"nothing retryable": {nil, "/" + repository.RepositoriesEndpoint + "foo", 1},
+		// the message is in the body of the successful response too, which is never retried
+		"successful response": {[]string{"foo"}, "/" + repository.RepositoriesEndpoint + "foo", 3},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&attempts, 1) < 3 {
+					w.WriteHeader(http.StatusConflict)
+					_, _ = w.Write([]byte(`{"errors":[{"status":409,"message":"metadata of foo is locked"}]}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"key":"foo"}`))
+			}))
+			defer server.Close()
+
+			restyClient, err := provider.RetryOnErrors(
+				resty.New().SetHostURL(server.URL).SetRetryCount(5).SetRetryWaitTime(time.Millisecond),
+				testCase.retryableErrors,
+			)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if _, err := restyClient.R().Put(testCase.path); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if attempts != testCase.expectedAttempts {
+				t.Fatalf("expected %d attempts, got %d", testCase.expectedAttempts, attempts)
+			}
+		})
+	}
+}
+
+func TestRetryOnErrorsRejectsInvalidPattern(t *testing.T) {
+	if _, err := provider.RetryOnErrors(resty.New(), []string{"locked ("}); err == nil {
+		t.Fatal("expected an invalid regular expression to be rejected")
+	}
+}
+
+func TestRetryOnErrorsRejectsSuccessStatusCode(t *testing.T) {
+	if _, err := provider.RetryOnErrors(resty.New(), []string{"200"}); err == nil {
+		t.Fatal("expected a successful status code to be rejected")
+	}
+}
+
+func TestRecordTimings(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		time.Sleep(10 * time.Millisecond)
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var order []string
+	var timings []provider.RequestTiming
+	recording := provider.RecordTimings(func(timing provider.RequestTiming) {
+		order = append(order, "recorder")
+		timings = append(timings, timing)
+	})
+	outer := func(transport http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			order = append(order, "outer")
+			return transport.RoundTrip(request)
+		})
+	}
+	restyClient := provider.WrapTransport(resty.New().SetHostURL(server.URL).SetRetryCount(1), outer, recording)
+
+	resp, err := restyClient.R().
+		AddRetryCondition(func(response *resty.Response, _ error) bool {
+			return response.StatusCode() == http.StatusServiceUnavailable
+		}).
+		Get("/artifactory/api/repositories/foo")
+	if err != nil || resp.StatusCode() != http.StatusOK {
+		t.Fatalf("unexpected response %v: %v", resp, err)
+	}
+
+	// every attempt is recorded, through the middlewares in order
+	if len(timings) != 2 {
+		t.Fatalf("expected 2 attempts to be recorded, got %v", timings)
+	}
+	for i, expectedStatus := range []int{http.StatusServiceUnavailable, http.StatusOK} {
+		timing := timings[i]
+		if timing.Method != http.MethodGet || timing.Path != "/artifactory/api/repositories/foo" || timing.Status != expectedStatus || timing.Err != nil {
+			t.Fatalf("unexpected timing of attempt %d: %+v", i+1, timing)
+		}
+		if timing.Duration < 10*time.Millisecond {
+			t.Fatalf("expected attempt %d to take at least 10ms, got %s", i+1, timing.Duration)
+		}
+	}
+	if expected := []string{"outer", "recorder", "outer", "recorder"}; !reflect.DeepEqual(order, expected) {
+		t.Fatalf("expected the first middleware to be the outermost, got %v", order)
+	}
+}
+
+func TestRecordTimingsWithoutResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	server.Close()
+
+	var timings []provider.RequestTiming
+	restyClient := provider.WrapTransport(resty.New().SetHostURL(server.URL), provider.RecordTimings(func(timing provider.RequestTiming) {
+		timings = append(timings, timing)
+	}))
+
+	if _, err := restyClient.R().Get("/"); err == nil {
+		t.Fatal("expected the request to fail")
+	}
+	if len(timings) != 1 || timings[0].Status != 0 || timings[0].Err == nil {
+		t.Fatalf("expected a timing without status and with the error, got %+v", timings)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+// newTLSRepositoriesServer serves the license and stores the repositories it receives, over TLS with its own
+// certificate, returned PEM encoded
+func newTLSRepositoriesServer(t *testing.T, clientCAs *x509.CertPool) (*httptest.Server, string) {
+	repos := map[string][]byte{}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+repository.LicenseEndpoint {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"type":"Enterprise Plus"}`))
+			return
+		}
+		key := strings.TrimPrefix(r.URL.Path, "/"+repository.RepositoriesEndpoint)
+		switch r.Method {
+		case http.MethodPut, http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			repos[key] = body
+		case http.MethodGet:
+			repo, ok := repos[key]
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(repo)
+		case http.MethodDelete:
+			delete(repos, key)
+		}
+	}))
+	if clientCAs != nil {
+		server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+}
+
+// newClientCertificate returns a self-signed client certificate and its key, PEM encoded
+func newClientCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "terraform"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encodedKey, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: encodedKey}))
+}
+
+func assertVirtualRepositoryCRUD(t *testing.T, restyClient *resty.Client) {
+	t.Helper()
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	ctx := context.Background()
+
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": "foo-generic"})
+	if diags := repoResource.CreateContext(ctx, d, restyClient); diags.HasError() {
+		t.Fatalf("failed to create: %v", diags)
+	}
+	if diags := repoResource.ReadContext(ctx, d, restyClient); diags.HasError() || d.Id() != "foo-generic" {
+		t.Fatalf("failed to read: %v", diags)
+	}
+	if err := d.Set("description", "updated"); err != nil {
+		t.Fatal(err)
+	}
+	if diags := repoResource.UpdateContext(ctx, d, restyClient); diags.HasError() {
+		t.Fatalf("failed to update: %v", diags)
+	}
+	if diags := repoResource.DeleteContext(ctx, d, restyClient); diags.HasError() {
+		t.Fatalf("failed to delete: %v", diags)
+	}
+}
+
+func TestProviderTLSCustomCA(t *testing.T) {
+	server, caCertificate := newTLSRepositoriesServer(t, nil)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte(caCertificate), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range map[string]string{"inline": caCertificate, "file": caFile} {
+		t.Run(name, func(t *testing.T) {
+			p := provider.Provider()
+			diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+				"url":            server.URL,
+				"access_token":   "token",
+				"ca_certificate": value,
+			}))
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			assertVirtualRepositoryCRUD(t, p.Meta().(*resty.Client))
+		})
+	}
+}
+
+func TestProviderWithMiddlewaresKeepsTLS(t *testing.T) {
+	server, caCertificate := newTLSRepositoriesServer(t, nil)
+
+	var timings []provider.RequestTiming
+	p := provider.ProviderWithMiddlewares(provider.RecordTimings(func(timing provider.RequestTiming) {
+		timings = append(timings, timing)
+	}))
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"url":            server.URL,
+		"access_token":   "token",
+		"ca_certificate": caCertificate,
+	}))
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	// the middlewares wrap the transport trusting the custom CA, the calls would fail otherwise
+	assertVirtualRepositoryCRUD(t, p.Meta().(*resty.Client))
+	if len(timings) == 0 {
+		t.Fatal("expected the calls to go through the middleware")
+	}
+	for _, timing := range timings {
+		if timing.Err != nil {
+			t.Fatalf("unexpected error in %s %s: %s", timing.Method, timing.Path, timing.Err)
+		}
+	}
+}
+
+func TestProviderTLSClientCertificate(t *testing.T) {
+	clientCertificate, clientKey := newClientCertificate(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM([]byte(clientCertificate))
+	server, caCertificate := newTLSRepositoriesServer(t, clientCAs)
+
+	p := provider.Provider()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"url":                server.URL,
+		"access_token":       "token",
+		"ca_certificate":     caCertificate,
+		"client_certificate": clientCertificate,
+		"client_key":         clientKey,
+	}))
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	assertVirtualRepositoryCRUD(t, p.Meta().(*resty.Client))
+}
+
+func TestProviderTLSRejectsInvalidCertificates(t *testing.T) {
+	clientCertificate, _ := newClientCertificate(t)
+	_, otherKey := newClientCertificate(t)
+
+	testCases := map[string]struct {
+		config   map[string]interface{}
+		expected string
+	}{
+		"ca without certificate": {
+			map[string]interface{}{"ca_certificate": "-----BEGIN CERTIFICATE-----\nbm9wZQ==\n-----END CERTIFICATE-----\n"},
+			"ca_certificate has no valid PEM encoded certificate",
+		},
+		"missing ca file": {
+			map[string]interface{}{"ca_certificate": filepath.Join(t.TempDir(), "missing.pem")},
+			"ca_certificate is neither PEM nor a readable file",
+		},
+		"mismatched key": {
+			map[string]interface{}{"client_certificate": clientCertificate, "client_key": otherKey},
+			"client_certificate and client_key are not a valid PEM encoded key pair",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			testCase.config["url"] = "https://artifactory.example.com"
+			testCase.config["access_token"] = "token"
+			diags := provider.Provider().Configure(context.Background(), terraform.NewResourceConfigRaw(testCase.config))
+			if !diags.HasError() || !strings.Contains(diags[0].Summary, testCase.expected) {
+				t.Fatalf("expected an error containing %q, got %v", testCase.expected, diags)
+			}
+		})
+	}
+}
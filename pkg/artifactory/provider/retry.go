@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+)
+
+// RetryOnErrors makes the client retry repository operations failing with one of retryableErrors. An entry is either an
+// HTTP status code, e.g. "409", or a regular expression matched against the response body, e.g. a transient lock
+// message. Only failed responses are matched, status codes below 400 are rejected. Other operations keep their own retry
+// conditions.
+func RetryOnErrors(restyClient *resty.Client, retryableErrors []string) (*resty.Client, error) {
+	if len(retryableErrors) == 0 {
+		return restyClient, nil
+	}
+
+	statusCodes := map[int]bool{}
+	var messages []*regexp.Regexp
+	for _, retryableError := range retryableErrors {
+		if statusCode, err := strconv.Atoi(retryableError); err == nil {
+			if statusCode < 400 {
+				return nil, fmt.Errorf("retryable error %q is not an error status code, only responses with a status code of 400 or more are retried", retryableError)
+			}
+			statusCodes[statusCode] = true
+			continue
+		}
+		message, err := regexp.Compile(retryableError)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retryable error %q: %w", retryableError, err)
+		}
+		messages = append(messages, message)
+	}
+
+	return restyClient.AddRetryCondition(func(response *resty.Response, err error) bool {
+		if response == nil || response.Request == nil || !strings.Contains(response.Request.URL, repository.RepositoriesEndpoint) {
+			return false
+		}
+		if err == nil && !response.IsError() {
+			return false
+		}
+		if statusCodes[response.StatusCode()] {
+			return true
+		}
+		for _, message := range messages {
+			if message.Match(response.Body()) {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
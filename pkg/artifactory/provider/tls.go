@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// readPEM returns value when it is inline PEM, otherwise the content of the file it is the path of
+func readPEM(attribute, value string) ([]byte, error) {
+	if strings.Contains(value, "-----BEGIN") {
+		return []byte(value), nil
+	}
+	content, err := os.ReadFile(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s is neither PEM nor a readable file: %w", attribute, err)
+	}
+	return content, nil
+}
+
+// ConfigureTLS trusts the certificates of caCertificate on top of the system ones, and presents the client certificate
+// for mutual TLS when clientCertificate and clientKey are set. Each is either PEM or the path of a PEM file. It must
+// be called before the transport is wrapped, resty only configures its own transport.
+func ConfigureTLS(restyClient *resty.Client, caCertificate, clientCertificate, clientKey string) (*resty.Client, error) {
+	if caCertificate == "" && clientCertificate == "" {
+		return restyClient, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caCertificate != "" {
+		ca, err := readPEM("ca_certificate", caCertificate)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("ca_certificate has no valid PEM encoded certificate")
+		}
+		config.RootCAs = pool
+	}
+
+	if clientCertificate != "" {
+		certificate, err := readPEM("client_certificate", clientCertificate)
+		if err != nil {
+			return nil, err
+		}
+		key, err := readPEM("client_key", clientKey)
+		if err != nil {
+			return nil, err
+		}
+		pair, err := tls.X509KeyPair(certificate, key)
+		if err != nil {
+			return nil, fmt.Errorf("client_certificate and client_key are not a valid PEM encoded key pair: %w", err)
+		}
+		config.Certificates = []tls.Certificate{pair}
+	}
+
+	return restyClient.SetTLSClientConfig(config), nil
+}
@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// TransportMiddleware wraps the transport of the Artifactory client, e.g. to trace or meter the API calls
+type TransportMiddleware func(http.RoundTripper) http.RoundTripper
+
+// WrapTransport wraps the transport of the client with middlewares, the first one being the outermost. It must be the
+// last change of the transport: resty applies SetTLSClientConfig and SetProxy to its *http.Transport, which the
+// middlewares hide, calls after it only log an error and leave the transport unchanged.
+func WrapTransport(restyClient *resty.Client, middlewares ...TransportMiddleware) *resty.Client {
+	if len(middlewares) == 0 {
+		return restyClient
+	}
+
+	transport := restyClient.GetClient().Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		transport = middlewares[i](transport)
+	}
+	return restyClient.SetTransport(transport)
+}
+
+// RequestTiming is the latency and outcome of one attempt of an API call. Status is 0 when no response was received.
+type RequestTiming struct {
+	Method   string
+	Path     string
+	Status   int
+	Duration time.Duration
+	Err      error
+}
+
+type timingTransport struct {
+	transport http.RoundTripper
+	record    func(RequestTiming)
+}
+
+func (t *timingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	start := time.Now()
+	response, err := t.transport.RoundTrip(request)
+
+	timing := RequestTiming{
+		Method:   request.Method,
+		Path:     request.URL.Path,
+		Duration: time.Since(start),
+		Err:      err,
+	}
+	if response != nil {
+		timing.Status = response.StatusCode
+	}
+	t.record(timing)
+	return response, err
+}
+
+// RecordTimings returns a middleware calling record after every attempt, resty retries included. The duration is the
+// time until the response headers are received, reading the body isn't included.
+func RecordTimings(record func(RequestTiming)) TransportMiddleware {
+	return func(transport http.RoundTripper) http.RoundTripper {
+		return &timingTransport{transport: transport, record: record}
+	}
+}
@@ -1,8 +1,11 @@
 package federated
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/go-resty/resty/v2"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
@@ -109,7 +112,7 @@ func ResourceArtifactoryFederatedGenericRepository(repoType string) *schema.Reso
 	}
 
 	packer := repository.ComposePacker(
-		repository.UniversalPack(repository.IgnoreHclPredicate("class", "rclass", "member")),
+		repository.UniversalPack(repository.IgnoreHclPredicate("class", "rclass", "member", "reset")),
 		packMembers,
 	)
 
@@ -122,5 +125,17 @@ func ResourceArtifactoryFederatedGenericRepository(repoType string) *schema.Reso
 		}
 	}
 
-	return repository.MkResourceSchema(federatedSchema, packer, unpackFederatedRepository, constructor)
+	resource := repository.MkResourceSchema(federatedSchema, packer, unpackFederatedRepository, constructor)
+	resource.CustomizeDiff = customdiff.All(resource.CustomizeDiff, requireFederationLicense)
+	return resource
+}
+
+// requireFederationLicense fails the plan of a new federated repository when the instance license doesn't support
+// federation, Artifactory otherwise only answers with a 400.
+func requireFederationLicense(_ context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	if diff.Id() != "" {
+		return nil
+	}
+	restyClient, _ := m.(*resty.Client)
+	return repository.RequireLicense(restyClient, "federated repositories", "Enterprise")
 }
@@ -235,7 +235,7 @@ func TestAccFederatedRepositoryWithInvalidProjectKeyGH318(t *testing.T) {
 		Steps: []resource.TestStep{
 			{
 				Config:      federatedRepositoryConfig,
-				ExpectError: regexp.MustCompile(".*project_key must be 3 - 10 lowercase alphanumeric characters"),
+				ExpectError: regexp.MustCompile(".*project keys are 3 to 10 lowercase letters or digits"),
 			},
 		},
 	})
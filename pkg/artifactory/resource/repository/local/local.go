@@ -1,10 +1,16 @@
 package local
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
 	"github.com/jfrog/terraform-provider-shared/util"
-	"github.com/jfrog/terraform-provider-shared/validator"
+	"golang.org/x/exp/slices"
 )
 
 var RepoTypesLikeGeneric = []string{
@@ -45,12 +51,18 @@ type LocalRepositoryBaseParams struct {
 	ArchiveBrowsingEnabled *bool    `hcl:"archive_browsing_enabled" json:"archiveBrowsingEnabled,omitempty"`
 	DownloadRedirect       *bool    `hcl:"download_direct" json:"downloadRedirect,omitempty"`
 	PriorityResolution     bool     `hcl:"priority_resolution" json:"priorityResolution"`
+	// Reset are the JSON fields of the attributes removed from the configuration, sent empty
+	Reset []string `json:"-"`
 }
 
 func (bp LocalRepositoryBaseParams) Id() string {
 	return bp.Key
 }
 
+func (bp LocalRepositoryBaseParams) ResetFields() []string {
+	return bp.Reset
+}
+
 var BaseLocalRepoSchema = map[string]*schema.Schema{
 	"key": {
 		Type:         schema.TypeString,
@@ -62,9 +74,16 @@ var BaseLocalRepoSchema = map[string]*schema.Schema{
 	"project_key": {
 		Type:             schema.TypeString,
 		Optional:         true,
-		ValidateDiagFunc: validator.ProjectKey,
+		ValidateDiagFunc: repository.ValidateProjectKey,
 		Description:      "Project key for assigning this repository to. When assigning repository to a project, repository key must be prefixed with project key, separated by a dash.",
 	},
+	"replace_on_project_key_change": {
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "When set, changing `project_key` from one project to another replaces the repository instead of reassigning it in place, for Artifactory versions that can't move a repository between projects. A warning is emitted for in-place reassignments otherwise. Default to 'false'.",
+	},
+
 	"project_environments": {
 		Type:        schema.TypeSet,
 		Elem:        &schema.Schema{Type: schema.TypeString},
@@ -72,7 +91,7 @@ var BaseLocalRepoSchema = map[string]*schema.Schema{
 		MaxItems:    2,
 		Set:         schema.HashString,
 		Optional:    true,
-		Description: `Project environment for assigning this repository to. Allow values: "DEV" or "PROD"`,
+		Description: "Project environment for assigning this repository to. Allow values: \"DEV\", \"PROD\" or, with `project_key`, an environment defined in the project",
 	},
 	"package_type": {
 		Type:     schema.TypeString,
@@ -142,13 +161,75 @@ var BaseLocalRepoSchema = map[string]*schema.Schema{
 	},
 }
 
+var forceDeleteSchema = map[string]*schema.Schema{
+	"force_delete": {
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "When set, the repository is deleted even when it stores artifacts and virtual repositories aggregate it. When unset, the delete of such a repository fails, naming the virtual repositories. Default to 'false'.",
+	},
+}
+
+// mkResourceSchema makes the resource of a local repository, whose delete is guarded by `force_delete`
+func mkResourceSchema(skeema map[string]*schema.Schema, packer repository.PackFunc, unpack repository.UnpackFunc, constructor repository.Constructor) *schema.Resource {
+	resource := repository.MkResourceSchema(util.MergeSchema(skeema, forceDeleteSchema), packer, unpack, constructor)
+	resource.DeleteContext = guardMemberDeletion(resource.DeleteContext)
+	return resource
+}
+
+// guardMemberDeletion fails the delete of a repository storing artifacts while virtual repositories aggregate it, unless
+// `force_delete` is set. Deleting a virtual repository loses no artifacts, deleting one of its members does. Virtual
+// repositories destroyed in the same apply are deleted first, so only members still in use are guarded. The artifacts
+// are checked first, the members of every virtual repository are only read for repositories that aren't empty.
+func guardMemberDeletion(delete schema.DeleteContextFunc) schema.DeleteContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		if d.Get("force_delete").(bool) {
+			return delete(ctx, d, m)
+		}
+
+		restyClient, err := repository.RestyClientOf(m, "force_delete")
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		hasArtifacts, err := repository.HasArtifacts(d.Id(), restyClient)
+		if err != nil {
+			return diag.Errorf("failed to check whether repository %s stores artifacts: %s", d.Id(), err)
+		}
+		if !hasArtifacts {
+			return delete(ctx, d, m)
+		}
+
+		virtualRepos, err := repository.ListVirtualRepositories(restyClient, "", "")
+		if err != nil {
+			return diag.Errorf("failed to look up virtual repositories aggregating %s: %s", d.Id(), err)
+		}
+		var referencing []string
+		for _, virtualRepo := range virtualRepos {
+			if slices.Contains(virtualRepo.Repositories, d.Id()) {
+				referencing = append(referencing, virtualRepo.Key)
+			}
+		}
+		if len(referencing) == 0 {
+			return delete(ctx, d, m)
+		}
+
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "Member repository stores artifacts",
+			Detail: fmt.Sprintf("The repository %s stores artifacts and is a member of the virtual repositories %s. "+
+				"Remove it from them first, or set force_delete = true and apply before destroying it.", d.Id(), strings.Join(referencing, ", ")),
+			AttributePath: cty.GetAttrPath("force_delete"),
+		}}
+	}
+}
+
 func UnpackBaseRepo(rclassType string, s *schema.ResourceData, packageType string) LocalRepositoryBaseParams {
 	d := &util.ResourceData{s}
 	return LocalRepositoryBaseParams{
 		Rclass:                 rclassType,
 		Key:                    d.GetString("key", false),
 		ProjectKey:             d.GetString("project_key", false),
-		ProjectEnvironments:    d.GetSet("project_environments"),
+		ProjectEnvironments:    repository.GetProjectEnvironments(d),
 		PackageType:            packageType,
 		Description:            d.GetString("description", false),
 		Notes:                  d.GetString("notes", false),
@@ -161,6 +242,7 @@ func UnpackBaseRepo(rclassType string, s *schema.ResourceData, packageType strin
 		XrayIndex:              d.GetBool("xray_index", false),
 		DownloadRedirect:       d.GetBoolRef("download_direct", false),
 		PriorityResolution:     d.GetBool("priority_resolution", false),
+		Reset:                  repository.GetResetFields(d),
 	}
 }
 
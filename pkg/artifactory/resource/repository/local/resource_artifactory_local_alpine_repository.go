@@ -37,7 +37,7 @@ func ResourceArtifactoryLocalAlpineRepository() *schema.Resource {
 		return repo, repo.Id(), nil
 	}
 
-	return repository.MkResourceSchema(alpineLocalSchema, repository.DefaultPacker(alpineLocalSchema), unPackLocalAlpineRepository, func() interface{} {
+	return mkResourceSchema(alpineLocalSchema, repository.DefaultPacker(alpineLocalSchema), unPackLocalAlpineRepository, func() interface{} {
 		return &AlpineLocalRepo{
 			LocalRepositoryBaseParams: LocalRepositoryBaseParams{
 				PackageType: "alpine",
@@ -25,5 +25,5 @@ func ResourceArtifactoryLocalGenericRepository(repoType string) *schema.Resource
 
 	genericRepoSchema := getGenericRepoSchema(repoType)
 
-	return repository.MkResourceSchema(genericRepoSchema, repository.DefaultPacker(genericRepoSchema), unpack, constructor)
+	return mkResourceSchema(genericRepoSchema, repository.DefaultPacker(genericRepoSchema), unpack, constructor)
 }
@@ -94,7 +94,7 @@ func ResourceArtifactoryLocalJavaRepository(repoType string, suppressPom bool) *
 
 	javaLocalSchema := getJavaRepoSchema(repoType, suppressPom)
 
-	return repository.MkResourceSchema(javaLocalSchema, repository.DefaultPacker(javaLocalSchema), unPackLocalJavaRepository, func() interface{} {
+	return mkResourceSchema(javaLocalSchema, repository.DefaultPacker(javaLocalSchema), unPackLocalJavaRepository, func() interface{} {
 		return &JavaLocalRepositoryParams{
 			LocalRepositoryBaseParams: LocalRepositoryBaseParams{
 				PackageType: repoType,
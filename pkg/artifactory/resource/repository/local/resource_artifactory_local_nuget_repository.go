@@ -46,7 +46,7 @@ func ResourceArtifactoryLocalNugetRepository() *schema.Resource {
 		return repo, repo.Id(), nil
 	}
 
-	return repository.MkResourceSchema(nugetLocalSchema, repository.DefaultPacker(nugetLocalSchema), unPackLocalNugetRepository, func() interface{} {
+	return mkResourceSchema(nugetLocalSchema, repository.DefaultPacker(nugetLocalSchema), unPackLocalNugetRepository, func() interface{} {
 		return &NugetLocalRepositoryParams{
 			LocalRepositoryBaseParams: LocalRepositoryBaseParams{
 				PackageType: "nuget",
@@ -1,8 +1,10 @@
 package local_test
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
+	"net/http"
 	"regexp"
 	"strings"
 	"testing"
@@ -10,6 +12,7 @@ import (
 
 	"github.com/go-resty/resty/v2"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
 	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
 	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/local"
@@ -604,6 +607,47 @@ func TestAccLocalGenericRepository(t *testing.T) {
 	})
 }
 
+func TestAccLocalGenericRepository_removedAttributesAreCleared(t *testing.T) {
+	_, fqrn, name := acctest.MkNames("generic-local", "artifactory_local_generic_repository")
+	withAttributes := acctest.ExecuteTemplate("TestAccLocalGenericRepository_removedAttributesAreCleared", `
+		resource "artifactory_local_generic_repository" "{{ .name }}" {
+		  key             = "{{ .name }}"
+		  description     = "a description"
+		  notes           = "some notes"
+		  repo_layout_ref = "maven-2-default"
+		}
+	`, map[string]interface{}{"name": name})
+	withoutAttributes := acctest.ExecuteTemplate("TestAccLocalGenericRepository_removedAttributesAreCleared", `
+		resource "artifactory_local_generic_repository" "{{ .name }}" {
+		  key = "{{ .name }}"
+		}
+	`, map[string]interface{}{"name": name})
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { acctest.PreCheck(t) },
+		ProviderFactories: acctest.ProviderFactories,
+		CheckDestroy:      acctest.VerifyDeleted(fqrn, acctest.CheckRepo),
+		Steps: []resource.TestStep{
+			{
+				Config: withAttributes,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(fqrn, "description", "a description"),
+					resource.TestCheckResourceAttr(fqrn, "notes", "some notes"),
+					resource.TestCheckResourceAttr(fqrn, "repo_layout_ref", "maven-2-default"),
+				),
+			},
+			{
+				Config: withoutAttributes,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(fqrn, "description", ""),
+					resource.TestCheckResourceAttr(fqrn, "notes", ""),
+					resource.TestCheckResourceAttr(fqrn, "repo_layout_ref", "simple-default"),
+				),
+			},
+		},
+	})
+}
+
 func TestAccLocalGenericRepositoryWithProjectAttributesGH318(t *testing.T) {
 
 	rand.Seed(time.Now().UnixNano())
@@ -682,7 +726,7 @@ func TestAccLocalGenericRepositoryWithInvalidProjectKeyGH318(t *testing.T) {
 		Steps: []resource.TestStep{
 			{
 				Config:      localRepositoryBasic,
-				ExpectError: regexp.MustCompile(".*project_key must be 3 - 10 lowercase alphanumeric characters"),
+				ExpectError: regexp.MustCompile(".*project keys are 3 to 10 lowercase letters or digits"),
 			},
 		},
 	})
@@ -916,3 +960,55 @@ func TestAccLocalCargoRepository(t *testing.T) {
 		},
 	})
 }
+
+func TestLocalRepositoryDeleteBlocksOnNonEmptyMember(t *testing.T) {
+	testCases := map[string]struct {
+		forceDelete     bool
+		aqlResults      string
+		members         string
+		expectedDeleted bool
+	}{
+		"non-empty member without force_delete": {false, `{"results":[{"name":"foo.jar"}]}`, `["foo-local"]`, false},
+		"non-empty member with force_delete":    {true, `{"results":[{"name":"foo.jar"}]}`, `["foo-local"]`, true},
+		"empty member without force_delete":     {false, `{"results":[]}`, `["foo-local"]`, true},
+		"non-empty without virtual repository":  {false, `{"results":[{"name":"foo.jar"}]}`, `["bar-local"]`, true},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			deleted := false
+			restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case r.Method == http.MethodPost && r.URL.Path == "/"+repository.AqlEndpoint:
+					_, _ = w.Write([]byte(testCase.aqlResults))
+				case r.Method == http.MethodGet && r.URL.Path == "/"+strings.TrimSuffix(repository.RepositoriesEndpoint, "/"):
+					_, _ = w.Write([]byte(`[{"key":"foo-virtual","type":"VIRTUAL","packageType":"Maven"}]`))
+				case r.Method == http.MethodGet && r.URL.Path == "/"+repository.RepositoriesEndpoint+"foo-virtual":
+					_, _ = w.Write([]byte(`{"key":"foo-virtual","rclass":"virtual","packageType":"maven","repositories":` + testCase.members + `}`))
+				case r.Method == http.MethodDelete && r.URL.Path == "/"+repository.RepositoriesEndpoint+"foo-local":
+					deleted = true
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			repoResource := local.ResourceArtifactoryLocalGenericRepository("generic")
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+				"key":          "foo-local",
+				"force_delete": testCase.forceDelete,
+			})
+			d.SetId("foo-local")
+
+			diags := repoResource.DeleteContext(context.Background(), d, restyClient)
+			if testCase.expectedDeleted && diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if !testCase.expectedDeleted && (!diags.HasError() || !strings.Contains(diags[0].Detail, "member of the virtual repositories foo-virtual")) {
+				t.Fatalf("expected the delete to be blocked naming the virtual repository, got %v", diags)
+			}
+			if deleted != testCase.expectedDeleted {
+				t.Fatalf("expected deleted to be %t", testCase.expectedDeleted)
+			}
+		})
+	}
+}
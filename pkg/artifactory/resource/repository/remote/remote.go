@@ -53,6 +53,8 @@ type RemoteRepositoryBaseParams struct {
 	ContentSynchronisation            *repository.ContentSynchronisation `hcl:"content_synchronisation" json:"contentSynchronisation,omitempty"`
 	MismatchingMimeTypeOverrideList   string                             `hcl:"mismatching_mime_types_override_list" json:"mismatchingMimeTypesOverrideList"`
 	ListRemoteFolderItems             bool                               `json:"listRemoteFolderItems"`
+	// Reset are the JSON fields of the attributes removed from the configuration, sent empty
+	Reset []string `json:"-"`
 }
 
 type RemoteRepositoryVcsParams struct {
@@ -64,6 +66,10 @@ func (bp RemoteRepositoryBaseParams) Id() string {
 	return bp.Key
 }
 
+func (bp RemoteRepositoryBaseParams) ResetFields() []string {
+	return bp.Reset
+}
+
 var RemoteRepoTypesLikeGeneric = []string{
 	"alpine",
 	"chef",
@@ -93,16 +99,23 @@ var BaseRemoteRepoSchema = map[string]*schema.Schema{
 	"project_key": {
 		Type:             schema.TypeString,
 		Optional:         true,
-		ValidateDiagFunc: validator.ProjectKey,
+		ValidateDiagFunc: repository.ValidateProjectKey,
 		Description:      "Project key for assigning this repository to. Must be 3 - 10 lowercase alphanumeric characters. When assigning repository to a project, repository key must be prefixed with project key, separated by a dash.",
 	},
+	"replace_on_project_key_change": {
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "When set, changing `project_key` from one project to another replaces the repository instead of reassigning it in place, for Artifactory versions that can't move a repository between projects. A warning is emitted for in-place reassignments otherwise. Default to 'false'.",
+	},
+
 	"project_environments": {
 		Type:        schema.TypeSet,
 		Elem:        &schema.Schema{Type: schema.TypeString},
 		MaxItems:    2,
 		Set:         schema.HashString,
 		Optional:    true,
-		Description: `Project environment for assigning this repository to. Allow values: "DEV" or "PROD"`,
+		Description: "Project environment for assigning this repository to. Allow values: \"DEV\", \"PROD\" or, with `project_key`, an environment defined in the project",
 	},
 	"package_type": {
 		Type:     schema.TypeString,
@@ -378,7 +391,7 @@ func UnpackBaseRemoteRepo(s *schema.ResourceData, packageType string) RemoteRepo
 		Rclass:                   "remote",
 		Key:                      d.GetString("key", false),
 		ProjectKey:               d.GetString("project_key", false),
-		ProjectEnvironments:      d.GetSet("project_environments"),
+		ProjectEnvironments:      repository.GetProjectEnvironments(d),
 		PackageType:              packageType, // must be set independently
 		Url:                      d.GetString("url", false),
 		Username:                 d.GetString("username", true),
@@ -415,6 +428,7 @@ func UnpackBaseRemoteRepo(s *schema.ResourceData, packageType string) RemoteRepo
 		PriorityResolution:                d.GetBool("priority_resolution", false),
 		ListRemoteFolderItems:             d.GetBool("list_remote_folder_items", false),
 		MismatchingMimeTypeOverrideList:   d.GetString("mismatching_mime_types_override_list", false),
+		Reset:                             repository.GetResetFields(d),
 	}
 	if v, ok := d.GetOk("content_synchronisation"); ok {
 		contentSynchronisationConfig := v.([]interface{})[0].(map[string]interface{})
@@ -2,17 +2,23 @@ package repository
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"net/http"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/jfrog/terraform-provider-shared/client"
@@ -61,74 +67,709 @@ type UnpackFunc func(s *schema.ResourceData) (interface{}, string, error)
 
 type PackFunc func(repo interface{}, d *schema.ResourceData) error
 
-func mkRepoCreate(unpack UnpackFunc, read schema.ReadContextFunc) schema.CreateContextFunc {
+// RepositoryClient is the repository configuration API the CRUD functions depend on. The provider meta is a resty
+// client, which RepositoryClientOf wraps, but any implementation can be passed as meta, e.g. a fake in unit tests.
+// Responses are returned so the CRUD functions can tell not found and forbidden errors apart. Members are looked up
+// through it as well, see GetMemberInfo. What else needs the Artifactory API gets the resty client with RestyClientOf
+// and fails for other metas, only the advisory plan checks Artifactory repeats at apply, e.g. that a project exists,
+// are skipped without it.
+type RepositoryClient interface {
+	Create(key string, repo interface{}) (*resty.Response, error)
+	Get(key string, repo interface{}) (*resty.Response, error)
+	Update(key string, repo interface{}) (*resty.Response, error)
+	Delete(key string) (*resty.Response, error)
+}
+
+// RepositoryClientOf returns the RepositoryClient of the provider meta
+func RepositoryClientOf(m interface{}) RepositoryClient {
+	switch meta := m.(type) {
+	case RepositoryClient:
+		return meta
+	case *resty.Client:
+		return restyRepositoryClient{restyClient: meta}
+	default:
+		panic(fmt.Sprintf("provider meta %T is neither a resty client nor a RepositoryClient", m))
+	}
+}
+
+// RestyClientOf returns the resty client of the provider meta for feature, which needs more of the Artifactory API than
+// a RepositoryClient offers. Other metas fail, so the feature doesn't silently not happen.
+func RestyClientOf(m interface{}, feature string) (*resty.Client, error) {
+	restyClient, ok := m.(*resty.Client)
+	if !ok {
+		return nil, fmt.Errorf("%s needs the Artifactory API, the provider meta %T is only a RepositoryClient", feature, m)
+	}
+	return restyClient, nil
+}
+
+type restyRepositoryClient struct {
+	restyClient *resty.Client
+}
+
+func (c restyRepositoryClient) Create(key string, repo interface{}) (*resty.Response, error) {
+	return c.restyClient.R().
+		AddRetryCondition(client.RetryOnMergeError).
+		SetBody(repo).
+		Put(RepositoriesEndpoint + key)
+}
+
+// Get unmarshals the configuration into repo, which must be a pointer
+func (c restyRepositoryClient) Get(key string, repo interface{}) (*resty.Response, error) {
+	return c.restyClient.R().SetResult(repo).Get(RepositoriesEndpoint + key)
+}
+
+func (c restyRepositoryClient) Update(key string, repo interface{}) (*resty.Response, error) {
+	return c.restyClient.R().
+		AddRetryCondition(client.RetryOnMergeError).
+		SetBody(repo).
+		Post(RepositoriesEndpoint + key)
+}
+
+func (c restyRepositoryClient) Delete(key string) (*resty.Response, error) {
+	return c.restyClient.R().
+		AddRetryCondition(client.RetryOnMergeError).
+		Delete(RepositoriesEndpoint + key)
+}
+
+// Artifactory versions without environments reject the whole configuration with the field, the clients of one that
+// rejected it are remembered in their cache and later writes leave it out up front.
+var unsupportedEnvironmentsError = regexp.MustCompile(`(?i)(unrecognized|unknown)\s+(field|property)\W+environments\b`)
+
+// writeRepo sends payload with write, without the `environments` field when Artifactory doesn't support it. An
+// Artifactory rejecting the field is retried once without it, and a warning tells the environments weren't assigned
+// when there were any.
+func writeRepo(ctx context.Context, m interface{}, key, operation string, payload interface{}, write func(interface{}) (*resty.Response, error)) (*resty.Response, diag.Diagnostics, error) {
+	restyClient, cacheable := m.(*resty.Client)
+	defer evictRepository(m, key)
+	unsupported := false
+	if cacheable {
+		unsupported, _ = CachedValue[bool](restyClient, environmentsSupportCache, "unsupported")
+	}
+
+	if !unsupported {
+		resp, err := write(payload)
+		if err == nil || resp == nil || resp.StatusCode() != http.StatusBadRequest || !unsupportedEnvironmentsError.Match(resp.Body()) {
+			return resp, nil, err
+		}
+		logResponse(ctx, resp)
+		tflog.Warn(ctx, "Artifactory rejected the environments field, retrying without it")
+		if cacheable {
+			CacheValue(restyClient, environmentsSupportCache, "unsupported", true)
+		}
+	}
+
+	withoutEnvironments, environments, err := withoutField(payload, "environments")
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := write(withoutEnvironments)
+	if err != nil || len(environments) == 0 {
+		return resp, nil, err
+	}
+	return resp, diag.Diagnostics{{
+		Severity: diag.Warning,
+		Summary:  "Artifactory doesn't support environments",
+		Detail: fmt.Sprintf("This version of Artifactory rejects the environments field, so repository %s was %sd without it "+
+			"and project_environments %v aren't assigned. Upgrade Artifactory to assign environments to repositories", key, operation, environments),
+	}}, nil
+}
+
+// withoutField returns the JSON object of payload without the field name, and the value of the field
+func withoutField(payload interface{}, name string) (map[string]interface{}, []interface{}, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, nil, err
+	}
+	value, _ := fields[name].([]interface{})
+	delete(fields, name)
+	return fields, value, nil
+}
+
+func MkRepoCreate(unpack UnpackFunc, read schema.ReadContextFunc) schema.CreateContextFunc {
 
 	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 		repo, key, err := unpack(d)
 		if err != nil {
 			return diag.FromErr(err)
 		}
+		ctx = withRepoLogFields(ctx, "create", key, packageTypeOf(repo))
+		payload, err := payloadOf(repo)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		duplicate := duplicateKeyWarning(m, key, repo)
 		// repo must be a pointer
-		_, err = m.(*resty.Client).R().
-			AddRetryCondition(client.RetryOnMergeError).
-			SetBody(repo).
-			Put(RepositoriesEndpoint + key)
+		resp, unsupported, err := writeRepo(ctx, m, key, "create", payload, func(payload interface{}) (*resty.Response, error) {
+			return RepositoryClientOf(m).Create(key, payload)
+		})
+		logResponse(ctx, resp)
+		duplicate = append(duplicate, unsupported...)
 
 		if err != nil {
-			return diag.FromErr(err)
+			if colliding := caseCollidingKey(ctx, resp, m, key); colliding != "" {
+				return append(duplicate, withSupportHeaders(diag.Errorf("failed to create repository %s, its key collides with the existing repository %s as "+
+					"Artifactory compares repository keys case-insensitively. Use another key, or import %s: %s", key, colliding, colliding, err), resp)...)
+			}
+			return append(duplicate, writeError(err, resp, m, "create")...)
 		}
 		d.SetId(key)
-		return read(ctx, d, m)
+		return append(append(duplicate, ResponseWarnings(resp)...), read(ctx, d, m)...)
 	}
 }
 
 func mkRepoRead(pack PackFunc, construct Constructor) schema.ReadContextFunc {
 	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 		repo := construct()
-		// repo must be a pointer
-		resp, err := m.(*resty.Client).R().SetResult(repo).Get(RepositoriesEndpoint + d.Id())
+		expectedRclass := stringFieldOf(repo, "Rclass")
+		resp, err := getRepository(m, d.Id(), repo)
+		normalizePackageType(repo)
+		// the package type is computed, so straight after create it's only known from the response
+		packageType := packageTypeOf(repo)
+		if packageType == "" {
+			packageType, _ = d.Get("package_type").(string)
+		}
+		ctx = withRepoLogFields(ctx, "read", d.Id(), packageType)
+		logResponse(ctx, resp)
 
 		if err != nil {
-			if resp != nil && (resp.StatusCode() == http.StatusBadRequest || resp.StatusCode() == http.StatusNotFound) {
-				d.SetId("")
+			if RemoveIfNotFound(ctx, d, resp) {
 				return nil
 			}
-			return diag.FromErr(err)
+			return withSupportHeaders(diag.FromErr(err), resp)
 		}
-		return diag.FromErr(pack(repo, d))
+		// a key reused for a repository of another class must not be adopted, its configuration doesn't fit the schema
+		if rclass := stringFieldOf(repo, "Rclass"); expectedRclass != "" && rclass != expectedRclass {
+			return diag.Errorf("repository %s is a %s repository, but this resource manages %s repositories. "+
+				"Remove it from the state or import it with the matching resource type", d.Id(), rclass, expectedRclass)
+		}
+		return append(duplicateKeyWarning(m, d.Id(), repo), diag.FromErr(pack(repo, d))...)
 	}
 }
 
+// getRepository reads the configuration into repo, which must be a pointer. Repositories keeping the fields returned
+// are given them from the same response.
+func getRepository(m interface{}, key string, repo interface{}) (*resty.Response, error) {
+	var body json.RawMessage
+	resp, err := RepositoryClientOf(m).Get(key, &body)
+	if err != nil || len(body) == 0 {
+		return resp, err
+	}
+	if err := json.Unmarshal(body, repo); err != nil {
+		return resp, err
+	}
+	if served, ok := repo.(ServedFieldsRepository); ok {
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return resp, err
+		}
+		served.SetServedFields(fields)
+	}
+	return resp, nil
+}
+
 func mkRepoUpdate(unpack UnpackFunc, read schema.ReadContextFunc) schema.UpdateContextFunc {
 	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 		repo, key, err := unpack(d)
 		if err != nil {
 			return diag.FromErr(err)
 		}
+		ctx = withRepoLogFields(ctx, "update", d.Id(), packageTypeOf(repo))
+		payload, err := payloadOf(repo)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if diags := unassignRemovedProject(ctx, d, m); diags.HasError() {
+			return diags
+		}
 		// repo must be a pointer
-		_, err = m.(*resty.Client).R().
-			AddRetryCondition(client.RetryOnMergeError).
-			SetBody(repo).
-			Post(RepositoriesEndpoint + d.Id())
+		resp, unsupported, err := writeRepo(ctx, m, d.Id(), "update", payload, func(payload interface{}) (*resty.Response, error) {
+			return RepositoryClientOf(m).Update(d.Id(), payload)
+		})
+		logResponse(ctx, resp)
+		if err != nil {
+			return writeError(err, resp, m, "update")
+		}
+
+		d.SetId(key)
+		return append(append(append(projectReassignmentWarning(d), unsupported...), ResponseWarnings(resp)...), read(ctx, d, m)...)
+	}
+}
+
+// MkRepoPartialUpdate is like the default update, but merges the Terraform managed fields over the current server
+// configuration, so fields set outside Terraform that the schema doesn't model are preserved.
+func MkRepoPartialUpdate(unpack UnpackFunc, read schema.ReadContextFunc) schema.UpdateContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		repo, key, err := unpack(d)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		ctx = withRepoLogFields(ctx, "update", d.Id(), packageTypeOf(repo))
+
+		current := map[string]interface{}{}
+		resp, err := RepositoryClientOf(m).Get(d.Id(), &current)
+		logResponse(ctx, resp)
+		if err != nil {
+			return withSupportHeaders(diag.FromErr(err), resp)
+		}
+
+		merged, err := MergeManagedFields(current, repo)
 		if err != nil {
 			return diag.FromErr(err)
 		}
+		if diags := unassignRemovedProject(ctx, d, m); diags.HasError() {
+			return diags
+		}
+
+		resp, unsupported, err := writeRepo(ctx, m, d.Id(), "update", merged, func(payload interface{}) (*resty.Response, error) {
+			return RepositoryClientOf(m).Update(d.Id(), payload)
+		})
+		logResponse(ctx, resp)
+		if err != nil {
+			return writeError(err, resp, m, "update")
+		}
 
 		d.SetId(key)
-		return read(ctx, d, m)
+		return append(append(append(projectReassignmentWarning(d), unsupported...), ResponseWarnings(resp)...), read(ctx, d, m)...)
 	}
 }
 
-func deleteRepo(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	resp, err := m.(*resty.Client).R().
-		AddRetryCondition(client.RetryOnMergeError).
-		Delete(RepositoriesEndpoint + d.Id())
+// MergeManagedFields overlays the JSON fields of the repository struct on the current server configuration. Every
+// field modeled by the struct is replaced, even when omitted from its JSON (e.g. an emptied optional string), so only
+// fields unknown to the struct keep their server value.
+func MergeManagedFields(current map[string]interface{}, repo interface{}) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(repo)
+	if err != nil {
+		return nil, err
+	}
+	managed := map[string]interface{}{}
+	if err := json.Unmarshal(encoded, &managed); err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]interface{}, len(current)+len(managed))
+	for name, value := range current {
+		merged[name] = value
+	}
+	for _, name := range jsonFieldNames(reflect.TypeOf(repo)) {
+		delete(merged, name)
+	}
+	for name, value := range managed {
+		merged[name] = value
+	}
+	if withReset, ok := repo.(ResetFieldsRepository); ok {
+		for _, name := range withReset.ResetFields() {
+			if _, set := managed[name]; !set {
+				merged[name] = ""
+			}
+		}
+	}
+	if withExtra, ok := repo.(ExtraAttributesRepository); ok {
+		managedNames := jsonFieldNames(reflect.TypeOf(repo))
+		for name, value := range withExtra.ExtraAttributes() {
+			if slices.Contains(managedNames, name) {
+				return nil, fmt.Errorf("extra attribute %s is managed by the resource, set its attribute instead", name)
+			}
+			merged[name] = value
+		}
+	}
+	return merged, nil
+}
+
+// UnmanagedFields returns the fields of the configuration current that the repository struct doesn't model, i.e. those
+// MergeManagedFields keeps
+func UnmanagedFields(current map[string]interface{}, repo interface{}) map[string]interface{} {
+	managedNames := jsonFieldNames(reflect.TypeOf(repo))
+	unmanaged := map[string]interface{}{}
+	for name, value := range current {
+		if !slices.Contains(managedNames, name) {
+			unmanaged[name] = value
+		}
+	}
+	return unmanaged
+}
+
+// ExtraAttributesRepository is implemented by the repository structs that carry JSON fields the provider doesn't model,
+// e.g. fields added by a newer Artifactory version. They are merged into the payload sent to Artifactory.
+type ExtraAttributesRepository interface {
+	ExtraAttributes() map[string]interface{}
+}
+
+// ServedFieldsRepository is implemented by the repository structs that keep the JSON fields returned by Artifactory on
+// read, e.g. to read back fields they don't model.
+type ServedFieldsRepository interface {
+	SetServedFields(fields map[string]interface{})
+}
+
+// ResetFieldsRepository is implemented by the repository structs that track the attributes removed from the
+// configuration. Their JSON fields are sent empty, as the structs omit empty strings and Artifactory leaves omitted
+// fields unchanged, so the removed value would otherwise stay.
+type ResetFieldsRepository interface {
+	ResetFields() []string
+}
+
+// ResettableFields maps the optional string attributes cleared in Artifactory when removed from the configuration to
+// their JSON field. `default_deployment_repo` isn't one of them, Artifactory ignores it when empty, see
+// HandleResetWithNonExistantValue.
+var ResettableFields = map[string]string{
+	"description":     "description",
+	"notes":           "notes",
+	"repo_layout_ref": "repoLayoutRef",
+}
+
+// GetResetFields returns the JSON fields of the attributes of ResettableFields changed to an empty string, sorted
+func GetResetFields(d *util.ResourceData) []string {
+	var fields []string
+	for attribute, field := range ResettableFields {
+		if value, ok := d.Get(attribute).(string); ok && value == "" && d.HasChange(attribute) {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// payloadOf returns the payload to send for repo, which is repo itself unless it carries extra attributes or fields
+// to reset
+func payloadOf(repo interface{}) (interface{}, error) {
+	withExtra, hasExtra := repo.(ExtraAttributesRepository)
+	withReset, hasReset := repo.(ResetFieldsRepository)
+	if (!hasExtra || len(withExtra.ExtraAttributes()) == 0) && (!hasReset || len(withReset.ResetFields()) == 0) {
+		return repo, nil
+	}
+	return MergeManagedFields(map[string]interface{}{}, repo)
+}
+
+// jsonFieldNames lists the JSON names of the fields of a struct, including those promoted from embedded structs
+func jsonFieldNames(t reflect.Type) []string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		switch {
+		case name == "-":
+			continue
+		case field.Anonymous && name == "":
+			names = append(names, jsonFieldNames(field.Type)...)
+		case name != "":
+			names = append(names, name)
+		case field.IsExported():
+			names = append(names, field.Name)
+		}
+	}
+	return names
+}
+
+func deleteRepo(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	packageType, _ := d.Get("package_type").(string)
+	ctx = withRepoLogFields(ctx, "delete", d.Id(), packageType)
+	resp, err := RepositoryClientOf(m).Delete(d.Id())
+	logResponse(ctx, resp)
+
+	if err != nil && RemoveIfNotFound(ctx, d, resp) {
+		releaseRepoKey(m, d.Id())
+		return nil
+	}
+	if err != nil {
+		return writeError(err, resp, m, "delete")
+	}
+	releaseRepoKey(m, d.Id())
+	return nil
+}
+
+// duplicateKeyWarning claims key for the type of repo, e.g. "local npm", and warns when a resource of another type
+// already claimed it. Claims are kept in the cache of the client, so resources of different types sharing a key within
+// one run are reported: Artifactory keys are unique across all repository types. The first claim is kept, so every
+// other resource using the key is reported. The check is advisory and skipped for clients not built by the provider.
+func duplicateKeyWarning(m interface{}, key string, repo interface{}) diag.Diagnostics {
+	restyClient, ok := m.(*resty.Client)
+	if !ok {
+		return nil
+	}
+	repoType := strings.TrimSpace(stringFieldOf(repo, "Rclass") + " " + packageTypeOf(repo))
 
-	if err != nil && (resp != nil && (resp.StatusCode() == http.StatusBadRequest || resp.StatusCode() == http.StatusNotFound)) {
-		d.SetId("")
+	claimedBy, claimed := CacheValue(restyClient, repoKeyClaimCache, key, repoType)
+	if claimed || claimedBy == repoType {
 		return nil
 	}
-	return diag.FromErr(err)
+	return diag.Diagnostics{{
+		Severity: diag.Warning,
+		Summary:  "Repository key used by several repository types",
+		Detail: fmt.Sprintf("The key %s is used by a %s repository and a %s repository in this configuration. "+
+			"Repository keys are unique across all repository types, only one of them can exist in Artifactory.", key, claimedBy, repoType),
+		AttributePath: cty.GetAttrPath("key"),
+	}}
+}
+
+func releaseRepoKey(m interface{}, key string) {
+	if restyClient, ok := m.(*resty.Client); ok {
+		EvictCached(restyClient, repoKeyClaimCache, key)
+	}
+	evictRepository(m, key)
+}
+
+// TokenScope returns the scope (`scp` claim) of the access token the client authenticates with, or "" when the client
+// uses an API key or the token can't be decoded. Only the claims are decoded, the signature isn't verified.
+func TokenScope(restyClient *resty.Client) string {
+	parts := strings.Split(restyClient.Token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return ""
+	}
+
+	claims := struct {
+		Scope string `json:"scp"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+	return claims.Scope
+}
+
+// IsMaintenanceMode reports whether a write was rejected because Artifactory is in read-only or maintenance mode, which
+// it answers with a 503 mentioning it, e.g. during an upgrade.
+func IsMaintenanceMode(resp *resty.Response) bool {
+	if resp == nil || resp.StatusCode() != http.StatusServiceUnavailable {
+		return false
+	}
+	body := strings.ToLower(resp.String())
+	for _, mention := range []string{"maintenance", "read-only", "read only", "readonly"} {
+		if strings.Contains(body, mention) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeError explains a failed write and adds the request identifying headers of the response for support tickets
+func writeError(err error, resp *resty.Response, m interface{}, operation string) diag.Diagnostics {
+	return withSupportHeaders(explainWriteError(err, resp, m, operation), resp)
+}
+
+// explainWriteError explains a forbidden write with the scope of the token, read-only tokens are otherwise only told
+// Artifactory's terse 403. Writes rejected in maintenance mode are explained as well.
+func explainWriteError(err error, resp *resty.Response, m interface{}, operation string) diag.Diagnostics {
+	if IsMaintenanceMode(resp) {
+		return diag.Errorf("failed to %s the repository, Artifactory is in read-only or maintenance mode and rejects changes. "+
+			"Nothing was changed, apply again once the instance accepts writes: %s", operation, err)
+	}
+	if resp == nil || resp.StatusCode() != http.StatusForbidden {
+		return diag.FromErr(err)
+	}
+
+	scope := ""
+	if restyClient, ok := m.(*resty.Client); ok {
+		scope = TokenScope(restyClient)
+	}
+	if scope == "" {
+		return diag.Errorf("permission denied to %s the repository, the credentials need the 'Manage' permission on repositories (admin for most settings): %s", operation, err)
+	}
+	return diag.Errorf("permission denied to %s the repository with an access token of scope %q. Read operations work with this token, "+
+		"but %s requires a token scoped to applied-permissions/admin or to a group with the 'Manage' permission on repositories: %s", operation, scope, operation, err)
+}
+
+// caseCollidingKey returns the key of an existing repository that only differs from key by case, when the create of key
+// was rejected as a conflict. Artifactory rejects such keys without naming the colliding repository.
+func caseCollidingKey(ctx context.Context, resp *resty.Response, m interface{}, key string) string {
+	restyClient, ok := m.(*resty.Client)
+	if !ok || resp == nil || (resp.StatusCode() != http.StatusConflict && resp.StatusCode() != http.StatusBadRequest) {
+		return ""
+	}
+
+	var repos []struct {
+		Key string `json:"key"`
+	}
+	listResp, err := restyClient.R().SetResult(&repos).Get("artifactory/api/repositories")
+	logResponse(ctx, listResp)
+	if err != nil {
+		tflog.Debug(ctx, fmt.Sprintf("failed to list repositories to check for key collisions: %s", err))
+		return ""
+	}
+	for _, repo := range repos {
+		if repo.Key != key && strings.EqualFold(repo.Key, key) {
+			return repo.Key
+		}
+	}
+	return ""
+}
+
+// ProjectAttachEndpoint assigns repositories to projects. An emptied `projectKey` in the repository configuration
+// isn't guaranteed to remove the assignment, the Access API is.
+const ProjectAttachEndpoint = "access/api/v1/projects/_/attach/repositories/"
+
+// unassignRemovedProject removes the repository from its project when `project_key` was removed from the
+// configuration, so the repository doesn't stay assigned to the project it was in.
+func unassignRemovedProject(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	old, new := d.GetChange("project_key")
+	oldProjectKey, _ := old.(string)
+	newProjectKey, _ := new.(string)
+	if oldProjectKey == "" || newProjectKey != "" {
+		return nil
+	}
+	// the assignment is only reachable through the Access API of the provider client, not a RepositoryClient
+	restyClient, err := RestyClientOf(m, "unassigning the repository from its project")
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	resp, err := restyClient.R().Delete(ProjectAttachEndpoint + d.Id())
+	logResponse(ctx, resp)
+	if err != nil && !IsNotFound(resp) {
+		return diag.Errorf("failed to unassign repository %s from project %s: %s", d.Id(), oldProjectKey, err)
+	}
+	return nil
+}
+
+// withRepoLogFields attaches the repository key, package type and CRUD operation to every log line written with the
+// returned context, so TF_LOG output of a large apply can be filtered down to a single repository.
+func withRepoLogFields(ctx context.Context, operation, key, packageType string) context.Context {
+	ctx = tflog.With(ctx, "operation", operation)
+	ctx = tflog.With(ctx, "repo_key", key)
+	return tflog.With(ctx, "package_type", packageType)
+}
+
+// logResponse logs a summary of the request, the response status and the time the last attempt took. The request and
+// response bodies are left out on purpose, they may contain credentials (e.g. the password of a remote repository).
+func logResponse(ctx context.Context, resp *resty.Response) {
+	if resp == nil || resp.Request == nil || resp.Request.RawRequest == nil {
+		return
+	}
+	tflog.Debug(ctx, "repository request completed", map[string]interface{}{
+		"method":      resp.Request.Method,
+		"path":        resp.Request.RawRequest.URL.Path,
+		"status":      resp.StatusCode(),
+		"duration_ms": resp.Time().Milliseconds(),
+	})
+}
+
+// ResponseWarnings returns the `warnings` of a successful write response as warning diagnostics. Artifactory answers
+// most writes with plain text, but may list warnings in a JSON body, e.g. about deprecated settings, either as strings
+// or as objects with a message. Bodies without warnings, or that aren't JSON, return none.
+func ResponseWarnings(resp *resty.Response) diag.Diagnostics {
+	if resp == nil || len(resp.Body()) == 0 {
+		return nil
+	}
+
+	var body struct {
+		Warnings []json.RawMessage `json:"warnings"`
+	}
+	if err := json.Unmarshal(resp.Body(), &body); err != nil {
+		return nil
+	}
+
+	var diags diag.Diagnostics
+	for _, warning := range body.Warnings {
+		var message string
+		if err := json.Unmarshal(warning, &message); err != nil {
+			var object struct {
+				Message string `json:"message"`
+			}
+			if err := json.Unmarshal(warning, &object); err != nil || object.Message == "" {
+				message = string(warning)
+			} else {
+				message = object.Message
+			}
+		}
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "Artifactory returned a warning",
+			Detail:   message,
+		})
+	}
+	return diags
+}
+
+// SupportHeaders are the response headers identifying a request, which JFrog support asks for to find it in the logs
+var SupportHeaders = []string{"X-Request-Id", "X-B3-TraceId", "X-Artifactory-Id", "X-Artifactory-Node-Id"}
+
+// withSupportHeaders adds the SupportHeaders of the response to the detail of the error diagnostics
+func withSupportHeaders(diags diag.Diagnostics, resp *resty.Response) diag.Diagnostics {
+	if resp == nil {
+		return diags
+	}
+
+	var headers []string
+	for _, name := range SupportHeaders {
+		if value := resp.Header().Get(name); value != "" {
+			headers = append(headers, fmt.Sprintf("%s: %s", name, value))
+		}
+	}
+	if len(headers) == 0 {
+		return diags
+	}
+
+	detail := "Include these response headers when contacting JFrog support: " + strings.Join(headers, ", ")
+	for i := range diags {
+		if diags[i].Severity != diag.Error {
+			continue
+		}
+		if diags[i].Detail != "" {
+			diags[i].Detail += "\n\n"
+		}
+		diags[i].Detail += detail
+	}
+	return diags
+}
+
+// packageTypeOf returns the PackageType field of the repository struct, which all repository payloads carry
+func packageTypeOf(repo interface{}) string {
+	return stringFieldOf(repo, "PackageType")
+}
+
+// normalizePackageType lowercases the package type read into repo. Artifactory may return it in another case, e.g.
+// "Maven", while the resources and the state use the lowercase package types, the computed package_type would drift.
+func normalizePackageType(repo interface{}) {
+	value := reflect.Indirect(reflect.ValueOf(repo))
+	if value.Kind() != reflect.Struct {
+		return
+	}
+	field := value.FieldByName("PackageType")
+	if field.IsValid() && field.Kind() == reflect.String && field.CanSet() {
+		field.SetString(strings.ToLower(field.String()))
+	}
+}
+
+func stringFieldOf(repo interface{}, name string) string {
+	value := reflect.Indirect(reflect.ValueOf(repo))
+	if value.Kind() != reflect.Struct {
+		return ""
+	}
+	field := value.FieldByName(name)
+	if !field.IsValid() || field.Kind() != reflect.String {
+		return ""
+	}
+	return field.String()
+}
+
+// IsNotFound reports whether the response says the repository doesn't exist. Only a 404 does, a 400 may as well be a
+// rejected request, e.g. a delete blocked by a dependency, which must be surfaced.
+func IsNotFound(resp *resty.Response) bool {
+	return resp != nil && resp.StatusCode() == http.StatusNotFound
+}
+
+// RemoveIfNotFound removes the resource from the state when the repository was deleted out-of-band, so the next plan
+// recreates it instead of failing. Returns false when the response is any other error, which must still be surfaced.
+func RemoveIfNotFound(ctx context.Context, d *schema.ResourceData, resp *resty.Response) bool {
+	if !IsNotFound(resp) {
+		return false
+	}
+
+	tflog.Warn(ctx, fmt.Sprintf("repository %s not found, removing it from the state", d.Id()))
+	d.SetId("")
+	return true
 }
 
 func Retry400(response *resty.Response, err error) bool {
@@ -147,6 +788,38 @@ var RepoKeyValidator = validation.All(
 	validation.StringDoesNotContainAny(" !@#$%^&*()+={}[]:;<>,/?~`|\\"),
 )
 
+// ValidateProjectKey checks `project_key` against the format of the project keys of Artifactory, 3 to 10 lowercase
+// letters or digits. Unlike the shared validator, the error quotes the value and says what's wrong with it.
+func ValidateProjectKey(value interface{}, path cty.Path) diag.Diagnostics {
+	projectKey, ok := value.(string)
+	if !ok {
+		return diag.Errorf("expected type of project_key to be string")
+	}
+
+	invalid := func(detail string) diag.Diagnostics {
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "Invalid project key",
+			Detail:        detail + ", project keys are 3 to 10 lowercase letters or digits",
+			AttributePath: path,
+		}}
+	}
+
+	for _, char := range projectKey {
+		switch {
+		case char >= 'a' && char <= 'z', char >= '0' && char <= '9':
+		case char >= 'A' && char <= 'Z':
+			return invalid(fmt.Sprintf("project_key %q contains the uppercase letter %q, use %q instead", projectKey, char, strings.ToLower(projectKey)))
+		default:
+			return invalid(fmt.Sprintf("project_key %q contains the character %q", projectKey, char))
+		}
+	}
+	if length := len(projectKey); length < 3 || length > 10 {
+		return invalid(fmt.Sprintf("project_key %q is %d characters long", projectKey, length))
+	}
+	return nil
+}
+
 var RepoTypesSupported = []string{
 	"alpine",
 	"bower",
@@ -187,17 +860,44 @@ var GradleLikeRepoTypes = []string{
 
 var ProjectEnvironmentsSupported = []string{"DEV", "PROD"}
 
+// LayoutImmutablePackageTypes are the package types whose repository paths are laid out by the client protocol, so
+// their `repo_layout_ref` is only set on create. Changing it fails the plan rather than replacing the repository.
+var LayoutImmutablePackageTypes = []string{"docker", "helm"}
+
 func RepoLayoutRefSchema(repositoryType string, packageType string) map[string]*schema.Schema {
 	return map[string]*schema.Schema{
 		"repo_layout_ref": {
-			Type:        schema.TypeString,
-			Optional:    true,
-			DefaultFunc: GetDefaultRepoLayoutRef(repositoryType, packageType),
-			Description: "Repository layout key for the local repository",
+			Type:             schema.TypeString,
+			Optional:         true,
+			DefaultFunc:      GetDefaultRepoLayoutRef(repositoryType, packageType),
+			DiffSuppressFunc: RepoLayoutRefDiffSuppress(repositoryType, packageType),
+			ValidateDiagFunc: ValidateRepoLayoutRef,
+			Description:      "Repository layout key for the local repository",
 		},
 	}
 }
 
+// RepoLayoutRefDiffSuppress ignores casing differences, and an empty configured layout when the server has the default
+// layout of the package type, which Artifactory assigns when none is set.
+func RepoLayoutRefDiffSuppress(repositoryType string, packageType string) schema.SchemaDiffSuppressFunc {
+	return func(k, old, new string, d *schema.ResourceData) bool {
+		if IgnoreCaseDiff(k, old, new, d) {
+			return true
+		}
+		if new != "" {
+			return false
+		}
+		defaultLayout, err := GetDefaultRepoLayoutRef(repositoryType, packageType)()
+		return err == nil && strings.EqualFold(old, defaultLayout.(string))
+	}
+}
+
+// IgnoreCaseDiff suppresses diffs that only differ in casing, e.g. layout refs which older Artifactory versions return
+// with a different casing than configured.
+func IgnoreCaseDiff(_, old, new string, _ *schema.ResourceData) bool {
+	return strings.EqualFold(old, new)
+}
+
 // Special handling for field that requires non-existant value for RT
 //
 // Artifactory REST API will not accept empty string or null to reset value to not set
@@ -433,24 +1133,169 @@ func UniversalPack(predicate util.HclPredicate) PackFunc {
 	}
 }
 
-func projectEnvironmentsDiff(_ context.Context, diff *schema.ResourceDiff, i interface{}) error {
-	if data, ok := diff.GetOk("project_environments"); ok {
-		projectEnvironments := data.(*schema.Set).List()
+// projectKeyChange returns the old and new `project_key` when the repository moves from one project to another,
+// adding it to a project or removing it from its project doesn't count.
+func projectKeyChange(getChange func(string) (interface{}, interface{})) (string, string, bool) {
+	old, new := getChange("project_key")
+	oldProjectKey, _ := old.(string)
+	newProjectKey, _ := new.(string)
+	return oldProjectKey, newProjectKey, oldProjectKey != "" && newProjectKey != "" && oldProjectKey != newProjectKey
+}
 
-		for _, projectEnvironment := range projectEnvironments {
-			if !slices.Contains(ProjectEnvironmentsSupported, projectEnvironment.(string)) {
-				return fmt.Errorf("project_environment %s not allowed", projectEnvironment)
-			}
-		}
+// ProjectKeyChangeDiff replaces the repository when it moves to another project and `replace_on_project_key_change`
+// is set, some Artifactory versions can't reassign a repository in place. Otherwise the update warns about it.
+func ProjectKeyChangeDiff(_ context.Context, diff *schema.ResourceDiff, _ interface{}) error {
+	if !ReplacesOnProjectKeyChange(diff) {
+		return nil
+	}
+	return diff.ForceNew("project_key")
+}
+
+// ReplacesOnProjectKeyChange tells whether ProjectKeyChangeDiff replaces the repository.
+func ReplacesOnProjectKeyChange(diff *schema.ResourceDiff) bool {
+	if _, _, moved := projectKeyChange(diff.GetChange); diff.Id() == "" || !moved {
+		return false
+	}
+	replace, _ := diff.Get("replace_on_project_key_change").(bool)
+	return replace
+}
+
+// ProjectEndpoint reads a project of the Access API
+const ProjectEndpoint = "access/api/v1/projects/{projectKey}"
+
+// ProjectExistsDiff fails the plan when `project_key` is changed to a project that doesn't exist, which Artifactory
+// rejects at apply with a bare 400 or 404. Only a 404 fails the plan, other lookup errors are left to the apply, e.g.
+// credentials that can't read projects.
+func ProjectExistsDiff(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	restyClient, ok := m.(*resty.Client)
+	if !ok || !diff.HasChange("project_key") || !diff.NewValueKnown("project_key") {
+		return nil
+	}
+	projectKey, _ := diff.Get("project_key").(string)
+	if projectKey == "" {
+		return nil
 	}
 
+	// the projects found are cached, planning many repositories of a project looks it up once. Missing projects aren't,
+	// the plan fails on them anyway.
+	missing := false
+	_, err := CachedLookup(restyClient, projectCache, projectKey, func() (bool, error) {
+		resp, err := restyClient.R().
+			AddRetryCondition(client.NeverRetry).
+			SetPathParam("projectKey", projectKey).
+			Get(ProjectEndpoint)
+		missing = err != nil && resp != nil && resp.StatusCode() == http.StatusNotFound
+		return err == nil, err
+	})
+	if missing {
+		return fmt.Errorf("project '%s' does not exist", projectKey)
+	}
+	if err != nil {
+		tflog.Debug(ctx, fmt.Sprintf("failed to check that project %s exists: %v", projectKey, err))
+	}
 	return nil
 }
 
+// projectReassignmentWarning warns about an in-place move of the repository to another project, which may leave it
+// assigned to neither project on Artifactory versions that don't support it.
+func projectReassignmentWarning(d *schema.ResourceData) diag.Diagnostics {
+	oldProjectKey, newProjectKey, moved := projectKeyChange(d.GetChange)
+	if !moved {
+		return nil
+	}
+	return diag.Diagnostics{{
+		Severity: diag.Warning,
+		Summary:  "Repository reassigned to another project in place",
+		Detail: fmt.Sprintf("The repository %s was moved from project %s to project %s without being recreated. "+
+			"Some Artifactory versions don't support moving a repository between projects and may leave it in neither, "+
+			"check its project assignment or set replace_on_project_key_change to recreate it instead.", d.Id(), oldProjectKey, newProjectKey),
+		AttributePath: cty.GetAttrPath("project_key"),
+	}}
+}
+
+// ProjectEnvironmentsEndpoint lists the environments of a project, global environments included
+const ProjectEnvironmentsEndpoint = "access/api/v1/projects/{projectKey}/environments"
+
+// GetCachedProjectEnvironments returns the names of the environments defined in the project, global ones included,
+// looking them up once per client, planning many repositories of a project lists its environments once.
+func GetCachedProjectEnvironments(projectKey string, restyClient *resty.Client) ([]string, error) {
+	return CachedLookup(restyClient, projectEnvironmentsCache, projectKey, func() ([]string, error) {
+		var environments []struct {
+			Name string `json:"name"`
+		}
+		_, err := restyClient.R().
+			AddRetryCondition(client.NeverRetry).
+			SetPathParam("projectKey", projectKey).
+			SetResult(&environments).
+			Get(ProjectEnvironmentsEndpoint)
+		if err != nil {
+			return nil, err
+		}
+		names := []string{}
+		for _, environment := range environments {
+			names = append(names, environment.Name)
+		}
+		return names, nil
+	})
+}
+
+// projectEnvironmentsDiff fails the plan on `project_environments` the project doesn't define. Projects can define
+// their own environments, when `project_key` is set they're looked up when either attribute changes, and lookup errors
+// are left to the apply. Without a project only the global environments are allowed.
+func projectEnvironmentsDiff(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	data, ok := diff.GetOk("project_environments")
+	if !ok || !diff.NewValueKnown("project_environments") || !diff.NewValueKnown("project_key") {
+		return nil
+	}
+
+	allowed := ProjectEnvironmentsSupported
+	projectKey, _ := diff.Get("project_key").(string)
+	if projectKey != "" {
+		restyClient, ok := m.(*resty.Client)
+		if !ok || !diff.HasChanges("project_environments", "project_key") {
+			return nil
+		}
+		names, err := GetCachedProjectEnvironments(projectKey, restyClient)
+		if err != nil {
+			tflog.Debug(ctx, fmt.Sprintf("failed to read environments of project %s: %v", projectKey, err))
+			return nil
+		}
+		allowed = names
+	}
+
+	var unknown []string
+	for _, projectEnvironment := range data.(*schema.Set).List() {
+		if !slices.Contains(allowed, projectEnvironment.(string)) {
+			unknown = append(unknown, projectEnvironment.(string))
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	if projectKey == "" {
+		return fmt.Errorf("project_environment %s not allowed, the environments are: %s", strings.Join(unknown, ", "), strings.Join(allowed, ", "))
+	}
+	return fmt.Errorf("project_environment %s not allowed, the environments of project %s are: %s", strings.Join(unknown, ", "), projectKey, strings.Join(allowed, ", "))
+}
+
+// GetProjectEnvironments returns the `project_environments` set sorted. Sets have no order while Artifactory stores the
+// environments as a list, sorting keeps the payload the same whatever order the set was built in.
+// Unset environments are nil, sent as null so Artifactory applies its defaults, while a cleared set is always an empty
+// list: Artifactory keeps the environments on a null, the assignment would never be removed.
+func GetProjectEnvironments(d *util.ResourceData) []string {
+	environments := d.GetSet("project_environments")
+	if environments == nil && d.HasChange("project_environments") {
+		environments = []string{}
+	}
+	sort.Strings(environments)
+	return environments
+}
+
 func MkResourceSchema(skeema map[string]*schema.Schema, packer PackFunc, unpack UnpackFunc, constructor Constructor) *schema.Resource {
 	var reader = mkRepoRead(packer, constructor)
 	return &schema.Resource{
-		CreateContext: mkRepoCreate(unpack, reader),
+		CreateContext: MkRepoCreate(unpack, reader),
 		ReadContext:   reader,
 		UpdateContext: mkRepoUpdate(unpack, reader),
 		DeleteContext: deleteRepo,
@@ -459,7 +1304,7 @@ func MkResourceSchema(skeema map[string]*schema.Schema, packer PackFunc, unpack
 		},
 
 		Schema:        skeema,
-		CustomizeDiff: projectEnvironmentsDiff,
+		CustomizeDiff: customdiff.All(projectEnvironmentsDiff, ProjectKeyChangeDiff, ProjectExistsDiff),
 	}
 }
 
@@ -499,6 +1344,403 @@ func CheckRepo(id string, request *resty.Request) (*resty.Response, error) {
 	return request.Head(RepositoriesEndpoint + id)
 }
 
+// RepoInfo holds the configuration attributes shared by all repository classes. It is used to inspect repositories
+// that aren't managed by the resource at hand, e.g. the members of a virtual repository.
+type RepoInfo struct {
+	Key         string `json:"key"`
+	Rclass      string `json:"rclass"`
+	PackageType string `json:"packageType"`
+	Offline     bool   `json:"offline"`
+	BlackedOut  bool   `json:"blackedOut"`
+}
+
+// IsOnline reports whether Artifactory will try to resolve artifacts from the repository. Only remote repositories
+// can be taken offline or blacked out.
+func (r RepoInfo) IsOnline() bool {
+	return r.Rclass != "remote" || (!r.Offline && !r.BlackedOut)
+}
+
+func GetRepoInfo(key string, restyClient *resty.Client) (*RepoInfo, *resty.Response, error) {
+	info := RepoInfo{}
+	resp, err := restyClient.R().
+		AddRetryCondition(client.NeverRetry).
+		SetResult(&info).
+		Get(RepositoriesEndpoint + key)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &info, resp, nil
+}
+
+// VirtualRepositorySummary is a virtual repository of the repository listing, with the members the listing doesn't return
+type VirtualRepositorySummary struct {
+	Key          string   `json:"key"`
+	PackageType  string   `json:"packageType"`
+	Description  string   `json:"description"`
+	Url          string   `json:"url"`
+	Repositories []string `json:"-"`
+}
+
+// ListVirtualRepositories lists the virtual repositories, only those of packageType and of the project of projectKey
+// when set. The list endpoint returns all repositories at once, members are only part of each repository
+// configuration, so the configuration of every listed repository is read.
+func ListVirtualRepositories(restyClient *resty.Client, packageType, projectKey string) ([]VirtualRepositorySummary, error) {
+	request := restyClient.R().SetQueryParam("type", "virtual")
+	if packageType != "" {
+		request.SetQueryParam("packageType", packageType)
+	}
+	if projectKey != "" {
+		request.SetQueryParam("project", projectKey)
+	}
+
+	var summaries []VirtualRepositorySummary
+	if _, err := request.SetResult(&summaries).Get(strings.TrimSuffix(RepositoriesEndpoint, "/")); err != nil {
+		return nil, err
+	}
+	for i, summary := range summaries {
+		members := struct {
+			Repositories []string `json:"repositories"`
+		}{}
+		if _, err := restyClient.R().SetResult(&members).Get(RepositoriesEndpoint + summary.Key); err != nil {
+			return nil, fmt.Errorf("failed to read members of virtual repository %s: %w", summary.Key, err)
+		}
+		summaries[i].Repositories = members.Repositories
+	}
+	return summaries, nil
+}
+
+const AqlEndpoint = "artifactory/api/search/aql"
+
+// HasArtifacts reports whether the repository stores at least one file. Only a single item is requested, so the check
+// stays cheap on large repositories.
+func HasArtifacts(key string, restyClient *resty.Client) (bool, error) {
+	result := struct {
+		Results []interface{} `json:"results"`
+	}{}
+	_, err := restyClient.R().
+		SetHeader("Content-Type", "text/plain").
+		SetBody(fmt.Sprintf(`items.find({"repo":%q,"type":"file"}).include("name").limit(1)`, key)).
+		SetResult(&result).
+		Post(AqlEndpoint)
+	if err != nil {
+		return false, err
+	}
+	return len(result.Results) > 0, nil
+}
+
+// GetMemberInfo looks up the repository key, e.g. a member of a virtual repository, through the RepositoryClient of the
+// provider meta. Lookups through the provider client are cached after the first successful one, so checking many
+// virtual repositories sharing members reads each member once per provider run. The response is only returned for
+// lookups sent, not for cached ones.
+func GetMemberInfo(m interface{}, key string) (*RepoInfo, *resty.Response, error) {
+	if restyClient, ok := m.(*resty.Client); ok {
+		if info, ok := CachedValue[*RepoInfo](restyClient, repoInfoCache, key); ok {
+			return info, nil, nil
+		}
+		info, resp, err := GetRepoInfo(key, restyClient)
+		if err != nil {
+			return nil, resp, err
+		}
+		cached, _ := CacheValue(restyClient, repoInfoCache, key, info)
+		return cached.(*RepoInfo), resp, nil
+	}
+
+	info := RepoInfo{}
+	resp, err := RepositoryClientOf(m).Get(key, &info)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &info, resp, nil
+}
+
+// javaPackageTypes share the maven layout, a virtual repository of one of them may aggregate any of the others
+var javaPackageTypes = map[string]bool{"maven": true, "gradle": true, "ivy": true, "sbt": true}
+
+// compositeMemberPackageTypes are the other package types a virtual repository of a package type may aggregate, e.g. a P2
+// virtual repository composes update sites from P2 repositories and from generic repositories hosting them
+var compositeMemberPackageTypes = map[string][]string{"p2": {"generic"}}
+
+// IsCompatibleMemberPackageType reports whether a virtual repository of packageType can aggregate a member of
+// memberPackageType.
+func IsCompatibleMemberPackageType(packageType, memberPackageType string) bool {
+	if strings.EqualFold(packageType, memberPackageType) {
+		return true
+	}
+	if slices.Contains(compositeMemberPackageTypes[strings.ToLower(packageType)], strings.ToLower(memberPackageType)) {
+		return true
+	}
+	return javaPackageTypes[strings.ToLower(packageType)] && javaPackageTypes[strings.ToLower(memberPackageType)]
+}
+
+// memberPackageTypes lists the package types of the members packageType can aggregate, for error messages
+func memberPackageTypes(packageType string) string {
+	return strings.Join(append([]string{packageType}, compositeMemberPackageTypes[packageType]...), " or ")
+}
+
+// ValidateMembersPackageType returns an error listing the members a virtual repository of expectedType can't aggregate,
+// with their package type. Members are looked up through GetMemberInfo, those that can't be, e.g. created in the
+// same apply, are skipped. Virtual resources call it from their CustomizeDiff.
+func ValidateMembersPackageType(ctx context.Context, m interface{}, members []string, expectedType string) diag.Diagnostics {
+	var incompatible []string
+	for _, member := range members {
+		info, _, err := GetMemberInfo(m, member)
+		if err != nil {
+			tflog.Debug(ctx, fmt.Sprintf("failed to check package type of member repository %s: %v", member, err))
+			continue
+		}
+		if !IsCompatibleMemberPackageType(expectedType, info.PackageType) {
+			incompatible = append(incompatible, fmt.Sprintf("%s (%s)", member, info.PackageType))
+		}
+	}
+
+	if len(incompatible) == 0 {
+		return nil
+	}
+	return diag.Diagnostics{{
+		Severity: diag.Error,
+		Summary:  "Incompatible member repositories",
+		Detail: fmt.Sprintf("a %s virtual repository can only aggregate %s repositories, these members are not: %s",
+			expectedType, memberPackageTypes(expectedType), strings.Join(incompatible, ", ")),
+		AttributePath: cty.GetAttrPath("repositories"),
+	}}
+}
+
+type RepoLayout struct {
+	Name                             string `xml:"name"`
+	ArtifactPathPattern              string `xml:"artifactPathPattern"`
+	DistinctiveDescriptorPathPattern bool   `xml:"distinctiveDescriptorPathPattern"`
+	DescriptorPathPattern            string `xml:"descriptorPathPattern"`
+}
+
+type RepoLayouts struct {
+	Layouts []RepoLayout `xml:"repoLayouts>repoLayout"`
+}
+
+// RepoLayoutNotFoundError is returned by GetRepoLayout when the system configuration has no layout of that name
+type RepoLayoutNotFoundError struct {
+	Name string
+	// Available are the names of the layouts of the system configuration
+	Available []string
+}
+
+func (e *RepoLayoutNotFoundError) Error() string {
+	return fmt.Sprintf("repository layout %s not found", e.Name)
+}
+
+// GetRepoLayout looks up a repository layout by name in the system configuration. The lookup ignores casing like
+// Artifactory does. Reading the system configuration requires admin permissions. It is the whole configuration, so its
+// layouts are cached for the client, and only read again when the name isn't one of them, e.g. a layout created since.
+func GetRepoLayout(name string, restyClient *resty.Client) (*RepoLayout, error) {
+	layout, err := findRepoLayout(name, restyClient)
+	if _, notFound := err.(*RepoLayoutNotFoundError); notFound {
+		EvictCached(restyClient, repoLayoutsCache, "")
+		layout, err = findRepoLayout(name, restyClient)
+	}
+	return layout, err
+}
+
+func findRepoLayout(name string, restyClient *resty.Client) (*RepoLayout, error) {
+	layouts, err := CachedLookup(restyClient, repoLayoutsCache, "", func() ([]RepoLayout, error) {
+		config := RepoLayouts{}
+		_, err := restyClient.R().
+			AddRetryCondition(client.NeverRetry).
+			SetResult(&config).
+			Get("artifactory/api/system/configuration")
+		return config.Layouts, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notFound := &RepoLayoutNotFoundError{Name: name}
+	for _, layout := range layouts {
+		if strings.EqualFold(layout.Name, name) {
+			return &layout, nil
+		}
+		notFound.Available = append(notFound.Available, layout.Name)
+	}
+	return nil, notFound
+}
+
+const LicenseEndpoint = "artifactory/api/system/license"
+
+// GetLicenseType reads the type of the instance license, e.g. "Enterprise Plus". Requires admin permissions.
+func GetLicenseType(restyClient *resty.Client) (string, error) {
+	license := struct {
+		Type     string `json:"type"`
+		Licenses []struct {
+			Type string `json:"type"`
+		} `json:"licenses"` // HA licenses returns as an array instead
+	}{}
+	_, err := restyClient.R().SetResult(&license).Get(LicenseEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	if len(license.Licenses) > 0 {
+		return license.Licenses[0].Type, nil
+	}
+	return license.Type, nil
+}
+
+// GetCachedLicenseType is GetLicenseType, read once per client. The provider reads it when configured with
+// `check_license`, so resources and data sources can check it without another request.
+func GetCachedLicenseType(restyClient *resty.Client) (string, error) {
+	return CachedLookup(restyClient, licenseTypeCache, "", func() (string, error) {
+		return GetLicenseType(restyClient)
+	})
+}
+
+// RequireLicense fails when the cached license type of the client matches none of requiredTypes, so resources can
+// explain an unsupported feature instead of surfacing Artifactory's 400 or 403. Nothing is checked when the license
+// wasn't read, i.e. with `check_license` disabled.
+func RequireLicense(restyClient *resty.Client, feature string, requiredTypes ...string) error {
+	licenseType, ok := CachedValue[string](restyClient, licenseTypeCache, "")
+	if !ok {
+		return nil
+	}
+
+	for _, requiredType := range requiredTypes {
+		if strings.Contains(licenseType, requiredType) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s require an Artifactory %s license, the instance has a %q license", feature, strings.Join(requiredTypes, " or "), licenseType)
+}
+
+// ProviderSettings holds the provider attributes resources check beyond the client configuration
+type ProviderSettings struct {
+	// MaxMemberRepositories caps the members of a virtual repository, 0 means unlimited
+	MaxMemberRepositories int
+	// MaxPatternsLength caps the combined length of the include and exclude patterns, 0 means unlimited
+	MaxPatternsLength int
+	// MaxRetrievalCachePeriodSecs caps retrieval_cache_period_seconds of virtual repositories, 0 means the default
+	MaxRetrievalCachePeriodSecs int
+	// DefaultRetrievalCachePeriodSecs is inherited by virtual repositories leaving retrieval_cache_period_seconds unset,
+	// nil means the default of the resource
+	DefaultRetrievalCachePeriodSecs *int
+	// DefaultRequestsCanRetrieveRemoteArtifacts is inherited by virtual repositories leaving
+	// artifactory_requests_can_retrieve_remote_artifacts unset, unless their package type has its own default
+	DefaultRequestsCanRetrieveRemoteArtifacts *bool
+	// UncachedMembersWarningThreshold is the number of members above which virtual repositories caching no metadata
+	// are warned about, 0 disables the warning and nil means the default of the resource
+	UncachedMembersWarningThreshold *int
+}
+
+// clientState is what the provider keeps per client: the settings of the provider that configured it, and the lookups
+// cached while it plans and applies, by kind and key. Failed lookups aren't cached, and the writes of a repository
+// evict what they change. States live as long as their client, i.e. one run of the provider.
+type clientState struct {
+	settings ProviderSettings
+	cached   map[string]map[string]interface{}
+}
+
+// clientStates are stored per client, the provider meta must stay the client for the shared telemetry wrapper
+var clientStates = struct {
+	sync.Mutex
+	byClient map[*resty.Client]*clientState
+}{byClient: map[*resty.Client]*clientState{}}
+
+// Kinds of the lookups cached per client
+const (
+	repoInfoCache            = "repo_info"
+	projectCache             = "project"
+	projectEnvironmentsCache = "project_environments"
+	licenseTypeCache         = "license_type"
+	environmentsSupportCache = "environments_support"
+	repoKeyClaimCache        = "repo_key_claim"
+	repoLayoutsCache         = "repo_layouts"
+	// ProjectRepositoriesCache has the keys of the repositories of each project
+	ProjectRepositoriesCache = "project_repositories"
+	RepositoryStorageCache   = "repository_storage"
+)
+
+// stateOf returns the state of the client, clientStates must be locked
+func stateOf(restyClient *resty.Client) *clientState {
+	state, ok := clientStates.byClient[restyClient]
+	if !ok {
+		state = &clientState{cached: map[string]map[string]interface{}{}}
+		clientStates.byClient[restyClient] = state
+	}
+	return state
+}
+
+func SetProviderSettings(restyClient *resty.Client, settings ProviderSettings) {
+	clientStates.Lock()
+	defer clientStates.Unlock()
+	stateOf(restyClient).settings = settings
+}
+
+// GetProviderSettings returns the settings of the provider that configured the client, or the zero settings for
+// clients not built by the provider, e.g. in unit tests.
+func GetProviderSettings(restyClient *resty.Client) ProviderSettings {
+	clientStates.Lock()
+	defer clientStates.Unlock()
+	return stateOf(restyClient).settings
+}
+
+// ProviderSettingsOf returns the settings of the provider meta, the zero settings for a meta that is only a
+// RepositoryClient, i.e. the defaults of each setting
+func ProviderSettingsOf(m interface{}) ProviderSettings {
+	if restyClient, ok := m.(*resty.Client); ok {
+		return GetProviderSettings(restyClient)
+	}
+	return ProviderSettings{}
+}
+
+// CachedValue returns the value cached for key in the lookups of kind of the client
+func CachedValue[T any](restyClient *resty.Client, kind, key string) (T, bool) {
+	clientStates.Lock()
+	defer clientStates.Unlock()
+	value, ok := stateOf(restyClient).cached[kind][key].(T)
+	return value, ok
+}
+
+// CacheValue caches value for key in the lookups of kind of the client, unless a value is cached already. The cached
+// value is returned, with whether it is value.
+func CacheValue(restyClient *resty.Client, kind, key string, value interface{}) (interface{}, bool) {
+	clientStates.Lock()
+	defer clientStates.Unlock()
+	state := stateOf(restyClient)
+	if cached, ok := state.cached[kind][key]; ok {
+		return cached, false
+	}
+	if state.cached[kind] == nil {
+		state.cached[kind] = map[string]interface{}{}
+	}
+	state.cached[kind][key] = value
+	return value, true
+}
+
+// EvictCached removes what is cached for key in the lookups of kind of the client
+func EvictCached(restyClient *resty.Client, kind, key string) {
+	clientStates.Lock()
+	defer clientStates.Unlock()
+	delete(stateOf(restyClient).cached[kind], key)
+}
+
+// CachedLookup returns the value cached for key in the lookups of kind of the client, and looks it up and caches it on
+// a miss. Failed lookups aren't cached.
+func CachedLookup[T any](restyClient *resty.Client, kind, key string, lookup func() (T, error)) (T, error) {
+	if value, ok := CachedValue[T](restyClient, kind, key); ok {
+		return value, nil
+	}
+	value, err := lookup()
+	if err != nil {
+		return value, err
+	}
+	cached, _ := CacheValue(restyClient, kind, key, value)
+	return cached.(T), nil
+}
+
+// evictRepository forgets what is cached about the repository key after a write of it
+func evictRepository(m interface{}, key string) {
+	if restyClient, ok := m.(*resty.Client); ok {
+		EvictCached(restyClient, repoInfoCache, key)
+		EvictCached(restyClient, RepositoryStorageCache, key)
+	}
+}
+
 func ValidateRepoLayoutRefSchemaOverride(_ interface{}, _ cty.Path) diag.Diagnostics {
 	return diag.Diagnostics{
 		diag.Diagnostic{
@@ -509,6 +1751,29 @@ func ValidateRepoLayoutRefSchemaOverride(_ interface{}, _ cty.Path) diag.Diagnos
 	}
 }
 
+// NoRepoLayoutRef is the layout of free-form repositories. Artifactory has no repositories without a layout, a
+// repository created without one gets the default layout of its package type, but this layout extracts no module
+// information from the paths, so artifacts can be stored anywhere like in a generic repository.
+const NoRepoLayoutRef = "simple-default"
+
+var repoLayoutRefRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// ValidateRepoLayoutRef accepts layout names made of letters, digits, `.`, `_` and `-`, like the built-in layouts and
+// NoRepoLayoutRef. Whether a layout with that name exists is only known to Artifactory, which rejects unknown layouts.
+func ValidateRepoLayoutRef(value interface{}, path cty.Path) diag.Diagnostics {
+	layoutRef := value.(string)
+	if layoutRef == "" || repoLayoutRefRegex.MatchString(layoutRef) {
+		return nil
+	}
+	return diag.Diagnostics{{
+		Severity: diag.Error,
+		Summary:  "Invalid repository layout",
+		Detail: fmt.Sprintf("%q is not a repository layout name, names only contain letters, digits, '.', '_' and '-'. "+
+			"Use %q for a free-form layout.", layoutRef, NoRepoLayoutRef),
+		AttributePath: path,
+	}}
+}
+
 type SupportedRepoClasses struct {
 	RepoLayoutRef      string
 	SupportedRepoTypes map[string]bool
@@ -557,3 +1822,56 @@ func GetDefaultRepoLayoutRef(repositoryType string, packageType string) func() (
 		return "", fmt.Errorf("default repo layout not found for repository type %v & package type %v", repositoryType, packageType)
 	}
 }
+
+// ConfigDrift is a field of the golden configuration whose value differs in the actual configuration.
+// Values are JSON encoded, a missing field has an empty actual value.
+type ConfigDrift struct {
+	Field    string
+	Expected string
+	Actual   string
+}
+
+// DiffConfig compares golden against actual. Objects are compared field by field, only the fields of golden
+// are considered as Artifactory returns every setting of a repository, arrays and scalars are compared as a whole.
+// Fields are named with their dotted path, e.g. "contentSynchronisation.enabled".
+func DiffConfig(golden, actual map[string]interface{}) []ConfigDrift {
+	return diffObjects("", golden, actual)
+}
+
+func diffObjects(prefix string, golden, actual map[string]interface{}) []ConfigDrift {
+	fields := make([]string, 0, len(golden))
+	for field := range golden {
+		fields = append(fields, field)
+	}
+	// map iteration order is random, keep the drift stable between reads
+	sort.Strings(fields)
+
+	var drift []ConfigDrift
+	for _, field := range fields {
+		path := prefix + field
+		expected := golden[field]
+		value, found := actual[field]
+		if !found {
+			drift = append(drift, ConfigDrift{Field: path, Expected: encodeJSON(expected)})
+			continue
+		}
+
+		expectedObject, expectedIsObject := expected.(map[string]interface{})
+		actualObject, actualIsObject := value.(map[string]interface{})
+		if expectedIsObject && actualIsObject {
+			drift = append(drift, diffObjects(path+".", expectedObject, actualObject)...)
+			continue
+		}
+
+		if !reflect.DeepEqual(expected, value) {
+			drift = append(drift, ConfigDrift{Field: path, Expected: encodeJSON(expected), Actual: encodeJSON(value)})
+		}
+	}
+	return drift
+}
+
+func encodeJSON(value interface{}) string {
+	// values come from json.Unmarshal, they always encode
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
@@ -36,7 +36,7 @@ func ResourceArtifactoryVirtualAlpineRepository() *schema.Resource {
 		return &repo, repo.Key, nil
 	}
 
-	return repository.MkResourceSchema(alpineVirtualSchema, repository.DefaultPacker(alpineVirtualSchema), unpackAlpineVirtualRepository, func() interface{} {
+	return mkResourceSchema(alpineVirtualSchema, repository.DefaultPacker(alpineVirtualSchema), unpackAlpineVirtualRepository, func() interface{} {
 		return &AlpineVirtualRepositoryParams{
 			VirtualRepositoryBaseParamsWithRetrievalCachePeriodSecs: VirtualRepositoryBaseParamsWithRetrievalCachePeriodSecs{
 				VirtualRepositoryBaseParams: VirtualRepositoryBaseParams{
@@ -58,7 +58,7 @@ func ResourceArtifactoryVirtualBowerRepository() *schema.Resource {
 		return &repo, repo.Key, nil
 	}
 
-	return repository.MkResourceSchema(bowerVirtualSchema, repository.DefaultPacker(bowerVirtualSchema), unpackBowerVirtualRepository, func() interface{} {
+	return mkResourceSchema(bowerVirtualSchema, repository.DefaultPacker(bowerVirtualSchema), unpackBowerVirtualRepository, func() interface{} {
 		return &BowerVirtualRepositoryParams{
 			VirtualRepositoryBaseParams: VirtualRepositoryBaseParams{
 				Rclass:      "virtual",
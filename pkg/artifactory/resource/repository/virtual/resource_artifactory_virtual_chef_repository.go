@@ -0,0 +1,40 @@
+package virtual
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-shared/util"
+)
+
+func ResourceArtifactoryVirtualChefRepository() *schema.Resource {
+
+	const packageType = "chef"
+
+	chefVirtualSchema := util.MergeSchema(BaseVirtualRepoSchema, map[string]*schema.Schema{
+		"retrieval_cache_period_seconds": {
+			Type:             schema.TypeInt,
+			Optional:         true,
+			Computed:         true,
+			Description:      "The number of seconds to cache the aggregated cookbook metadata before checking the members for newer versions. A value of 0 indicates no caching. Default to the `default_retrieval_cache_period_seconds` of the provider, `7200` unless set.",
+			ValidateDiagFunc: ValidateRetrievalCachePeriodSecs,
+		},
+	}, repository.RepoLayoutRefSchema("virtual", packageType))
+
+	unpackChefVirtualRepository := func(data *schema.ResourceData) (interface{}, string, error) {
+		repo := UnpackBaseVirtRepoWithRetrievalCachePeriodSecs(data, packageType)
+		return repo, repo.Id(), nil
+	}
+
+	constructor := func() interface{} {
+		return &VirtualRepositoryBaseParamsWithRetrievalCachePeriodSecs{
+			VirtualRepositoryBaseParams: VirtualRepositoryBaseParams{
+				Rclass:      "virtual",
+				PackageType: packageType,
+			},
+		}
+	}
+
+	// unlike CRAN, members of other package types don't fail the plan, mixed setups are common while migrating cookbooks.
+	// The read, also run after create and on refresh, warns about them.
+	return mkResourceSchema(chefVirtualSchema, repository.DefaultPacker(chefVirtualSchema), unpackChefVirtualRepository, constructor)
+}
@@ -0,0 +1,95 @@
+package virtual_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+func TestAccVirtualChefRepository(t *testing.T) {
+	_, fqrn, name := acctest.MkNames("virtual-chef-repo", "artifactory_virtual_chef_repository")
+	_, _, supermarketName := acctest.MkNames("chef-remote", "artifactory_remote_chef_repository")
+	_, _, mirrorName := acctest.MkNames("chef-mirror", "artifactory_remote_chef_repository")
+	config := acctest.ExecuteTemplate("TestAccVirtualChefRepository", `
+		resource "artifactory_remote_chef_repository" "{{ .supermarketName }}" {
+		  key = "{{ .supermarketName }}"
+		  url = "https://supermarket.chef.io"
+		}
+
+		resource "artifactory_remote_chef_repository" "{{ .mirrorName }}" {
+		  key = "{{ .mirrorName }}"
+		  url = "https://supermarket.chef.io"
+		}
+
+		resource "artifactory_virtual_chef_repository" "{{ .name }}" {
+		  key          = "{{ .name }}"
+		  repositories = [
+		    artifactory_remote_chef_repository.{{ .supermarketName }}.key,
+		    artifactory_remote_chef_repository.{{ .mirrorName }}.key,
+		  ]
+		}
+	`, map[string]interface{}{
+		"name":            name,
+		"supermarketName": supermarketName,
+		"mirrorName":      mirrorName,
+	})
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { acctest.PreCheck(t) },
+		ProviderFactories: acctest.ProviderFactories,
+		CheckDestroy:      acctest.VerifyDeleted(fqrn, acctest.CheckRepo),
+
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(fqrn, "key", name),
+					resource.TestCheckResourceAttr(fqrn, "package_type", "chef"),
+					resource.TestCheckResourceAttr(fqrn, "retrieval_cache_period_seconds", "7200"),
+					resource.TestCheckResourceAttr(fqrn, "repositories.#", "2"),
+					resource.TestCheckResourceAttr(fqrn, "repositories.0", supermarketName),
+					resource.TestCheckResourceAttr(fqrn, "repositories.1", mirrorName),
+				),
+			},
+		},
+	})
+}
+
+func TestVirtualChefRepositoryWarnsOnNonChefMembers(t *testing.T) {
+	restyClient, _ := mockRepositories(t, map[string]string{
+		"chef-remote": `{"key":"chef-remote","rclass":"remote","packageType":"chef"}`,
+		"npm-remote":  `{"key":"npm-remote","rclass":"remote","packageType":"npm"}`,
+	})
+	repoResource := virtual.ResourceArtifactoryVirtualChefRepository()
+	members := []interface{}{"chef-remote", "npm-remote"}
+
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"key":          "foo-chef",
+		"repositories": members,
+	})
+	if _, err := repoResource.Diff(context.Background(), nil, config, restyClient); err != nil {
+		t.Fatalf("expected mixed members not to fail the plan, got %s", err)
+	}
+
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+		"key":          "foo-chef",
+		"repositories": members,
+	})
+	diags := repoResource.CreateContext(context.Background(), d, restyClient)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	for _, diagnostic := range diags {
+		if diagnostic.Severity == diag.Warning && strings.Contains(diagnostic.Detail, "npm-remote (npm)") && !strings.Contains(diagnostic.Detail, "chef-remote") {
+			return
+		}
+	}
+	t.Fatalf("expected a warning naming npm-remote, got %v", diags)
+}
@@ -0,0 +1,41 @@
+package virtual
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-shared/util"
+)
+
+func ResourceArtifactoryVirtualComposerRepository() *schema.Resource {
+
+	const packageType = "composer"
+
+	var composerVirtualSchema = util.MergeSchema(
+		BaseVirtualRepoSchema,
+		externalDependenciesSchema("When set, external dependencies of the packages are resolved through the Packagist mirrors aggregated by the repository, limited to `external_dependencies_patterns`.", "resolved"),
+		externalDependenciesRemoteRepoSchema,
+		repository.RepoLayoutRefSchema("virtual", packageType),
+	)
+
+	type ComposerVirtualRepositoryParams struct {
+		VirtualRepositoryBaseParams
+		ExternalDependenciesWithRemoteRepoParams
+	}
+
+	var unpackComposerVirtualRepository = func(s *schema.ResourceData) (interface{}, string, error) {
+		repo := ComposerVirtualRepositoryParams{
+			VirtualRepositoryBaseParams:              UnpackBaseVirtRepo(s, packageType),
+			ExternalDependenciesWithRemoteRepoParams: unpackExternalDependenciesWithRemoteRepo(s),
+		}
+		return &repo, repo.Key, nil
+	}
+
+	return withExternalDependenciesRemoteRepo(mkResourceSchema(composerVirtualSchema, packExternalDependencies(composerVirtualSchema), unpackComposerVirtualRepository, func() interface{} {
+		return &ComposerVirtualRepositoryParams{
+			VirtualRepositoryBaseParams: VirtualRepositoryBaseParams{
+				Rclass:      "virtual",
+				PackageType: packageType,
+			},
+		}
+	}))
+}
@@ -0,0 +1,115 @@
+package virtual_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+func TestVirtualComposerRepositoryExternalDependencies(t *testing.T) {
+	patterns := []interface{}{"**/packagist.org/**"}
+	testCases := map[string]struct {
+		enabled            bool
+		remoteRepo         string
+		expectedPatterns   interface{}
+		expectedRemoteRepo string
+	}{
+		"enabled with remote":    {true, "composer-remote", patterns, "composer-remote"},
+		"enabled without remote": {true, "", patterns, ""},
+		"disabled":               {false, "", nil, ""},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, sent := mockRepositories(t, map[string]string{
+				"composer-remote": `{"key":"composer-remote","rclass":"remote","packageType":"composer"}`,
+			})
+			repoResource := virtual.ResourceArtifactoryVirtualComposerRepository()
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+				"key":                               "foo-composer",
+				"external_dependencies_enabled":     testCase.enabled,
+				"external_dependencies_patterns":    patterns,
+				"external_dependencies_remote_repo": testCase.remoteRepo,
+			})
+
+			if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			if sent["foo-composer"]["externalDependenciesEnabled"] != testCase.enabled {
+				t.Fatalf("expected externalDependenciesEnabled to be %t, got %v", testCase.enabled, sent["foo-composer"])
+			}
+			if !reflect.DeepEqual(sent["foo-composer"]["externalDependenciesPatterns"], testCase.expectedPatterns) {
+				t.Fatalf("expected externalDependenciesPatterns %v, got %v", testCase.expectedPatterns, sent["foo-composer"])
+			}
+			if sent["foo-composer"]["externalDependenciesRemoteRepo"] != testCase.expectedRemoteRepo {
+				t.Fatalf("expected externalDependenciesRemoteRepo %q, got %v", testCase.expectedRemoteRepo, sent["foo-composer"])
+			}
+			if d.Get("repo_layout_ref") != "composer-default" {
+				t.Fatalf("expected the composer default layout, got %v", d.Get("repo_layout_ref"))
+			}
+		})
+	}
+}
+
+func TestAccVirtualComposerRepository(t *testing.T) {
+	_, fqrn, name := acctest.MkNames("virtual-composer-repo", "artifactory_virtual_composer_repository")
+	_, _, remoteName := acctest.MkNames("composer-remote", "artifactory_remote_composer_repository")
+	const template = `
+		resource "artifactory_remote_composer_repository" "{{ .remoteName }}" {
+		  key                   = "{{ .remoteName }}"
+		  url                   = "https://github.com/"
+		  vcs_git_provider      = "GITHUB"
+		  composer_registry_url = "https://packagist.org"
+		}
+
+		resource "artifactory_virtual_composer_repository" "{{ .name }}" {
+		  key                               = "{{ .name }}"
+		  repositories                      = [artifactory_remote_composer_repository.{{ .remoteName }}.key]
+		  external_dependencies_enabled     = {{ .enabled }}
+		  external_dependencies_patterns    = ["**/packagist.org/**"]
+		  {{ if .enabled }}external_dependencies_remote_repo = artifactory_remote_composer_repository.{{ .remoteName }}.key{{ end }}
+		}
+	`
+	config := func(enabled bool) string {
+		return acctest.ExecuteTemplate("TestAccVirtualComposerRepository", template, map[string]interface{}{
+			"name":       name,
+			"remoteName": remoteName,
+			"enabled":    enabled,
+		})
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { acctest.PreCheck(t) },
+		ProviderFactories: acctest.ProviderFactories,
+		CheckDestroy:      acctest.CheckRepoDestroy(t, fqrn),
+		Steps: []resource.TestStep{
+			{
+				Config: config(true),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(fqrn, "package_type", "composer"),
+					resource.TestCheckResourceAttr(fqrn, "external_dependencies_enabled", "true"),
+					resource.TestCheckResourceAttr(fqrn, "external_dependencies_patterns.#", "1"),
+					resource.TestCheckResourceAttr(fqrn, "external_dependencies_patterns.0", "**/packagist.org/**"),
+					resource.TestCheckResourceAttr(fqrn, "external_dependencies_remote_repo", remoteName),
+				),
+			},
+			{
+				Config: config(false),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(fqrn, "external_dependencies_enabled", "false"),
+					resource.TestCheckResourceAttr(fqrn, "external_dependencies_remote_repo", ""),
+				),
+			},
+			{
+				Config:   config(false),
+				PlanOnly: true,
+			},
+		},
+	})
+}
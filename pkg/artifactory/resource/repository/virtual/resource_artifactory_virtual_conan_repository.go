@@ -0,0 +1,50 @@
+package virtual
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-shared/util"
+)
+
+func ResourceArtifactoryVirtualConanRepository() *schema.Resource {
+
+	const packageType = "conan"
+
+	conanVirtualSchema := util.MergeSchema(BaseVirtualRepoSchema, map[string]*schema.Schema{
+		"force_conan_authentication": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "Force basic authentication credentials in order to use this repository. Default to 'false'.",
+		},
+	}, repository.RepoLayoutRefSchema("virtual", packageType))
+
+	type ConanVirtualRepositoryParams struct {
+		VirtualRepositoryBaseParamsWithRetrievalCachePeriodSecs
+		ForceConanAuthentication bool `json:"forceConanAuthentication,omitempty"`
+	}
+
+	unpackConanVirtualRepository := func(data *schema.ResourceData) (interface{}, string, error) {
+		d := &util.ResourceData{data}
+		repo := ConanVirtualRepositoryParams{
+			VirtualRepositoryBaseParamsWithRetrievalCachePeriodSecs: UnpackBaseVirtRepoWithRetrievalCachePeriodSecs(data, packageType),
+			ForceConanAuthentication:                                d.GetBool("force_conan_authentication", false),
+		}
+
+		return repo, repo.Id(), nil
+	}
+
+	constructor := func() interface{} {
+		return &ConanVirtualRepositoryParams{
+			VirtualRepositoryBaseParamsWithRetrievalCachePeriodSecs: VirtualRepositoryBaseParamsWithRetrievalCachePeriodSecs{
+				VirtualRepositoryBaseParams: VirtualRepositoryBaseParams{
+					Rclass:      "virtual",
+					PackageType: packageType,
+				},
+			},
+			ForceConanAuthentication: false,
+		}
+	}
+
+	return mkResourceSchema(conanVirtualSchema, repository.DefaultPacker(conanVirtualSchema), unpackConanVirtualRepository, constructor)
+}
@@ -0,0 +1,51 @@
+package virtual_test
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+)
+
+func TestAccVirtualConanRepository_forceConanAuthentication(t *testing.T) {
+	_, fqrn, name := acctest.MkNames("virtual-conan-repo", "artifactory_virtual_conan_repository")
+	const template = `
+		resource "artifactory_virtual_conan_repository" "{{ .name }}" {
+		  key                        = "{{ .name }}"
+		  force_conan_authentication = {{ .forceConanAuthentication }}
+		}
+	`
+	withAuth := acctest.ExecuteTemplate("TestAccVirtualConanRepository", template, map[string]interface{}{
+		"name":                     name,
+		"forceConanAuthentication": true,
+	})
+	withoutAuth := acctest.ExecuteTemplate("TestAccVirtualConanRepository", template, map[string]interface{}{
+		"name":                     name,
+		"forceConanAuthentication": false,
+	})
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { acctest.PreCheck(t) },
+		ProviderFactories: acctest.ProviderFactories,
+		CheckDestroy:      acctest.VerifyDeleted(fqrn, acctest.CheckRepo),
+
+		Steps: []resource.TestStep{
+			{
+				Config: withAuth,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(fqrn, "key", name),
+					resource.TestCheckResourceAttr(fqrn, "package_type", "conan"),
+					resource.TestCheckResourceAttr(fqrn, "force_conan_authentication", "true"),
+					resource.TestCheckResourceAttr(fqrn, "retrieval_cache_period_seconds", "7200"),
+				),
+			},
+			{
+				Config: withoutAuth,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(fqrn, "force_conan_authentication", "false"),
+					resource.TestCheckResourceAttr(fqrn, "retrieval_cache_period_seconds", "7200"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,34 @@
+package virtual
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-shared/util"
+)
+
+func ResourceArtifactoryVirtualCranRepository() *schema.Resource {
+
+	const packageType = "cran"
+
+	cranVirtualSchema := util.MergeSchema(BaseVirtualRepoSchema, repository.RepoLayoutRefSchema("virtual", packageType))
+
+	unpackCranVirtualRepository := func(data *schema.ResourceData) (interface{}, string, error) {
+		repo := UnpackBaseVirtRepoWithRetrievalCachePeriodSecs(data, packageType)
+		return repo, repo.Id(), nil
+	}
+
+	constructor := func() interface{} {
+		return &VirtualRepositoryBaseParamsWithRetrievalCachePeriodSecs{
+			VirtualRepositoryBaseParams: VirtualRepositoryBaseParams{
+				Rclass:      "virtual",
+				PackageType: packageType,
+			},
+		}
+	}
+
+	resource := mkResourceSchema(cranVirtualSchema, repository.DefaultPacker(cranVirtualSchema), unpackCranVirtualRepository, constructor)
+	// Artifactory accepts members of any package type, but only serves the CRAN index from CRAN members
+	resource.CustomizeDiff = customdiff.All(resource.CustomizeDiff, membersPackageTypeDiff(packageType))
+	return resource
+}
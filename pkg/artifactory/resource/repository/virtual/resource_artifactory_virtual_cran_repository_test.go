@@ -0,0 +1,97 @@
+package virtual_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+func TestAccVirtualCranRepository(t *testing.T) {
+	_, fqrn, name := acctest.MkNames("virtual-cran-repo", "artifactory_virtual_cran_repository")
+	_, _, cranName := acctest.MkNames("cran-remote", "artifactory_remote_cran_repository")
+	_, _, mirrorName := acctest.MkNames("cran-mirror", "artifactory_remote_cran_repository")
+	config := acctest.ExecuteTemplate("TestAccVirtualCranRepository", `
+		resource "artifactory_remote_cran_repository" "{{ .cranName }}" {
+		  key = "{{ .cranName }}"
+		  url = "https://cran.r-project.org/"
+		}
+
+		resource "artifactory_remote_cran_repository" "{{ .mirrorName }}" {
+		  key = "{{ .mirrorName }}"
+		  url = "https://cloud.r-project.org/"
+		}
+
+		resource "artifactory_virtual_cran_repository" "{{ .name }}" {
+		  key                            = "{{ .name }}"
+		  repositories                   = [
+		    artifactory_remote_cran_repository.{{ .cranName }}.key,
+		    artifactory_remote_cran_repository.{{ .mirrorName }}.key,
+		  ]
+		  retrieval_cache_period_seconds = 3600
+		}
+	`, map[string]interface{}{
+		"name":       name,
+		"cranName":   cranName,
+		"mirrorName": mirrorName,
+	})
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { acctest.PreCheck(t) },
+		ProviderFactories: acctest.ProviderFactories,
+		CheckDestroy:      acctest.VerifyDeleted(fqrn, acctest.CheckRepo),
+
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(fqrn, "key", name),
+					resource.TestCheckResourceAttr(fqrn, "package_type", "cran"),
+					resource.TestCheckResourceAttr(fqrn, "repo_layout_ref", "simple-default"),
+					resource.TestCheckResourceAttr(fqrn, "retrieval_cache_period_seconds", "3600"),
+					resource.TestCheckResourceAttr(fqrn, "repositories.#", "2"),
+					resource.TestCheckResourceAttr(fqrn, "repositories.0", cranName),
+					resource.TestCheckResourceAttr(fqrn, "repositories.1", mirrorName),
+				),
+			},
+		},
+	})
+}
+
+func TestVirtualCranRepositoryRejectsNonCranMembers(t *testing.T) {
+	restyClient, _ := mockRepositories(t, map[string]string{
+		"cran-remote": `{"key":"cran-remote","rclass":"remote","packageType":"cran"}`,
+		"npm-remote":  `{"key":"npm-remote","rclass":"remote","packageType":"npm"}`,
+	})
+
+	testCases := map[string]struct {
+		repositories  []interface{}
+		expectedError bool
+	}{
+		"cran members":     {[]interface{}{"cran-remote"}, false},
+		"non-cran member":  {[]interface{}{"cran-remote", "npm-remote"}, true},
+		"not yet existing": {[]interface{}{"cran-remote", "cran-new"}, false},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			repoResource := virtual.ResourceArtifactoryVirtualCranRepository()
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":          "foo-cran",
+				"repositories": testCase.repositories,
+			})
+
+			_, err := repoResource.Diff(context.Background(), nil, config, restyClient)
+			if testCase.expectedError && (err == nil || !strings.Contains(err.Error(), "npm-remote (npm)") || strings.Contains(err.Error(), "cran-remote")) {
+				t.Fatalf("expected an error naming only npm-remote, got %v", err)
+			}
+			if !testCase.expectedError && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
@@ -69,7 +69,7 @@ func ResourceArtifactoryVirtualDebianRepository() *schema.Resource {
 		return &repo, repo.Key, nil
 	}
 
-	return repository.MkResourceSchema(debianVirtualSchema, repository.DefaultPacker(debianVirtualSchema), unpackDebianVirtualRepository, func() interface{} {
+	return mkResourceSchema(debianVirtualSchema, repository.DefaultPacker(debianVirtualSchema), unpackDebianVirtualRepository, func() interface{} {
 		return &DebianVirtualRepositoryParams{
 			VirtualRepositoryBaseParamsWithRetrievalCachePeriodSecs: VirtualRepositoryBaseParamsWithRetrievalCachePeriodSecs{
 				VirtualRepositoryBaseParams: VirtualRepositoryBaseParams{
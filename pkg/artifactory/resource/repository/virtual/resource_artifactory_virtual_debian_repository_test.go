@@ -0,0 +1,32 @@
+package virtual_test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+)
+
+func TestAccVirtualDebianRepository_invalidCompressionFormat(t *testing.T) {
+	_, fqrn, name := acctest.MkNames("foo", "artifactory_virtual_debian_repository")
+	virtualRepositoryInvalid := fmt.Sprintf(`
+		resource "artifactory_virtual_debian_repository" "%s" {
+			key                                = "%s"
+			optional_index_compression_formats = ["gzip"]
+		}
+	`, name, name)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { acctest.PreCheck(t) },
+		ProviderFactories: acctest.ProviderFactories,
+		CheckDestroy:      acctest.VerifyDeleted(fqrn, acctest.CheckRepo),
+		Steps: []resource.TestStep{
+			{
+				Config:      virtualRepositoryInvalid,
+				ExpectError: regexp.MustCompile(`.*expected optional_index_compression_formats.* to be one of \[bz2 lzma xz\], got gzip.*`),
+			},
+		},
+	})
+}
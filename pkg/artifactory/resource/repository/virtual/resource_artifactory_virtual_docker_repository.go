@@ -0,0 +1,87 @@
+package virtual
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-shared/util"
+)
+
+type DockerVirtualRepositoryParams struct {
+	VirtualRepositoryBaseParams
+	ResolveDockerTagsByTimestamp bool `hcl:"resolve_docker_tags_by_timestamp" json:"resolveDockerTagsByTimestamp"`
+}
+
+// Docker virtual repositories resolve the images requested by other instances through their remote members by default
+func (DockerVirtualRepositoryParams) requestsCanRetrieveRemoteArtifactsDefault() bool {
+	return true
+}
+
+func ResourceArtifactoryVirtualDockerRepository() *schema.Resource {
+
+	const packageType = "docker"
+
+	dockerVirtualSchema := util.MergeSchema(BaseVirtualRepoSchema, map[string]*schema.Schema{
+		"resolve_docker_tags_by_timestamp": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "When set, a tag found in several members resolves to the most recently pushed image. When unset, it resolves from the first member listing it in `repositories`. Default to 'false'.",
+		},
+	}, repository.RepoLayoutRefSchema("virtual", packageType))
+
+	unpackDockerVirtualRepository := func(data *schema.ResourceData) (interface{}, string, error) {
+		d := &util.ResourceData{data}
+		repo := DockerVirtualRepositoryParams{
+			VirtualRepositoryBaseParams:  UnpackBaseVirtRepo(data, packageType),
+			ResolveDockerTagsByTimestamp: d.GetBool("resolve_docker_tags_by_timestamp", false),
+		}
+		return repo, repo.Id(), nil
+	}
+
+	constructor := func() interface{} {
+		return &DockerVirtualRepositoryParams{
+			VirtualRepositoryBaseParams: VirtualRepositoryBaseParams{
+				Rclass:      "virtual",
+				PackageType: packageType,
+			},
+		}
+	}
+
+	resource := mkResourceSchema(dockerVirtualSchema, repository.DefaultPacker(dockerVirtualSchema), unpackDockerVirtualRepository, constructor)
+	resource.CreateContext = warnOnTagResolutionByOrder(warnOnEmptyMembers("image", resource.CreateContext))
+	resource.UpdateContext = warnOnTagResolutionByOrder(warnOnEmptyMembers("image", resource.UpdateContext))
+	// OCI repositories are a package type of their own, a docker virtual repository can't serve them
+	resource.CustomizeDiff = customdiff.All(resource.CustomizeDiff, membersPackageTypeDiff(packageType))
+	return resource
+}
+
+// warnOnTagResolutionByOrder reminds that a tag found in several members resolves from the first one listed when
+// `resolve_docker_tags_by_timestamp` is unset. Whether members have overlapping tags isn't known, any virtual repository
+// with several members may resolve a tag from another member than expected, so it's only a warning, given when the
+// members or the setting changed.
+func warnOnTagResolutionByOrder(apply func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		diags := apply(ctx, d, m)
+		if diags.HasError() || d.Get("resolve_docker_tags_by_timestamp").(bool) || !d.HasChanges("repositories", "member_repositories", "resolve_docker_tags_by_timestamp") {
+			return diags
+		}
+		members := len(configuredMembers(d))
+		if members < 2 {
+			return diags
+		}
+
+		return append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "Docker tags resolved by member order",
+			Detail: fmt.Sprintf("resolve_docker_tags_by_timestamp is unset, a tag found in several of the %d members of the virtual repository %s resolves from the first one listed in `repositories`. "+
+				"Order the members by precedence, or set resolve_docker_tags_by_timestamp to resolve the most recently pushed image.", members, d.Id()),
+			AttributePath: cty.GetAttrPath("resolve_docker_tags_by_timestamp"),
+		})
+	}
+}
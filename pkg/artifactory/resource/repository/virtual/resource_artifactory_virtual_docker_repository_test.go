@@ -0,0 +1,125 @@
+package virtual_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+func TestAccVirtualDockerRepository(t *testing.T) {
+	resource.Test(mkNewVirtualTestCase("docker", t, map[string]interface{}{
+		"description":                      "docker virtual repository public description testing.",
+		"resolve_docker_tags_by_timestamp": true,
+	}))
+}
+
+func TestVirtualDockerRepositoryTagResolutionWarning(t *testing.T) {
+	testCases := map[string]struct {
+		members            map[string]interface{}
+		resolveByTimestamp bool
+		expectedWarning    bool
+	}{
+		"several members by order":     {map[string]interface{}{"repositories": []interface{}{"docker-local", "docker-remote"}}, false, true},
+		"several members as a set":     {map[string]interface{}{"member_repositories": []interface{}{"docker-local", "docker-remote"}}, false, true},
+		"several members by timestamp": {map[string]interface{}{"repositories": []interface{}{"docker-local", "docker-remote"}}, true, false},
+		"single member":                {map[string]interface{}{"repositories": []interface{}{"docker-local"}}, false, false},
+		"single member as a set":       {map[string]interface{}{"member_repositories": []interface{}{"docker-local"}}, false, false},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, sent := mockRepositories(t, map[string]string{
+				"docker-local":  `{"key":"docker-local","rclass":"local","packageType":"docker"}`,
+				"docker-remote": `{"key":"docker-remote","rclass":"remote","packageType":"docker"}`,
+			})
+			repoResource := virtual.ResourceArtifactoryVirtualDockerRepository()
+			config := map[string]interface{}{
+				"key":                              "foo-docker",
+				"resolve_docker_tags_by_timestamp": testCase.resolveByTimestamp,
+			}
+			for attribute, members := range testCase.members {
+				config[attribute] = members
+			}
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, config)
+
+			diags := repoResource.CreateContext(context.Background(), d, restyClient)
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if sent["foo-docker"]["resolveDockerTagsByTimestamp"] != testCase.resolveByTimestamp {
+				t.Fatalf("expected resolveDockerTagsByTimestamp %t to be sent, got %v", testCase.resolveByTimestamp, sent["foo-docker"])
+			}
+
+			var warnings []diag.Diagnostic
+			for _, diagnostic := range diags {
+				if diagnostic.Severity == diag.Warning && diagnostic.Summary == "Docker tags resolved by member order" {
+					warnings = append(warnings, diagnostic)
+				}
+			}
+			if testCase.expectedWarning != (len(warnings) == 1) {
+				t.Fatalf("expected warning to be %t, got %v", testCase.expectedWarning, diags)
+			}
+			if testCase.expectedWarning && !strings.Contains(warnings[0].Detail, "the 2 members of the virtual repository foo-docker") {
+				t.Fatalf("expected the warning to name the members count, got %q", warnings[0].Detail)
+			}
+		})
+	}
+}
+
+func TestVirtualDockerRepositoryCopyFrom(t *testing.T) {
+	restyClient, sent := mockRepositories(t, map[string]string{
+		"template-docker": `{"key":"template-docker","rclass":"virtual","packageType":"docker","forceNonDuplicateImages":true}`,
+	})
+	repoResource := virtual.ResourceArtifactoryVirtualDockerRepository()
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+		"key":       "foo-docker",
+		"copy_from": "template-docker",
+	})
+
+	if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if created := sent["foo-docker"]; created["key"] != "foo-docker" || created["forceNonDuplicateImages"] != true {
+		t.Fatalf("expected the unmanaged settings to be inherited from the source, got %v", created)
+	}
+}
+
+func TestVirtualDockerRepositoryRejectsOciMembers(t *testing.T) {
+	restyClient, _ := mockRepositories(t, map[string]string{
+		"docker-local":  `{"key":"docker-local","rclass":"local","packageType":"docker"}`,
+		"docker-remote": `{"key":"docker-remote","rclass":"remote","packageType":"docker"}`,
+		"oci-local":     `{"key":"oci-local","rclass":"local","packageType":"oci"}`,
+	})
+
+	testCases := map[string]struct {
+		repositories  []interface{}
+		expectedError bool
+	}{
+		"docker members":       {[]interface{}{"docker-local", "docker-remote"}, false},
+		"mixed docker and oci": {[]interface{}{"docker-local", "oci-local", "docker-remote"}, true},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			repoResource := virtual.ResourceArtifactoryVirtualDockerRepository()
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":          "foo-docker",
+				"repositories": testCase.repositories,
+			})
+
+			_, err := repoResource.Diff(context.Background(), nil, config, restyClient)
+			if testCase.expectedError && (err == nil || !strings.Contains(err.Error(), "oci-local (oci)") || strings.Contains(err.Error(), "docker-local")) {
+				t.Fatalf("expected an error naming only oci-local, got %v", err)
+			}
+			if !testCase.expectedError && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
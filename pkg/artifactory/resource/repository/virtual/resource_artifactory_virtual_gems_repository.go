@@ -0,0 +1,40 @@
+package virtual
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-shared/util"
+)
+
+func ResourceArtifactoryVirtualGemsRepository() *schema.Resource {
+
+	const packageType = "gems"
+
+	var gemsVirtualSchema = util.MergeSchema(
+		BaseVirtualRepoSchema,
+		externalDependenciesSchema("When set, external dependencies are resolved through the remote repositories, limited to `external_dependencies_patterns`.", "resolved"),
+		repository.RepoLayoutRefSchema("virtual", packageType),
+	)
+
+	type GemsVirtualRepositoryParams struct {
+		VirtualRepositoryBaseParams
+		ExternalDependenciesParams
+	}
+
+	var unpackGemsVirtualRepository = func(s *schema.ResourceData) (interface{}, string, error) {
+		repo := GemsVirtualRepositoryParams{
+			VirtualRepositoryBaseParams: UnpackBaseVirtRepo(s, packageType),
+			ExternalDependenciesParams:  unpackExternalDependencies(s),
+		}
+		return &repo, repo.Key, nil
+	}
+
+	return mkResourceSchema(gemsVirtualSchema, packExternalDependencies(gemsVirtualSchema), unpackGemsVirtualRepository, func() interface{} {
+		return &GemsVirtualRepositoryParams{
+			VirtualRepositoryBaseParams: VirtualRepositoryBaseParams{
+				Rclass:      "virtual",
+				PackageType: packageType,
+			},
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package virtual_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+func TestVirtualGemsRepositoryExternalDependencies(t *testing.T) {
+	patterns := []interface{}{"rubygems.org/**"}
+	testCases := map[string]struct {
+		enabled          bool
+		expectedPatterns interface{}
+	}{
+		"enabled":  {true, patterns},
+		"disabled": {false, nil},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, sent := mockRepositories(t, map[string]string{})
+			repoResource := virtual.ResourceArtifactoryVirtualGemsRepository()
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+				"key":                            "foo-gems",
+				"external_dependencies_enabled":  testCase.enabled,
+				"external_dependencies_patterns": patterns,
+			})
+
+			if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			if sent["foo-gems"]["externalDependenciesEnabled"] != testCase.enabled {
+				t.Fatalf("expected externalDependenciesEnabled to be %t, got %v", testCase.enabled, sent["foo-gems"])
+			}
+			if !reflect.DeepEqual(sent["foo-gems"]["externalDependenciesPatterns"], testCase.expectedPatterns) {
+				t.Fatalf("expected externalDependenciesPatterns %v, got %v", testCase.expectedPatterns, sent["foo-gems"])
+			}
+			// the configured patterns must stay in the state even when they're not sent
+			if !reflect.DeepEqual(d.Get("external_dependencies_patterns"), patterns) {
+				t.Fatalf("expected patterns to be kept in the state, got %v", d.Get("external_dependencies_patterns"))
+			}
+		})
+	}
+}
+
+func TestAccVirtualGemsRepository_externalDependencies(t *testing.T) {
+	_, fqrn, name := acctest.MkNames("virtual-gems-repo", "artifactory_virtual_gems_repository")
+	const template = `
+		resource "artifactory_virtual_gems_repository" "{{ .name }}" {
+		  key                            = "{{ .name }}"
+		  external_dependencies_enabled  = {{ .enabled }}
+		  external_dependencies_patterns = ["rubygems.org/**"]
+		}
+	`
+	enabled := acctest.ExecuteTemplate("TestAccVirtualGemsRepository", template, map[string]interface{}{
+		"name":    name,
+		"enabled": true,
+	})
+	disabled := acctest.ExecuteTemplate("TestAccVirtualGemsRepository", template, map[string]interface{}{
+		"name":    name,
+		"enabled": false,
+	})
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { acctest.PreCheck(t) },
+		ProviderFactories: acctest.ProviderFactories,
+		CheckDestroy:      acctest.VerifyDeleted(fqrn, acctest.CheckRepo),
+
+		Steps: []resource.TestStep{
+			{
+				Config: enabled,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(fqrn, "key", name),
+					resource.TestCheckResourceAttr(fqrn, "package_type", "gems"),
+					resource.TestCheckResourceAttr(fqrn, "external_dependencies_enabled", "true"),
+					resource.TestCheckResourceAttr(fqrn, "external_dependencies_patterns.#", "1"),
+					resource.TestCheckResourceAttr(fqrn, "external_dependencies_patterns.0", "rubygems.org/**"),
+				),
+			},
+			{
+				Config: disabled,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(fqrn, "external_dependencies_enabled", "false"),
+				),
+			},
+		},
+	})
+}
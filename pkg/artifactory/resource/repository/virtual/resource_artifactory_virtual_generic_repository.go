@@ -1,8 +1,14 @@
 package virtual
 
 import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
 	"github.com/jfrog/terraform-provider-shared/util"
 )
@@ -19,19 +25,62 @@ func ResourceArtifactoryVirtualGenericRepository(pkt string) *schema.Resource {
 		return repo, repo.Id(), nil
 	}
 
-	genericSchema := util.MergeSchema(BaseVirtualRepoSchema, repository.RepoLayoutRefSchema("virtual", pkt))
+	genericSchema := util.MergeSchema(BaseVirtualRepoSchema, map[string]*schema.Schema{
+		"require_homogeneous_members": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "When set, the plan fails when the members of `repositories` don't all have the same package type, listing the package types found. Members that don't exist yet aren't checked. When unset, members of any package type can be mixed. Default to 'false'.",
+		},
+	}, repository.RepoLayoutRefSchema("virtual", pkt))
+
+	resource := mkResourceSchema(genericSchema, repository.DefaultPacker(genericSchema), unpack, constructor)
+	resource.CustomizeDiff = customdiff.All(resource.CustomizeDiff, homogeneousMembersDiff)
+	return resource
+}
+
+// homogeneousMembersDiff fails the plan when `require_homogeneous_members` is set and the existing members of
+// `repositories` have several package types, listing the members of each. Generic virtual repositories may otherwise
+// aggregate members of any package type.
+func homogeneousMembersDiff(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	if !diff.Get("require_homogeneous_members").(bool) || !membersKnown(diff) {
+		return nil
+	}
 
-	return repository.MkResourceSchema(genericSchema, repository.DefaultPacker(genericSchema), unpack, constructor)
+	membersByType := map[string][]string{}
+	for _, member := range configuredMembers(diff) {
+		info, _, err := repository.GetMemberInfo(m, member)
+		if err != nil {
+			tflog.Debug(ctx, fmt.Sprintf("failed to check package type of member repository %s: %v", member, err))
+			continue
+		}
+		packageType := strings.ToLower(info.PackageType)
+		membersByType[packageType] = append(membersByType[packageType], member)
+	}
+	if len(membersByType) < 2 {
+		return nil
+	}
+
+	packageTypes := make([]string, 0, len(membersByType))
+	for packageType := range membersByType {
+		packageTypes = append(packageTypes, packageType)
+	}
+	sort.Strings(packageTypes)
+	found := make([]string, 0, len(packageTypes))
+	for _, packageType := range packageTypes {
+		found = append(found, fmt.Sprintf("%s (%s)", packageType, strings.Join(membersByType[packageType], ", ")))
+	}
+	return fmt.Errorf("require_homogeneous_members is set, but the members of repositories have %d package types: %s", len(packageTypes), strings.Join(found, "; "))
 }
 
 func ResourceArtifactoryVirtualRepositoryWithRetrievalCachePeriodSecs(pkt string) *schema.Resource {
 	var repoWithRetrivalCachePeriodSecsVirtualSchema = util.MergeSchema(BaseVirtualRepoSchema, map[string]*schema.Schema{
 		"retrieval_cache_period_seconds": {
-			Type:         schema.TypeInt,
-			Optional:     true,
-			Default:      7200,
-			Description:  "This value refers to the number of seconds to cache metadata files before checking for newer versions on aggregated repositories. A value of 0 indicates no caching.",
-			ValidateFunc: validation.IntAtLeast(0),
+			Type:             schema.TypeInt,
+			Optional:         true,
+			Computed:         true,
+			Description:      "This value refers to the number of seconds to cache metadata files before checking for newer versions on aggregated repositories. A value of 0 indicates no caching. Default to the `default_retrieval_cache_period_seconds` of the provider, `7200` unless set.",
+			ValidateDiagFunc: ValidateRetrievalCachePeriodSecs,
 		},
 	}, repository.RepoLayoutRefSchema("virtual", pkt))
 	constructor := func() interface{} {
@@ -46,5 +95,5 @@ func ResourceArtifactoryVirtualRepositoryWithRetrievalCachePeriodSecs(pkt string
 		repo := UnpackBaseVirtRepoWithRetrievalCachePeriodSecs(data, pkt)
 		return repo, repo.Id(), nil
 	}
-	return repository.MkResourceSchema(repoWithRetrivalCachePeriodSecsVirtualSchema, repository.DefaultPacker(repoWithRetrivalCachePeriodSecsVirtualSchema), unpack, constructor)
+	return mkResourceSchema(repoWithRetrivalCachePeriodSecsVirtualSchema, repository.DefaultPacker(repoWithRetrivalCachePeriodSecsVirtualSchema), unpack, constructor)
 }
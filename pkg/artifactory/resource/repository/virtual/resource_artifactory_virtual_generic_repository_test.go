@@ -0,0 +1,50 @@
+package virtual_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+func TestVirtualGenericRepositoryHomogeneousMembers(t *testing.T) {
+	restyClient, _ := mockRepositories(t, map[string]string{
+		"npm-local":     `{"key":"npm-local","rclass":"local","packageType":"npm"}`,
+		"npm-remote":    `{"key":"npm-remote","rclass":"remote","packageType":"npm"}`,
+		"docker-remote": `{"key":"docker-remote","rclass":"remote","packageType":"docker"}`,
+		"generic-local": `{"key":"generic-local","rclass":"local","packageType":"generic"}`,
+	})
+
+	testCases := map[string]struct {
+		repositories  []interface{}
+		required      bool
+		expectedError string
+	}{
+		"homogeneous":             {[]interface{}{"npm-local", "npm-remote"}, true, ""},
+		"mixed required":          {[]interface{}{"npm-local", "docker-remote", "npm-remote", "generic-local"}, true, "require_homogeneous_members is set, but the members of repositories have 3 package types: docker (docker-remote); generic (generic-local); npm (npm-local, npm-remote)"},
+		"mixed allowed":           {[]interface{}{"npm-local", "docker-remote", "generic-local"}, false, ""},
+		"missing members skipped": {[]interface{}{"npm-local", "created-later"}, true, ""},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":                         "foo-generic",
+				"repositories":                testCase.repositories,
+				"require_homogeneous_members": testCase.required,
+			})
+
+			_, err := repoResource.SimpleDiff(context.Background(), nil, config, restyClient)
+			if testCase.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+					t.Fatalf("expected error %q, got %v", testCase.expectedError, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
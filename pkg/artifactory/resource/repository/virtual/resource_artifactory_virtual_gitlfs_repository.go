@@ -0,0 +1,13 @@
+package virtual
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func ResourceArtifactoryVirtualGitlfsRepository() *schema.Resource {
+	resource := ResourceArtifactoryVirtualGenericRepository("gitlfs")
+	// Artifactory accepts members of any package type, but only serves Git LFS objects from Git LFS members
+	resource.CustomizeDiff = customdiff.All(resource.CustomizeDiff, membersPackageTypeDiff("gitlfs"))
+	return resource
+}
@@ -0,0 +1,68 @@
+package virtual_test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+)
+
+func TestAccVirtualGitlfsRepository(t *testing.T) {
+	_, fqrn, name := acctest.MkNames("virtual-gitlfs-repo", "artifactory_virtual_gitlfs_repository")
+	_, _, firstName := acctest.MkNames("gitlfs-local-a", "artifactory_local_gitlfs_repository")
+	_, _, secondName := acctest.MkNames("gitlfs-local-b", "artifactory_local_gitlfs_repository")
+	_, _, genericName := acctest.MkNames("generic-local", "artifactory_local_generic_repository")
+	const template = `
+		resource "artifactory_local_gitlfs_repository" "{{ .firstName }}" {
+		  key = "{{ .firstName }}"
+		}
+
+		resource "artifactory_local_gitlfs_repository" "{{ .secondName }}" {
+		  key = "{{ .secondName }}"
+		}
+
+		resource "artifactory_local_generic_repository" "{{ .genericName }}" {
+		  key = "{{ .genericName }}"
+		}
+
+		resource "artifactory_virtual_gitlfs_repository" "{{ .name }}" {
+		  key          = "{{ .name }}"
+		  repositories = [
+		    artifactory_local_gitlfs_repository.{{ .firstName }}.key,
+		    {{ .lastMember }}.key,
+		  ]
+		}
+	`
+	config := func(lastMember string) string {
+		return acctest.ExecuteTemplate("TestAccVirtualGitlfsRepository", template, map[string]interface{}{
+			"name":        name,
+			"firstName":   firstName,
+			"secondName":  secondName,
+			"genericName": genericName,
+			"lastMember":  lastMember,
+		})
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { acctest.PreCheck(t) },
+		ProviderFactories: acctest.ProviderFactories,
+		CheckDestroy:      acctest.CheckRepoDestroy(t, fqrn),
+		Steps: []resource.TestStep{
+			{
+				Config: config("artifactory_local_gitlfs_repository." + secondName),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(fqrn, "package_type", "gitlfs"),
+					resource.TestCheckResourceAttr(fqrn, "repositories.#", "2"),
+					resource.TestCheckResourceAttr(fqrn, "repositories.0", firstName),
+					resource.TestCheckResourceAttr(fqrn, "repositories.1", secondName),
+				),
+			},
+			{
+				Config:      config("artifactory_local_generic_repository." + genericName),
+				ExpectError: regexp.MustCompile(fmt.Sprintf("can only aggregate gitlfs repositories, these members are not: %s \\(generic\\)", genericName)),
+			},
+		},
+	})
+}
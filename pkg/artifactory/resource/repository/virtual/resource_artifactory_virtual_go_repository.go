@@ -49,7 +49,7 @@ func ResourceArtifactoryVirtualGoRepository() *schema.Resource {
 		return &repo, repo.Key, nil
 	}
 
-	return repository.MkResourceSchema(goVirtualSchema, repository.DefaultPacker(goVirtualSchema), unpackGoVirtualRepository, func() interface{} {
+	return mkResourceSchema(goVirtualSchema, repository.DefaultPacker(goVirtualSchema), unpackGoVirtualRepository, func() interface{} {
 		return &GoVirtualRepositoryParams{
 			VirtualRepositoryBaseParams: VirtualRepositoryBaseParams{
 				Rclass:      "virtual",
@@ -46,5 +46,5 @@ func ResourceArtifactoryVirtualHelmRepository() *schema.Resource {
 		}
 	}
 
-	return repository.MkResourceSchema(helmVirtualSchema, repository.DefaultPacker(helmVirtualSchema), unpackHelmVirtualRepository, constructor)
+	return mkResourceSchema(helmVirtualSchema, repository.DefaultPacker(helmVirtualSchema), unpackHelmVirtualRepository, constructor)
 }
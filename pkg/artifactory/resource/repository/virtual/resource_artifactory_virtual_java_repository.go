@@ -62,7 +62,7 @@ func ResourceArtifactoryVirtualJavaRepository(repoType string) *schema.Resource
 		return &repo, repo.Key, nil
 	}
 
-	return repository.MkResourceSchema(mavenVirtualSchema, repository.DefaultPacker(mavenVirtualSchema), unpackMavenVirtualRepository, func() interface{} {
+	return mkResourceSchema(mavenVirtualSchema, repository.DefaultPacker(mavenVirtualSchema), unpackMavenVirtualRepository, func() interface{} {
 		return &JavaVirtualRepositoryParams{
 			VirtualRepositoryBaseParams: VirtualRepositoryBaseParams{
 				Rclass:      "virtual",
@@ -0,0 +1,43 @@
+package virtual
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-shared/util"
+)
+
+func ResourceArtifactoryVirtualNpmRepository() *schema.Resource {
+
+	const packageType = "npm"
+
+	var npmVirtualSchema = util.MergeSchema(
+		BaseVirtualRepoSchema,
+		externalDependenciesSchema("When set, external dependencies are rewritten, limited to `external_dependencies_patterns`.", "rewritten"),
+		externalDependenciesRemoteRepoSchema,
+		repository.RepoLayoutRefSchema("virtual", packageType),
+	)
+
+	type NpmVirtualRepositoryParams struct {
+		VirtualRepositoryBaseParamsWithRetrievalCachePeriodSecs
+		ExternalDependenciesWithRemoteRepoParams
+	}
+
+	var unpackNpmVirtualRepository = func(s *schema.ResourceData) (interface{}, string, error) {
+		repo := NpmVirtualRepositoryParams{
+			VirtualRepositoryBaseParamsWithRetrievalCachePeriodSecs: UnpackBaseVirtRepoWithRetrievalCachePeriodSecs(s, packageType),
+			ExternalDependenciesWithRemoteRepoParams:                unpackExternalDependenciesWithRemoteRepo(s),
+		}
+		return &repo, repo.Key, nil
+	}
+
+	return withExternalDependenciesRemoteRepo(mkResourceSchema(npmVirtualSchema, packExternalDependencies(npmVirtualSchema), unpackNpmVirtualRepository, func() interface{} {
+		return &NpmVirtualRepositoryParams{
+			VirtualRepositoryBaseParamsWithRetrievalCachePeriodSecs: VirtualRepositoryBaseParamsWithRetrievalCachePeriodSecs{
+				VirtualRepositoryBaseParams: VirtualRepositoryBaseParams{
+					Rclass:      "virtual",
+					PackageType: packageType,
+				},
+			},
+		}
+	}))
+}
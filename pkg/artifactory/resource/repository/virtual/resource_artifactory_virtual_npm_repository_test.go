@@ -0,0 +1,95 @@
+package virtual_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+func TestVirtualNpmRepositoryExternalDependencies(t *testing.T) {
+	patterns := []interface{}{"**/github.com/**"}
+	testCases := map[string]struct {
+		enabled            bool
+		remoteRepo         string
+		expectedPatterns   interface{}
+		expectedRemoteRepo string
+	}{
+		"enabled with remote":    {true, "npm-remote", patterns, "npm-remote"},
+		"enabled without remote": {true, "", patterns, ""},
+		"disabled":               {false, "", nil, ""},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, sent := mockRepositories(t, map[string]string{
+				"npm-remote": `{"key":"npm-remote","rclass":"remote","packageType":"npm"}`,
+			})
+			repoResource := virtual.ResourceArtifactoryVirtualNpmRepository()
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+				"key":                               "foo-npm",
+				"external_dependencies_enabled":     testCase.enabled,
+				"external_dependencies_patterns":    patterns,
+				"external_dependencies_remote_repo": testCase.remoteRepo,
+			})
+
+			if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			if sent["foo-npm"]["externalDependenciesEnabled"] != testCase.enabled {
+				t.Fatalf("expected externalDependenciesEnabled to be %t, got %v", testCase.enabled, sent["foo-npm"])
+			}
+			if !reflect.DeepEqual(sent["foo-npm"]["externalDependenciesPatterns"], testCase.expectedPatterns) {
+				t.Fatalf("expected externalDependenciesPatterns %v, got %v", testCase.expectedPatterns, sent["foo-npm"])
+			}
+			if sent["foo-npm"]["externalDependenciesRemoteRepo"] != testCase.expectedRemoteRepo {
+				t.Fatalf("expected externalDependenciesRemoteRepo %q, got %v", testCase.expectedRemoteRepo, sent["foo-npm"])
+			}
+			if d.Get("retrieval_cache_period_seconds") != 7200 {
+				t.Fatalf("expected the default retrieval cache period, got %v", d.Get("retrieval_cache_period_seconds"))
+			}
+		})
+	}
+}
+
+func TestVirtualNpmRepositoryExternalDependenciesRemoteRepoValidation(t *testing.T) {
+	repoResource := virtual.ResourceArtifactoryVirtualNpmRepository()
+	_, err := repoResource.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"key":                               "foo-npm",
+		"external_dependencies_enabled":     false,
+		"external_dependencies_remote_repo": "npm-remote",
+	}), nil)
+	if err == nil || !strings.Contains(err.Error(), "external_dependencies_enabled is true") {
+		t.Fatalf("expected the remote repo to be rejected while external dependencies are disabled, got %v", err)
+	}
+
+	testCases := map[string]string{
+		"npm-missing": "does not exist",
+		"npm-local":   "must be a remote repository",
+	}
+	for remoteRepo, expectedError := range testCases {
+		t.Run(remoteRepo, func(t *testing.T) {
+			restyClient, sent := mockRepositories(t, map[string]string{
+				"npm-local": `{"key":"npm-local","rclass":"local","packageType":"npm"}`,
+			})
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+				"key":                               "foo-npm",
+				"external_dependencies_enabled":     true,
+				"external_dependencies_remote_repo": remoteRepo,
+			})
+
+			diags := repoResource.CreateContext(context.Background(), d, restyClient)
+			if !diags.HasError() || !strings.Contains(diags[0].Summary, expectedError) {
+				t.Fatalf("expected error %q, got %v", expectedError, diags)
+			}
+			if _, ok := sent["foo-npm"]; ok {
+				t.Fatal("expected the repository not to be created")
+			}
+		})
+	}
+}
@@ -35,7 +35,7 @@ func ResourceArtifactoryVirtualNugetRepository() *schema.Resource {
 		return &repo, repo.Key, nil
 	}
 
-	return repository.MkResourceSchema(nugetVirtualSchema, repository.DefaultPacker(nugetVirtualSchema), unpackNugetVirtualRepository, func() interface{} {
+	return mkResourceSchema(nugetVirtualSchema, repository.DefaultPacker(nugetVirtualSchema), unpackNugetVirtualRepository, func() interface{} {
 		return &NugetVirtualRepositoryParams{
 			VirtualRepositoryBaseParams: VirtualRepositoryBaseParams{
 				Rclass:      "virtual",
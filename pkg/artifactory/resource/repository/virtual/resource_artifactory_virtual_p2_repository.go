@@ -0,0 +1,33 @@
+package virtual
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-shared/util"
+)
+
+func ResourceArtifactoryVirtualP2Repository() *schema.Resource {
+
+	const packageType = "p2"
+
+	p2VirtualSchema := util.MergeSchema(BaseVirtualRepoSchema, repository.RepoLayoutRefSchema("virtual", packageType))
+
+	unpackP2VirtualRepository := func(data *schema.ResourceData) (interface{}, string, error) {
+		repo := UnpackBaseVirtRepo(data, packageType)
+		return repo, repo.Id(), nil
+	}
+
+	constructor := func() interface{} {
+		return &VirtualRepositoryBaseParams{
+			Rclass:      "virtual",
+			PackageType: packageType,
+		}
+	}
+
+	resource := mkResourceSchema(p2VirtualSchema, repository.DefaultPacker(p2VirtualSchema), unpackP2VirtualRepository, constructor)
+	// a P2 virtual repository composes the update sites of its members, which Artifactory only finds in P2 and
+	// generic repositories
+	resource.CustomizeDiff = customdiff.All(resource.CustomizeDiff, membersPackageTypeDiff(packageType))
+	return resource
+}
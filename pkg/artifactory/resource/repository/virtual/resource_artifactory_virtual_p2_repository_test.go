@@ -0,0 +1,94 @@
+package virtual_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+func TestAccVirtualP2Repository(t *testing.T) {
+	_, fqrn, name := acctest.MkNames("virtual-p2-repo", "artifactory_virtual_p2_repository")
+	_, _, eclipseName := acctest.MkNames("p2-eclipse", "artifactory_remote_p2_repository")
+	_, _, orbitName := acctest.MkNames("p2-orbit", "artifactory_remote_p2_repository")
+	config := acctest.ExecuteTemplate("TestAccVirtualP2Repository", `
+		resource "artifactory_remote_p2_repository" "{{ .eclipseName }}" {
+		  key = "{{ .eclipseName }}"
+		  url = "https://download.eclipse.org/releases/latest/"
+		}
+
+		resource "artifactory_remote_p2_repository" "{{ .orbitName }}" {
+		  key = "{{ .orbitName }}"
+		  url = "https://download.eclipse.org/tools/orbit/downloads/latest-R/"
+		}
+
+		resource "artifactory_virtual_p2_repository" "{{ .name }}" {
+		  key          = "{{ .name }}"
+		  repositories = [
+		    artifactory_remote_p2_repository.{{ .eclipseName }}.key,
+		    artifactory_remote_p2_repository.{{ .orbitName }}.key,
+		  ]
+		}
+	`, map[string]interface{}{
+		"name":        name,
+		"eclipseName": eclipseName,
+		"orbitName":   orbitName,
+	})
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { acctest.PreCheck(t) },
+		ProviderFactories: acctest.ProviderFactories,
+		CheckDestroy:      acctest.VerifyDeleted(fqrn, acctest.CheckRepo),
+
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(fqrn, "key", name),
+					resource.TestCheckResourceAttr(fqrn, "package_type", "p2"),
+					resource.TestCheckResourceAttr(fqrn, "repositories.#", "2"),
+					resource.TestCheckResourceAttr(fqrn, "repositories.0", eclipseName),
+					resource.TestCheckResourceAttr(fqrn, "repositories.1", orbitName),
+				),
+			},
+		},
+	})
+}
+
+func TestVirtualP2RepositoryMemberValidation(t *testing.T) {
+	restyClient, _ := mockRepositories(t, map[string]string{
+		"p2-remote":     `{"key":"p2-remote","rclass":"remote","packageType":"p2"}`,
+		"generic-local": `{"key":"generic-local","rclass":"local","packageType":"generic"}`,
+		"maven-remote":  `{"key":"maven-remote","rclass":"remote","packageType":"maven"}`,
+	})
+	repoResource := virtual.ResourceArtifactoryVirtualP2Repository()
+
+	testCases := map[string]struct {
+		members []interface{}
+		err     string
+	}{
+		"p2 and generic":  {[]interface{}{"p2-remote", "generic-local"}, ""},
+		"not created yet": {[]interface{}{"p2-remote", "p2-new"}, ""},
+		"maven":           {[]interface{}{"p2-remote", "maven-remote"}, "can only aggregate p2 or generic repositories, these members are not: maven-remote (maven)"},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":          "foo-p2",
+				"repositories": testCase.members,
+			})
+			_, err := repoResource.Diff(context.Background(), nil, config, restyClient)
+			if testCase.err == "" && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if testCase.err != "" && (err == nil || !strings.Contains(err.Error(), testCase.err)) {
+				t.Fatalf("expected error containing %q, got %v", testCase.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package virtual
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func ResourceArtifactoryVirtualPubRepository() *schema.Resource {
+	resource := ResourceArtifactoryVirtualGenericRepository("pub")
+	// Artifactory accepts members of any package type, but only serves the Dart package index from Pub members
+	resource.CustomizeDiff = customdiff.All(resource.CustomizeDiff, membersPackageTypeDiff("pub"))
+	return resource
+}
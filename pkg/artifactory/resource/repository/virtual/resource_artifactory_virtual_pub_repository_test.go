@@ -0,0 +1,11 @@
+package virtual_test
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccVirtualPubRepository(t *testing.T) {
+	resource.Test(mkVirtualMembersOfOwnTypeTestCase("pub", t))
+}
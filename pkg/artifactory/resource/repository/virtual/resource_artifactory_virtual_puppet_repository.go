@@ -0,0 +1,13 @@
+package virtual
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func ResourceArtifactoryVirtualPuppetRepository() *schema.Resource {
+	resource := ResourceArtifactoryVirtualGenericRepository("puppet")
+	// Artifactory accepts members of any package type, but only serves the Puppet module index from Puppet members
+	resource.CustomizeDiff = customdiff.All(resource.CustomizeDiff, membersPackageTypeDiff("puppet"))
+	return resource
+}
@@ -0,0 +1,32 @@
+package virtual
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-shared/util"
+)
+
+func ResourceArtifactoryVirtualPypiRepository() *schema.Resource {
+
+	const packageType = "pypi"
+
+	pypiVirtualSchema := util.MergeSchema(BaseVirtualRepoSchema, repository.RepoLayoutRefSchema("virtual", packageType))
+
+	unpackPypiVirtualRepository := func(data *schema.ResourceData) (interface{}, string, error) {
+		repo := UnpackBaseVirtRepo(data, packageType)
+		return repo, repo.Id(), nil
+	}
+
+	constructor := func() interface{} {
+		return &VirtualRepositoryBaseParams{
+			Rclass:      "virtual",
+			PackageType: packageType,
+		}
+	}
+
+	resource := mkResourceSchema(pypiVirtualSchema, repository.DefaultPacker(pypiVirtualSchema), unpackPypiVirtualRepository, constructor)
+	// Artifactory resolves the simple index from the members in list order, without members there is nothing to resolve
+	resource.CreateContext = warnOnEmptyMembers("package", resource.CreateContext)
+	resource.UpdateContext = warnOnEmptyMembers("package", resource.UpdateContext)
+	return resource
+}
@@ -202,7 +202,7 @@ func TestAccVirtualGenericRepository_basic(t *testing.T) {
 	resource.Test(t, resource.TestCase{
 		PreCheck:          func() { acctest.PreCheck(t) },
 		ProviderFactories: acctest.ProviderFactories,
-		CheckDestroy:      acctest.VerifyDeleted(fqrn, acctest.CheckRepo),
+		CheckDestroy:      acctest.CheckRepoDestroy(t, fqrn),
 
 		Steps: []resource.TestStep{
 			{
@@ -516,6 +516,7 @@ func TestNugetPackageCreationFull(t *testing.T) {
 	})
 
 }
+
 func TestAccVirtualRepository_full(t *testing.T) {
 	id := test.RandomInt()
 	name := fmt.Sprintf("foo%d", id)
@@ -636,7 +637,7 @@ func TestAccVirtualRepositoryWithInvalidProjectKeyGH318(t *testing.T) {
 		Steps: []resource.TestStep{
 			{
 				Config:      virualRepositoryBasic,
-				ExpectError: regexp.MustCompile(".*project_key must be 3 - 10 lowercase alphanumeric characters"),
+				ExpectError: regexp.MustCompile(".*project keys are 3 to 10 lowercase letters or digits"),
 			},
 		},
 	})
@@ -703,7 +704,7 @@ func mkNewVirtualTestCase(repoType string, t *testing.T, extraFields map[string]
 	return t, resource.TestCase{
 		PreCheck:          func() { acctest.PreCheck(t) },
 		ProviderFactories: acctest.ProviderFactories,
-		CheckDestroy:      acctest.VerifyDeleted(fqrn, acctest.CheckRepo),
+		CheckDestroy:      acctest.CheckRepoDestroy(t, fqrn),
 		Steps: []resource.TestStep{
 			{
 				Config: config,
@@ -748,6 +749,13 @@ func TestAccVirtualDebianRepository_full(t *testing.T) {
             ]
 		}
 	`
+	const virtualRepositoryDefaults = `
+		resource "artifactory_virtual_debian_repository" "%s" {
+			key          = "%s"
+			repositories = []
+			optional_index_compression_formats = ["lzma"]
+		}
+	`
 
 	resource.Test(t, resource.TestCase{
 		PreCheck:          func() { acctest.PreCheck(t) },
@@ -761,6 +769,19 @@ func TestAccVirtualDebianRepository_full(t *testing.T) {
 					resource.TestCheckResourceAttr(fqrn, "key", name),
 					resource.TestCheckResourceAttr(fqrn, "package_type", "debian"),
 					resource.TestCheckResourceAttr(fqrn, "repositories.#", "0"),
+					resource.TestCheckResourceAttr(fqrn, "debian_default_architectures", "i386,amd64"),
+					resource.TestCheckResourceAttr(fqrn, "optional_index_compression_formats.#", "2"),
+					resource.TestCheckTypeSetElemAttr(fqrn, "optional_index_compression_formats.*", "bz2"),
+					resource.TestCheckTypeSetElemAttr(fqrn, "optional_index_compression_formats.*", "xz"),
+				),
+			},
+			{
+				Config: fmt.Sprintf(virtualRepositoryDefaults, name, name),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(fqrn, "key", name),
+					resource.TestCheckResourceAttr(fqrn, "debian_default_architectures", "amd64,i386"),
+					resource.TestCheckResourceAttr(fqrn, "optional_index_compression_formats.#", "1"),
+					resource.TestCheckTypeSetElemAttr(fqrn, "optional_index_compression_formats.*", "lzma"),
 				),
 			},
 		},
@@ -51,7 +51,7 @@ func ResourceArtifactoryVirtualRpmRepository() *schema.Resource {
 		return &repo, repo.Key, nil
 	}
 
-	return repository.MkResourceSchema(rpmVirtualSchema, repository.DefaultPacker(rpmVirtualSchema), unpackRpmVirtualRepository, func() interface{} {
+	return mkResourceSchema(rpmVirtualSchema, repository.DefaultPacker(rpmVirtualSchema), unpackRpmVirtualRepository, func() interface{} {
 		return &RpmVirtualRepositoryParams{
 			VirtualRepositoryBaseParams: VirtualRepositoryBaseParams{
 				Rclass:      "virtual",
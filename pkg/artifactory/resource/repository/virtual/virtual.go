@@ -1,11 +1,30 @@
 package virtual
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
 	"github.com/jfrog/terraform-provider-shared/util"
-	"github.com/jfrog/terraform-provider-shared/validator"
+	"golang.org/x/exp/slices"
 )
 
 type VirtualRepositoryBaseParams struct {
@@ -19,9 +38,15 @@ type VirtualRepositoryBaseParams struct {
 	IncludesPattern                               string   `hcl:"includes_pattern" json:"includesPattern,omitempty"`
 	ExcludesPattern                               string   `hcl:"excludes_pattern" json:"excludesPattern,omitempty"`
 	RepoLayoutRef                                 string   `hcl:"repo_layout_ref" json:"repoLayoutRef,omitempty"`
-	Repositories                                  []string `hcl:"repositories" json:"repositories,omitempty"`
+	Repositories                                  []string `hcl:"repositories" json:"repositories"`
 	ArtifactoryRequestsCanRetrieveRemoteArtifacts bool     `hcl:"artifactory_requests_can_retrieve_remote_artifacts" json:"artifactoryRequestsCanRetrieveRemoteArtifacts,omitempty"`
 	DefaultDeploymentRepo                         string   `hcl:"default_deployment_repo" json:"defaultDeploymentRepo,omitempty"`
+	// Extra are the `extra_attributes`, merged into the payload by the repository CRUD functions
+	Extra map[string]interface{} `json:"-"`
+	// Reset are the JSON fields of the attributes removed from the configuration, sent empty
+	Reset []string `json:"-"`
+	// Served are the JSON fields returned by Artifactory on read, the `effective_extra_attributes` are read from them
+	Served map[string]interface{} `json:"-"`
 }
 
 type VirtualRepositoryBaseParamsWithRetrievalCachePeriodSecs struct {
@@ -33,46 +58,212 @@ func (bp VirtualRepositoryBaseParams) Id() string {
 	return bp.Key
 }
 
+func (bp VirtualRepositoryBaseParams) patterns() (string, string) {
+	return bp.IncludesPattern, bp.ExcludesPattern
+}
+
+func (bp VirtualRepositoryBaseParams) members() []string {
+	return bp.Repositories
+}
+
+func (bp VirtualRepositoryBaseParams) ExtraAttributes() map[string]interface{} {
+	return bp.Extra
+}
+
+// inherit adds fields to the extra attributes, which keep their configured values
+func (bp *VirtualRepositoryBaseParams) inherit(fields map[string]interface{}) {
+	extra := make(map[string]interface{}, len(fields)+len(bp.Extra))
+	for name, value := range fields {
+		extra[name] = value
+	}
+	for name, value := range bp.Extra {
+		extra[name] = value
+	}
+	bp.Extra = extra
+}
+
+func (bp *VirtualRepositoryBaseParams) SetServedFields(fields map[string]interface{}) {
+	bp.Served = fields
+}
+
+func (bp VirtualRepositoryBaseParams) servedFields() map[string]interface{} {
+	return bp.Served
+}
+
+func (bp VirtualRepositoryBaseParams) ResetFields() []string {
+	return bp.Reset
+}
+
+func (bp VirtualRepositoryBaseParams) projectEnvironments() []string {
+	return bp.ProjectEnvironments
+}
+
+func (bp VirtualRepositoryBaseParams) packageType() string {
+	return bp.PackageType
+}
+
+// cachesMetadata marks the params of package types whose virtual repositories cache the aggregated metadata
+func (bp VirtualRepositoryBaseParamsWithRetrievalCachePeriodSecs) cachesMetadata() {}
+
+// requestsCanRetrieveRemoteArtifactsDefault is implemented by the params of package types overriding the default of
+// artifactory_requests_can_retrieve_remote_artifacts, they don't inherit the one of the provider
+type requestsCanRetrieveRemoteArtifactsDefault interface {
+	requestsCanRetrieveRemoteArtifactsDefault() bool
+}
+
 var VirtualRepoTypesLikeGeneric = []string{
-	"docker",
-	"gems",
 	"generic",
-	"gitlfs",
-	"composer",
-	"p2",
-	"pub",
-	"puppet",
-	"pypi",
 }
 
 var VirtualRepoTypesLikeGenericWithRetrievalCachePeriodSecs = []string{
-	"chef",
-	"conan",
 	"conda",
-	"cran",
-	"npm",
+}
+
+// RepoKeyValidator rejects keys ending with '-cache', which Artifactory reserves for the cache of remote repositories.
+var RepoKeyValidator = validation.StringDoesNotMatch(
+	regexp.MustCompile(`(?i)-cache$`),
+	"virtual repository key cannot end with '-cache', the suffix is reserved for remote repository caches",
+)
+
+// ValidateRepoKey enforces the rules from the `key` description before Artifactory rejects the create: no leading digit,
+// no whitespace, only letters, digits, '.', '_' and '-', and no '-cache' suffix.
+func ValidateRepoKey(value interface{}, path cty.Path) diag.Diagnostics {
+	key, ok := value.(string)
+	if !ok {
+		return diag.Errorf("expected type of key to be string")
+	}
+
+	invalid := func(detail string) diag.Diagnostics {
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "Invalid repository key",
+			Detail:        detail,
+			AttributePath: path,
+		}}
+	}
+
+	if key != "" && unicode.IsDigit(rune(key[0])) {
+		return invalid(fmt.Sprintf("key %q must not begin with a number", key))
+	}
+	for _, char := range key {
+		if unicode.IsSpace(char) {
+			return invalid(fmt.Sprintf("key %q must not contain whitespace", key))
+		}
+		if !isRepoKeyChar(char) {
+			return invalid(fmt.Sprintf("key %q contains illegal character %q, only letters, digits, '.', '_' and '-' are allowed", key, char))
+		}
+	}
+
+	return validation.ToDiagFunc(RepoKeyValidator)(value, path)
+}
+
+// ValidatePatternList rejects empty entries in a comma separated pattern list, e.g. a trailing comma, which Artifactory
+// would otherwise store as a meaningless pattern.
+func ValidatePatternList(value interface{}, path cty.Path) diag.Diagnostics {
+	patterns, ok := value.(string)
+	if !ok {
+		return diag.Errorf("expected type of pattern list to be string")
+	}
+	if patterns == "" {
+		return nil
+	}
+
+	for index, pattern := range strings.Split(patterns, ",") {
+		if strings.TrimSpace(pattern) == "" {
+			return diag.Diagnostics{{
+				Severity:      diag.Error,
+				Summary:       "Empty pattern",
+				Detail:        fmt.Sprintf("pattern %d of %q is empty, remove the extra comma", index+1, patterns),
+				AttributePath: path,
+			}}
+		}
+	}
+
+	return nil
+}
+
+// MaxDescriptionLength and MaxNotesLength are the longest `description` and `notes` Artifactory stores, in characters
+const (
+	MaxDescriptionLength = 2048
+	MaxNotesLength       = 2048
+)
+
+// ValidateMaxLength rejects strings longer than max characters. Characters are counted as runes, not bytes, so
+// multibyte content isn't rejected early.
+func ValidateMaxLength(max int) schema.SchemaValidateDiagFunc {
+	return func(value interface{}, path cty.Path) diag.Diagnostics {
+		text, ok := value.(string)
+		if !ok {
+			return diag.Errorf("expected type to be string")
+		}
+
+		if length := utf8.RuneCountInString(text); length > max {
+			return diag.Diagnostics{{
+				Severity:      diag.Error,
+				Summary:       "Value too long",
+				Detail:        fmt.Sprintf("value is %d characters long, Artifactory accepts at most %d", length, max),
+				AttributePath: path,
+			}}
+		}
+		return nil
+	}
+}
+
+func isRepoKeyChar(char rune) bool {
+	return (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9') ||
+		char == '.' || char == '_' || char == '-'
 }
 
 var BaseVirtualRepoSchema = map[string]*schema.Schema{
 	"key": {
-		Type:        schema.TypeString,
-		Required:    true,
-		ForceNew:    true,
-		Description: "The Repository Key. A mandatory identifier for the repository and must be unique. It cannot begin with a number or contain spaces or special characters. For local repositories, we recommend using a '-local' suffix (e.g. 'libs-release-local').",
+		Type:             schema.TypeString,
+		Required:         true,
+		ForceNew:         true,
+		ValidateDiagFunc: ValidateRepoKey,
+		Description:      "The Repository Key. A mandatory identifier for the repository and must be unique. It cannot begin with a number or contain spaces or special characters. For local repositories, we recommend using a '-local' suffix (e.g. 'libs-release-local').",
 	},
 	"project_key": {
 		Type:             schema.TypeString,
 		Optional:         true,
-		ValidateDiagFunc: validator.ProjectKey,
+		ValidateDiagFunc: repository.ValidateProjectKey,
 		Description:      "Project key for assigning this repository to. Must be 3 - 10 lowercase alphanumeric characters. When assigning repository to a project, repository key must be prefixed with project key, separated by a dash.",
 	},
+	"replace_on_project_key_change": {
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "When set, changing `project_key` from one project to another replaces the repository instead of reassigning it in place, for Artifactory versions that can't move a repository between projects. A warning is emitted for in-place reassignments otherwise. Default to 'false'.",
+	},
+
 	"project_environments": {
 		Type:        schema.TypeSet,
 		Elem:        &schema.Schema{Type: schema.TypeString},
 		MaxItems:    2,
 		Set:         schema.HashString,
 		Optional:    true,
-		Description: `Project environment for assigning this repository to. Allow values: "DEV" or "PROD"`,
+		Description: "Project environment for assigning this repository to. Allow values: \"DEV\", \"PROD\" or, with `project_key`, an environment defined in the project",
+	},
+	"available_environments": {
+		Type:        schema.TypeList,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+		Computed:    true,
+		Description: "Environments defined in the project of `project_key`, the values `project_environments` can be set to. Empty without `project_key` or when the project can't be read.",
+	},
+	"read_timestamps": {
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "When set, `created` and `last_updated` are read from the storage info of the repository on refresh, one more request per repository. Default to 'false'.",
+	},
+	"created": {
+		Type:        schema.TypeString,
+		Computed:    true,
+		Description: "When the repository was created, as returned by Artifactory, e.g. `2023-02-13T10:15:30.123Z`. Empty unless `read_timestamps` is set, or when Artifactory doesn't return it.",
+	},
+	"last_updated": {
+		Type:        schema.TypeString,
+		Computed:    true,
+		Description: "When the content of the repository was last updated, as returned by Artifactory. Empty unless `read_timestamps` is set, or when Artifactory doesn't return it.",
 	},
 	"package_type": {
 		Type:        schema.TypeString,
@@ -82,59 +273,232 @@ var BaseVirtualRepoSchema = map[string]*schema.Schema{
 		Description: "The Package Type. This must be specified when the repository is created, and once set, cannot be changed.",
 	},
 	"description": {
-		Type:        schema.TypeString,
-		Optional:    true,
-		Description: "A free text field that describes the content and purpose of the repository.\nIf you choose to insert a link into this field, clicking the link will prompt the user to confirm that they might be redirected to a new domain.",
+		Type:             schema.TypeString,
+		Optional:         true,
+		ValidateDiagFunc: ValidateMaxLength(MaxDescriptionLength),
+		Description:      "A free text field that describes the content and purpose of the repository.\nIf you choose to insert a link into this field, clicking the link will prompt the user to confirm that they might be redirected to a new domain.",
 	},
 	"notes": {
-		Type:        schema.TypeString,
-		Optional:    true,
-		Description: "A free text field to add additional notes about the repository. These are only visible to the administrator.",
+		Type:             schema.TypeString,
+		Optional:         true,
+		ValidateDiagFunc: ValidateMaxLength(MaxNotesLength),
+		Description:      "A free text field to add additional notes about the repository. These are only visible to the administrator.",
 	},
 	"includes_pattern": {
-		Type:     schema.TypeString,
-		Optional: true,
-		Default:  "**/*",
+		Type:             schema.TypeString,
+		Optional:         true,
+		Default:          "**/*",
+		ValidateDiagFunc: ValidatePatternList,
+		ConflictsWith:    []string{"includes_patterns"},
+		DiffSuppressFunc: suppressWithPatternsList("includes_patterns"),
 		Description: "List of artifact patterns to include when evaluating artifact requests in the form of x/y/**/z/*. " +
 			"When used, only artifacts matching one of the include patterns are served. By default, all artifacts are included (**/*).",
 	},
+	"includes_patterns": {
+		Type:          schema.TypeList,
+		Optional:      true,
+		Elem:          &schema.Schema{Type: schema.TypeString, ValidateFunc: patternListElement},
+		ConflictsWith: []string{"includes_pattern"},
+		Description:   "List form of `includes_pattern`, the patterns are joined with commas.",
+	},
 	"excludes_pattern": {
-		Type:     schema.TypeString,
-		Optional: true,
+		Type:             schema.TypeString,
+		Optional:         true,
+		ConflictsWith:    []string{"excludes_patterns"},
+		DiffSuppressFunc: suppressWithPatternsList("excludes_patterns"),
 		Description: "List of artifact patterns to exclude when evaluating artifact requests, in the form of x/y/**/z/*." +
 			"By default no artifacts are excluded.",
 	},
+	"excludes_patterns": {
+		Type:          schema.TypeList,
+		Optional:      true,
+		Elem:          &schema.Schema{Type: schema.TypeString, ValidateFunc: patternListElement},
+		ConflictsWith: []string{"excludes_pattern"},
+		Description:   "List form of `excludes_pattern`, the patterns are joined with commas.",
+	},
+	"effective_includes_pattern": {
+		Type:        schema.TypeString,
+		Computed:    true,
+		Description: "The include patterns as stored by Artifactory, after its normalization of `includes_pattern`. The configured patterns when Artifactory doesn't return them.",
+	},
+	"effective_excludes_pattern": {
+		Type:        schema.TypeString,
+		Computed:    true,
+		Description: "The exclude patterns as stored by Artifactory, after its normalization of `excludes_pattern`.",
+	},
 	"repo_layout_ref": {
 		Type:             schema.TypeString,
 		Optional:         true,
 		ValidateDiagFunc: repository.ValidateRepoLayoutRefSchemaOverride,
+		DiffSuppressFunc: repository.IgnoreCaseDiff,
 		Description:      "Sets the layout that the repository should use for storing and identifying modules. A recommended layout that corresponds to the package type defined is suggested, and index packages uploaded and calculate metadata accordingly.",
 	},
 	"repositories": {
-		Type:        schema.TypeList,
-		Elem:        &schema.Schema{Type: schema.TypeString},
-		Optional:    true,
-		Description: "The effective list of actual repositories included in this virtual repository.",
+		Type:             schema.TypeList,
+		Elem:             &schema.Schema{Type: schema.TypeString},
+		Optional:         true,
+		ConflictsWith:    []string{"member_repositories"},
+		DiffSuppressFunc: suppressWithMemberRepositories,
+		Description:      "The effective list of actual repositories included in this virtual repository.",
+	},
+	"member_repositories": {
+		Type:          schema.TypeSet,
+		Elem:          &schema.Schema{Type: schema.TypeString},
+		Set:           schema.HashString,
+		Optional:      true,
+		ConflictsWith: []string{"repositories"},
+		Description:   "Set form of `repositories`, for members built with `for_each` or `toset` whose resolution order doesn't matter. The members are sent sorted by key.",
 	},
 
 	"artifactory_requests_can_retrieve_remote_artifacts": {
-		Type:        schema.TypeBool,
+		Type: schema.TypeBool,
+		// the default is planned by requestsCanRetrieveRemoteArtifactsDefaultDiff, it may come from the package type or
+		// the provider configuration
 		Optional:    true,
-		Default:     false,
-		Description: "Whether the virtual repository should search through remote repositories when trying to resolve an artifact requested by another Artifactory instance.",
+		Computed:    true,
+		Description: "Whether the virtual repository should search through remote repositories when trying to resolve an artifact requested by another Artifactory instance. Default to 'true' for docker, otherwise to the `default_requests_can_retrieve_remote_artifacts` of the provider, 'false' unless set.",
 	},
 	"default_deployment_repo": {
-		Type:        schema.TypeString,
-		Optional:    true,
+		Type:     schema.TypeString,
+		Optional: true,
+		// the repository resolved by auto_default_deployment_repo isn't in the configuration
+		DiffSuppressFunc: func(_, _, new string, d *schema.ResourceData) bool {
+			return new == "" && d.Get("auto_default_deployment_repo").(bool)
+		},
 		Description: "Default repository to deploy artifacts.",
 	},
+	"auto_default_deployment_repo": {
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "When set and `default_deployment_repo` is unset, the first local repository of `repositories` is used as default deployment repository on apply. A warning is emitted when there is no local member. Default to 'false'.",
+	},
 	"retrieval_cache_period_seconds": {
-		Type:         schema.TypeInt,
-		Optional:     true,
-		Default:      7200,
-		Description:  "This value refers to the number of seconds to cache metadata files before checking for newer versions on aggregated repositories. A value of 0 indicates no caching.",
-		ValidateFunc: validation.IntAtLeast(0),
+		Type: schema.TypeInt,
+		// the default is planned by retrievalCachePeriodDefaultDiff, it may come from the provider configuration
+		Optional:         true,
+		Computed:         true,
+		Description:      "This value refers to the number of seconds to cache metadata files before checking for newer versions on aggregated repositories. A value of 0 indicates no caching. Default to the `default_retrieval_cache_period_seconds` of the provider, `7200` unless set.",
+		ValidateDiagFunc: ValidateRetrievalCachePeriodSecs,
+	},
+	"read_repo_layout_patterns": {
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "When set, `repo_layout_patterns` is read from the system configuration on refresh, which requires admin permissions. Default to 'false'.",
+	},
+	"repo_layout_patterns": {
+		Type:        schema.TypeList,
+		Computed:    true,
+		Description: "Path patterns of the layout referenced by `repo_layout_ref`. Empty unless `read_repo_layout_patterns` is set, or when the layout can't be read.",
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"artifact_path_pattern": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"distinctive_descriptor_path_pattern": {
+					Type:     schema.TypeBool,
+					Computed: true,
+				},
+				"descriptor_path_pattern": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+			},
+		},
+	},
+	"prune_offline_members_on_apply": {
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "When set, member remote repositories that are offline or blacked out are left out of the update, and a warning lists them. The state keeps the configured members. When unset, members are sent as configured. Default to 'false'.",
+	},
+	"best_effort_members": {
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "When set and an update is rejected, the members of `repositories` that don't exist are dropped, the update is retried once without them, and a warning lists them. The state keeps the configured members. When unset, the update is all-or-nothing. Default to 'false'.",
 	},
+	"wait_for_ready": {
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "When set, the repository configuration is polled after create until it can be read, for deployments where a new repository takes a while to propagate to all nodes. The poll gives up after the create timeout. Default to 'false'.",
+	},
+	"copy_from": {
+		Type:             schema.TypeString,
+		Optional:         true,
+		ValidateDiagFunc: ValidateRepoKey,
+		// the configuration is only copied on create
+		DiffSuppressFunc: func(_, _, _ string, d *schema.ResourceData) bool { return d.Id() != "" },
+		Description:      "Key of an existing virtual repository of the same package type whose configuration is used as the base on create. Only the settings not managed by this resource are copied, the configured attributes always apply. Ignored after create, changing it doesn't plan a change.",
+	},
+	"cleanup_dependent_references": {
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "When set and the delete fails, the virtual repositories that still list this repository in `repositories` are looked up and listed in the error, so they can be removed first. References are not removed. Default to 'false'.",
+	},
+	"deletion_protection": {
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "When set, the repository can't be destroyed or replaced, the delete and the plans replacing it fail until it is unset and applied. Default to 'false'.",
+	},
+	"validate_only": {
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "When set, the repository is created to have Artifactory validate the configuration and deleted straight away, on create and on every update, so nothing is left behind. Unsetting it creates the repository, it can't be set on an existing repository. Default to 'false'.",
+	},
+	"adopt_existing": {
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "When set and the create fails because a repository with the same key exists, e.g. after a partial apply, the repository is adopted into the state if its configuration matches. A mismatching repository still fails the create. Ignored with `copy_from` and `validate_only`. Default to 'false'.",
+	},
+	"extra_attributes": {
+		Type:        schema.TypeMap,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+		Optional:    true,
+		Description: "Fields of the repository configuration JSON the provider doesn't support yet, e.g. added by a newer Artifactory version, merged into the configuration sent to Artifactory. Values that are valid JSON, e.g. `true` or `42`, are sent decoded, others as strings. Fields managed by another attribute can't be set.",
+	},
+	"effective_extra_attributes": {
+		Type:        schema.TypeMap,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+		Computed:    true,
+		Description: "The values Artifactory stores for the fields of `extra_attributes`, JSON encoded unless they are strings. Fields Artifactory doesn't know are missing.",
+	},
+}
+
+// MinEffectiveRetrievalCachePeriodSecs is the smallest non-zero cache period that is not almost certainly a typo.
+// Anything below this still caches metadata, but expires it so quickly that it mostly adds upstream load.
+const MinEffectiveRetrievalCachePeriodSecs = 60
+
+// DefaultMaxRetrievalCachePeriodSecs is the largest cache period allowed unless the provider sets
+// max_retrieval_cache_period_seconds. Longer periods are most likely typos, e.g. a period in milliseconds.
+const DefaultMaxRetrievalCachePeriodSecs = 365 * 24 * 60 * 60
+
+// ValidateRetrievalCachePeriodSecs rejects negative values and values Artifactory can't store as a 32-bit integer,
+// and warns when caching is enabled with a period too short to be useful. 0 is valid and disables caching.
+// The configurable maximum is checked at plan time by retrievalCachePeriodMaxDiff, as it depends on the provider.
+func ValidateRetrievalCachePeriodSecs(value interface{}, path cty.Path) diag.Diagnostics {
+	diags := validation.ToDiagFunc(validation.All(validation.IntAtLeast(0), validation.IntAtMost(math.MaxInt32)))(value, path)
+	if diags.HasError() {
+		return diags
+	}
+
+	if period := value.(int); period > 0 && period < MinEffectiveRetrievalCachePeriodSecs {
+		diags = append(diags, diag.Diagnostic{
+			Severity:      diag.Warning,
+			Summary:       "Ineffective retrieval cache period",
+			Detail:        fmt.Sprintf("retrieval_cache_period_seconds is set to %d seconds. Values below %d seconds expire metadata almost immediately; use 0 to disable caching or a larger value to benefit from it.", period, MinEffectiveRetrievalCachePeriodSecs),
+			AttributePath: path,
+		})
+	}
+
+	return diags
 }
 
 func UnpackBaseVirtRepo(s *schema.ResourceData, packageType string) VirtualRepositoryBaseParams {
@@ -144,24 +508,1748 @@ func UnpackBaseVirtRepo(s *schema.ResourceData, packageType string) VirtualRepos
 		Key:                 d.GetString("key", false),
 		Rclass:              "virtual",
 		ProjectKey:          d.GetString("project_key", false),
-		ProjectEnvironments: d.GetSet("project_environments"),
+		ProjectEnvironments: repository.GetProjectEnvironments(d),
 		PackageType:         packageType, // must be set independently
-		IncludesPattern:     d.GetString("includes_pattern", false),
-		ExcludesPattern:     d.GetString("excludes_pattern", false),
+		IncludesPattern:     configuredPatterns(s, "includes_pattern"),
+		ExcludesPattern:     configuredPatterns(s, "excludes_pattern"),
 		RepoLayoutRef:       d.GetString("repo_layout_ref", false),
 		ArtifactoryRequestsCanRetrieveRemoteArtifacts: d.GetBool("artifactory_requests_can_retrieve_remote_artifacts", false),
-		Repositories:          d.GetList("repositories"),
+		Repositories:          configuredMembers(s),
 		Description:           d.GetString("description", false),
 		Notes:                 d.GetString("notes", false),
 		DefaultDeploymentRepo: repository.HandleResetWithNonExistantValue(d, "default_deployment_repo"),
+		Extra:                 unpackExtraAttributes(s.Get("extra_attributes").(map[string]interface{})),
+		Reset:                 repository.GetResetFields(d),
+	}
+}
+
+// unpackExtraAttributes decodes the values of `extra_attributes` that are valid JSON, the others are kept as strings
+func unpackExtraAttributes(attributes map[string]interface{}) map[string]interface{} {
+	if len(attributes) == 0 {
+		return nil
 	}
+
+	extra := make(map[string]interface{}, len(attributes))
+	for name, value := range attributes {
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(value.(string)), &decoded); err != nil {
+			decoded = value
+		}
+		extra[name] = decoded
+	}
+	return extra
 }
 
 func UnpackBaseVirtRepoWithRetrievalCachePeriodSecs(s *schema.ResourceData, packageType string) VirtualRepositoryBaseParamsWithRetrievalCachePeriodSecs {
 	d := &util.ResourceData{s}
 
-	return VirtualRepositoryBaseParamsWithRetrievalCachePeriodSecs{
+	repo := VirtualRepositoryBaseParamsWithRetrievalCachePeriodSecs{
 		VirtualRepositoryBaseParams:     UnpackBaseVirtRepo(s, packageType),
 		VirtualRetrievalCachePeriodSecs: d.GetInt("retrieval_cache_period_seconds", false),
 	}
+	// the period inherited from the provider is planned by retrievalCachePeriodDefaultDiff, it's only unknown when
+	// the resource is applied without a plan
+	if _, ok := s.GetOkExists("retrieval_cache_period_seconds"); !ok {
+		repo.VirtualRetrievalCachePeriodSecs = DefaultRetrievalCachePeriodSecs
+	}
+	return repo
+}
+
+// externalDependenciesSchema has the attributes of the package types resolving external dependencies. They only differ
+// by what Artifactory does with the dependencies matching the patterns, enabledDescription and action tell it.
+func externalDependenciesSchema(enabledDescription, action string) map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"external_dependencies_enabled": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: enabledDescription + " Default to 'false'.",
+		},
+		"external_dependencies_patterns": {
+			Type:     schema.TypeList,
+			Optional: true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+			Description: fmt.Sprintf("An allow list of Ant-style path patterns that determine which external dependencies may be %s. ", action) +
+				"Ignored and not sent when `external_dependencies_enabled` is false.",
+		},
+	}
+}
+
+// externalDependenciesRemoteRepoSchema is the attribute added to externalDependenciesSchema by the package types that
+// cache the external dependencies in a remote member
+var externalDependenciesRemoteRepoSchema = map[string]*schema.Schema{
+	"external_dependencies_remote_repo": {
+		Type:             schema.TypeString,
+		Optional:         true,
+		ValidateDiagFunc: ValidateRepoKey,
+		Description:      "The remote repository aggregated by this virtual repository in which the external dependencies are cached. Can only be set when `external_dependencies_enabled` is true.",
+	},
+}
+
+type ExternalDependenciesParams struct {
+	ExternalDependenciesEnabled  bool     `hcl:"external_dependencies_enabled" json:"externalDependenciesEnabled"`
+	ExternalDependenciesPatterns []string `hcl:"external_dependencies_patterns" json:"externalDependenciesPatterns,omitempty"`
+}
+
+func (p ExternalDependenciesParams) externalDependencies() (bool, []string) {
+	return p.ExternalDependenciesEnabled, p.ExternalDependenciesPatterns
+}
+
+type ExternalDependenciesWithRemoteRepoParams struct {
+	ExternalDependenciesParams
+	ExternalDependenciesRemoteRepo string `hcl:"external_dependencies_remote_repo" json:"externalDependenciesRemoteRepo"`
+}
+
+// unpackExternalDependencies only unpacks the patterns when enabled, Artifactory ignores them otherwise
+func unpackExternalDependencies(s *schema.ResourceData) ExternalDependenciesParams {
+	d := &util.ResourceData{s}
+
+	params := ExternalDependenciesParams{ExternalDependenciesEnabled: d.GetBool("external_dependencies_enabled", false)}
+	if params.ExternalDependenciesEnabled {
+		params.ExternalDependenciesPatterns = d.GetList("external_dependencies_patterns")
+	}
+	return params
+}
+
+func unpackExternalDependenciesWithRemoteRepo(s *schema.ResourceData) ExternalDependenciesWithRemoteRepoParams {
+	params := ExternalDependenciesWithRemoteRepoParams{ExternalDependenciesParams: unpackExternalDependencies(s)}
+	if params.ExternalDependenciesEnabled {
+		params.ExternalDependenciesRemoteRepo = (&util.ResourceData{s}).GetString("external_dependencies_remote_repo", false)
+	}
+	return params
+}
+
+// packExternalDependencies is the packer of the package types resolving external dependencies. The patterns are only
+// packed when enabled, otherwise the configured (and ignored) patterns would show as drift.
+func packExternalDependencies(skeema map[string]*schema.Schema) repository.PackFunc {
+	return repository.ComposePacker(
+		repository.UniversalPack(
+			repository.AllHclPredicate(
+				util.SchemaHasKey(skeema),
+				repository.IgnoreHclPredicate("external_dependencies_patterns"),
+			),
+		),
+		func(repo interface{}, d *schema.ResourceData) error {
+			enabled, patterns := repo.(interface{ externalDependencies() (bool, []string) }).externalDependencies()
+			if !enabled {
+				return nil
+			}
+			return d.Set("external_dependencies_patterns", patterns)
+		},
+	)
+}
+
+// withExternalDependenciesRemoteRepo adds the checks of `external_dependencies_remote_repo` to the resource
+func withExternalDependenciesRemoteRepo(resource *schema.Resource) *schema.Resource {
+	resource.CreateContext = checkExternalDependenciesRemoteRepo(resource.CreateContext)
+	resource.UpdateContext = checkExternalDependenciesRemoteRepo(resource.UpdateContext)
+	resource.CustomizeDiff = customdiff.All(resource.CustomizeDiff, externalDependenciesRemoteRepoDiff)
+	return resource
+}
+
+// externalDependenciesRemoteRepoDiff fails the plan when `external_dependencies_remote_repo` is set while external
+// dependencies are disabled, Artifactory would silently ignore it.
+func externalDependenciesRemoteRepoDiff(_ context.Context, diff *schema.ResourceDiff, _ interface{}) error {
+	if diff.Get("external_dependencies_remote_repo").(string) != "" && !diff.Get("external_dependencies_enabled").(bool) {
+		return fmt.Errorf("external_dependencies_remote_repo can only be set when external_dependencies_enabled is true")
+	}
+	return nil
+}
+
+// checkExternalDependenciesRemoteRepo verifies the remote repository for external dependencies exists before the
+// write. It's checked at apply rather than plan, as it may be created in the same apply.
+func checkExternalDependenciesRemoteRepo(apply func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		remoteRepo := d.Get("external_dependencies_remote_repo").(string)
+		if remoteRepo == "" || !d.Get("external_dependencies_enabled").(bool) {
+			return apply(ctx, d, m)
+		}
+
+		info, resp, err := repository.GetMemberInfo(m, remoteRepo)
+		if err != nil {
+			if repository.IsNotFound(resp) {
+				return diag.Errorf("external_dependencies_remote_repo %s does not exist", remoteRepo)
+			}
+			return diag.Errorf("failed to check external_dependencies_remote_repo %s: %s", remoteRepo, err)
+		}
+		if info.Rclass != "remote" {
+			return diag.Errorf("external_dependencies_remote_repo %s is a %s repository, it must be a remote repository", remoteRepo, info.Rclass)
+		}
+		return apply(ctx, d, m)
+	}
+}
+
+func mkResourceSchema(skeema map[string]*schema.Schema, packer repository.PackFunc, unpack repository.UnpackFunc, constructor repository.Constructor) *schema.Resource {
+	resource := repository.MkResourceSchema(skeema, repository.ComposePacker(packer, packEffectivePatterns, packProjectEnvironments, packMemberRepositories, packEffectiveExtraAttributes), unpack, constructor)
+	params, ok := constructor().(interface{ packageType() string })
+	if !ok {
+		panic(fmt.Sprintf("the virtual repository params %T don't embed VirtualRepositoryBaseParams", constructor()))
+	}
+	packageType := params.packageType()
+	resource.ReadContext = warnOnPackageTypeChange(packageType,
+		clearMissingDefaultDeploymentRepo(
+			warnOnIncompatibleMembers(
+				keepDroppedMembers(
+					readAvailableEnvironments(
+						readTimestamps(
+							readRepoLayoutPatterns(
+								readEffectiveIncludesPattern(resource.ReadContext))))))))
+	readAfterCreate := waitForReady(resource.ReadContext)
+	_, cachesMetadata := constructor().(interface{ cachesMetadata() })
+	var typeDefault *bool
+	if withDefault, ok := constructor().(requestsCanRetrieveRemoteArtifactsDefault); ok {
+		value := withDefault.requestsCanRetrieveRemoteArtifactsDefault()
+		typeDefault = &value
+	}
+
+	// The wrappers run from the outermost in: validate_only, the checks and warnings, then the write. copy_from
+	// creates the repository through adopt_existing, with the settings it inherits.
+	createWith := func(unpack repository.UnpackFunc) schema.CreateContextFunc {
+		return adoptExisting(unpack, readAfterCreate,
+			repository.MkRepoCreate(unpack, readAfterCreate))
+	}
+	create := warnOnUncachedMembers(cachesMetadata,
+		warnOnAllExcludingPatterns(
+			checkRepoLayoutExists(packageType,
+				warnOnIgnoredProjectEnvironments(
+					warnOnMemberOrdering(
+						autoDefaultDeploymentRepo(
+							copyFrom(unpack, createWith)))))))
+	update := warnOnUncachedMembers(cachesMetadata,
+		warnOnAllExcludingPatterns(
+			checkRepoLayoutExists(packageType,
+				warnOnIgnoredProjectEnvironments(
+					warnOnMemberOrdering(
+						autoDefaultDeploymentRepo(
+							pruneOfflineMembers(
+								bestEffortMembers(
+									repository.MkRepoPartialUpdate(unpack, resource.ReadContext)))))))))
+	resource.CreateContext, resource.UpdateContext = validateOnly(create, update)
+	resource.ReadContext = skipIfValidateOnly(resource.ReadContext)
+	resource.DeleteContext = skipIfValidateOnly(
+		preventProtectedDeletion(
+			reportDependentReferences(resource.DeleteContext)))
+	resource.Importer = &schema.ResourceImporter{
+		StateContext: importProjectScopedKey,
+	}
+	resource.Timeouts = &schema.ResourceTimeout{
+		Create: schema.DefaultTimeout(DefaultWaitForReadyTimeout),
+	}
+	resource.CustomizeDiff = customdiff.All(
+		resource.CustomizeDiff,
+		selfReferenceDiff,
+		duplicateMembersDiff,
+		patternListDiff,
+		patternsLengthDiff,
+		maxMembersDiff,
+		retrievalCachePeriodDefaultDiff(cachesMetadata),
+		retrievalCachePeriodUnsupportedDiff(cachesMetadata, packageType),
+		retrievalCachePeriodMaxDiff,
+		uncachedMembersDiff(cachesMetadata),
+		requestsCanRetrieveRemoteArtifactsDefaultDiff(typeDefault),
+		validateOnlyChangeDiff,
+		defaultDeploymentRepoDiff,
+		defaultDeploymentRepoMemberDiff,
+		keyChangeDiff,
+		keyAvailableDiff,
+		repoLayoutRefChangeDiff(packageType),
+		projectMembersDiff,
+		packageTypeChangeDiff(packageType),
+		protectedReplacementDiff(resource.Schema),
+	)
+	return resource
+}
+
+// packageTypeChangeDiff plans the package type of the resource for new repositories, so `package_type` is known at
+// plan time, e.g. in the `count` of another resource. It also plans the replacement of a repository whose package type
+// differs from the one of the resource, e.g. after it was recreated out-of-band with another package type.
+// `package_type` is immutable, so the repository can only be destroyed and created again.
+func packageTypeChangeDiff(packageType string) schema.CustomizeDiffFunc {
+	return func(_ context.Context, diff *schema.ResourceDiff, _ interface{}) error {
+		if diff.Id() == "" {
+			return diff.SetNew("package_type", packageType)
+		}
+		if current := diff.Get("package_type").(string); current == "" || current == packageType {
+			return nil
+		}
+
+		if err := diff.SetNew("package_type", packageType); err != nil {
+			return err
+		}
+		return diff.ForceNew("package_type")
+	}
+}
+
+// warnOnPackageTypeChange explains on read, so during the plan, the replacement planned by packageTypeChangeDiff.
+// Plan-time checks can't return warnings.
+func warnOnPackageTypeChange(packageType string, read schema.ReadContextFunc) schema.ReadContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		diags := read(ctx, d, m)
+		current := d.Get("package_type").(string)
+		if diags.HasError() || d.Id() == "" || current == "" || current == packageType {
+			return diags
+		}
+
+		return append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "Repository will be destroyed and recreated",
+			Detail: fmt.Sprintf("The repository %s is a %s repository, but this resource manages %s repositories. "+
+				"The package type of a repository can't be changed, so the next apply destroys the repository and creates it again as a %s repository. "+
+				"Its members and any settings not in the configuration are reset.", d.Id(), current, packageType, packageType),
+			AttributePath: cty.GetAttrPath("package_type"),
+		})
+	}
+}
+
+// patternListElement validates a single pattern of the list forms, which are joined with commas
+var patternListElement = validation.All(validation.StringIsNotWhiteSpace, validation.StringDoesNotContainAny(","))
+
+// configuredPatterns returns the patterns of `key`, joining its list form with commas when it is used. The whitespace
+// around the patterns is trimmed, like Artifactory stores them.
+func configuredPatterns(d interface{ Get(string) interface{} }, key string) string {
+	if patterns := d.Get(key + "s").([]interface{}); len(patterns) > 0 {
+		return normalizePatterns(strings.Join(util.CastToStringArr(patterns), ","))
+	}
+	return normalizePatterns(d.Get(key).(string))
+}
+
+// configuredMembers returns the members of `member_repositories` sorted by key when the set form is used, otherwise
+// the members of `repositories` in their resolution order
+func configuredMembers(d interface{ Get(string) interface{} }) []string {
+	if members := d.Get("member_repositories").(*schema.Set); members.Len() > 0 {
+		sorted := util.CastToStringArr(members.List())
+		sort.Strings(sorted)
+		return sorted
+	}
+	return util.CastToStringArr(d.Get("repositories").([]interface{}))
+}
+
+// membersKnown reports whether the members of both forms are known at plan time
+func membersKnown(diff *schema.ResourceDiff) bool {
+	return diff.NewValueKnown("repositories") && diff.NewValueKnown("member_repositories")
+}
+
+// suppressWithMemberRepositories ignores `repositories` while the set form is used, the read stores the members in both
+// and they would otherwise be planned for removal
+func suppressWithMemberRepositories(_, _, _ string, d *schema.ResourceData) bool {
+	return d.Get("member_repositories").(*schema.Set).Len() > 0
+}
+
+// packMemberRepositories stores the members returned by Artifactory in `member_repositories` when the set form is used
+func packMemberRepositories(repo interface{}, d *schema.ResourceData) error {
+	withMembers, ok := repo.(interface{ members() []string })
+	if !ok || d.Get("member_repositories").(*schema.Set).Len() == 0 {
+		return nil
+	}
+	if errors := util.MkLens(d)("member_repositories", withMembers.members()); len(errors) > 0 {
+		return fmt.Errorf("failed to pack member_repositories %q", errors)
+	}
+	return nil
+}
+
+// normalizePatterns trims the whitespace around each pattern of a comma separated list, e.g. `a, b` is `a,b`
+func normalizePatterns(patterns string) string {
+	return strings.Join(splitPatterns(patterns), ",")
+}
+
+func splitPatterns(patterns string) []string {
+	if patterns == "" {
+		return []string{}
+	}
+	split := strings.Split(patterns, ",")
+	for i, pattern := range split {
+		split[i] = strings.TrimSpace(pattern)
+	}
+	return split
+}
+
+// suppressWithPatternsList ignores the string form while the list form listKey is used, the read stores the joined
+// patterns in both and the string form would otherwise be planned back to its default. Patterns only differing by the
+// whitespace around the commas are the same once normalized, and don't plan a change either.
+func suppressWithPatternsList(listKey string) schema.SchemaDiffSuppressFunc {
+	return func(_, old, new string, d *schema.ResourceData) bool {
+		return len(d.Get(listKey).([]interface{})) > 0 || normalizePatterns(old) == normalizePatterns(new)
+	}
+}
+
+// patternListDiff fails the plan on empty patterns in `includes_pattern` or `excludes_pattern`, e.g. a trailing comma.
+// Unlike the validation of the attributes, it also checks patterns interpolated from values only known at plan time.
+func patternListDiff(_ context.Context, diff *schema.ResourceDiff, _ interface{}) error {
+	for _, key := range []string{"includes_pattern", "excludes_pattern"} {
+		if !diff.HasChange(key) || !diff.NewValueKnown(key) {
+			continue
+		}
+		if diags := ValidatePatternList(diff.Get(key), cty.GetAttrPath(key)); diags.HasError() {
+			return fmt.Errorf("%s: %s", key, diags[0].Detail)
+		}
+	}
+	return nil
+}
+
+// packEffectivePatterns stores the patterns returned by Artifactory, which may differ from the configured ones, e.g. with
+// whitespace around the commas removed.
+func packEffectivePatterns(repo interface{}, d *schema.ResourceData) error {
+	withPatterns, ok := repo.(interface{ patterns() (string, string) })
+	if !ok {
+		return nil
+	}
+
+	includesPattern, excludesPattern := withPatterns.patterns()
+	setValue := util.MkLens(d)
+	setValue("effective_includes_pattern", includesPattern)
+	errors := setValue("effective_excludes_pattern", excludesPattern)
+	// the list forms are only read back when used, the string forms are stored by the default packer
+	for key, patterns := range map[string]string{"includes_patterns": includesPattern, "excludes_patterns": excludesPattern} {
+		if len(d.Get(key).([]interface{})) > 0 {
+			errors = setValue(key, splitPatterns(patterns))
+		}
+	}
+	if len(errors) > 0 {
+		return fmt.Errorf("failed saving effective patterns to state %q", errors)
+	}
+	return nil
+}
+
+// readEffectiveIncludesPattern echoes the configured include patterns as `effective_includes_pattern` when Artifactory
+// doesn't return the patterns it resolved, so the attribute always shows the filtering in use. The configuration is
+// captured before the read, which overwrites it with what Artifactory returns. Exclude patterns aren't echoed, no
+// exclude patterns is a valid answer.
+func readEffectiveIncludesPattern(read schema.ReadContextFunc) schema.ReadContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		configured := configuredPatterns(d, "includes_pattern")
+		diags := read(ctx, d, m)
+		if diags.HasError() || d.Id() == "" || configured == "" || d.Get("effective_includes_pattern").(string) != "" {
+			return diags
+		}
+		return append(diags, diag.FromErr(d.Set("effective_includes_pattern", configured))...)
+	}
+}
+
+// packProjectEnvironments stores the environments returned by Artifactory as a set, so the order Artifactory lists them
+// in never shows as a diff. Like the pattern lists, they are only read back when configured, as Artifactory may return
+// default environments for a repository that doesn't set any.
+func packProjectEnvironments(repo interface{}, d *schema.ResourceData) error {
+	withEnvironments, ok := repo.(interface{ projectEnvironments() []string })
+	if !ok || d.Get("project_environments").(*schema.Set).Len() == 0 {
+		return nil
+	}
+
+	environments := withEnvironments.projectEnvironments()
+	sort.Strings(environments)
+	if errors := util.MkLens(d)("project_environments", environments); len(errors) > 0 {
+		return fmt.Errorf("failed saving project environments to state %q", errors)
+	}
+	return nil
+}
+
+// warnOnEmptyMembers warns when the virtual repository is left without members nor a default deployment repository.
+// For package types like docker or pypi such a repository serves nothing, so it's almost always a mistake, e.g. the
+// last member was removed by accident.
+func warnOnEmptyMembers(served string, apply func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		diags := apply(ctx, d, m)
+		if diags.HasError() || len(d.Get("repositories").([]interface{})) > 0 || d.Get("default_deployment_repo").(string) != "" {
+			return diags
+		}
+
+		return append(diags, diag.Diagnostic{
+			Severity:      diag.Warning,
+			Summary:       "Virtual repository has no members",
+			Detail:        fmt.Sprintf("The virtual repository %s has no member repositories in `repositories` and will not serve any %s.", d.Id(), served),
+			AttributePath: cty.GetAttrPath("repositories"),
+		})
+	}
+}
+
+// autoDefaultDeploymentRepo sets `default_deployment_repo` to the first local member before the apply when
+// `auto_default_deployment_repo` is set and the default isn't, or no longer is, a member. The resolved repository is
+// stored in the state, a warning is emitted when no member is a local repository.
+func autoDefaultDeploymentRepo(apply func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		if !d.Get("auto_default_deployment_repo").(bool) {
+			return apply(ctx, d, m)
+		}
+
+		members := configuredMembers(d)
+		if current := d.Get("default_deployment_repo").(string); current != "" && slices.Contains(members, current) {
+			return apply(ctx, d, m)
+		}
+
+		resolved := ""
+		for _, member := range members {
+			info, _, err := repository.GetMemberInfo(m, member)
+			if err != nil {
+				return diag.Errorf("failed to read member repository %s to resolve the default deployment repository: %s", member, err)
+			}
+			if info.Rclass == "local" {
+				resolved = member
+				break
+			}
+		}
+
+		if err := d.Set("default_deployment_repo", resolved); err != nil {
+			return diag.FromErr(err)
+		}
+		diags := apply(ctx, d, m)
+		if diags.HasError() || resolved != "" {
+			return diags
+		}
+		return append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "No local member to deploy to",
+			Detail: fmt.Sprintf("auto_default_deployment_repo is set, but none of the members of the virtual repository %s is a local repository. "+
+				"The repository has no default deployment repository.", d.Id()),
+			AttributePath: cty.GetAttrPath("auto_default_deployment_repo"),
+		})
+	}
+}
+
+// warnOnIgnoredProjectEnvironments warns when `project_environments` is set without a `project_key`, e.g. after the
+// repository was unassigned from its project. Artifactory only applies environments to project repositories.
+func warnOnIgnoredProjectEnvironments(apply func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		diags := apply(ctx, d, m)
+		if diags.HasError() || d.Get("project_key").(string) != "" || d.Get("project_environments").(*schema.Set).Len() == 0 {
+			return diags
+		}
+
+		return append(diags, diag.Diagnostic{
+			Severity:      diag.Warning,
+			Summary:       "Project environments ignored",
+			Detail:        fmt.Sprintf("The repository %s has `project_environments` set but no `project_key`. The environments are ignored unless the repository is assigned to a project.", d.Id()),
+			AttributePath: cty.GetAttrPath("project_environments"),
+		})
+	}
+}
+
+// DefaultRetrievalCachePeriodSecs is the default of `retrieval_cache_period_seconds`
+const DefaultRetrievalCachePeriodSecs = 7200
+
+// warnOnMemberOrdering informs about virtual members listed before local members when `repositories` changes.
+// Artifactory resolves members in list order, so a nested virtual repository listed first shadows the local ones.
+// Members that can't be looked up are skipped.
+func warnOnMemberOrdering(apply func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		diags := apply(ctx, d, m)
+		if diags.HasError() || !d.HasChanges("repositories", "member_repositories") {
+			return diags
+		}
+
+		var virtuals, precedingVirtuals []string
+		for _, member := range configuredMembers(d) {
+			info, _, err := repository.GetMemberInfo(m, member)
+			if err != nil {
+				tflog.Debug(ctx, fmt.Sprintf("failed to check type of member repository %s: %v", member, err))
+				continue
+			}
+			switch info.Rclass {
+			case "virtual":
+				virtuals = append(virtuals, member)
+			case "local":
+				precedingVirtuals = virtuals
+			}
+		}
+
+		if len(precedingVirtuals) == 0 {
+			return diags
+		}
+		return append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "Virtual members precede local members",
+			Detail: fmt.Sprintf("In the virtual repository %s, the virtual members %s are listed before local members. "+
+				"Artifactory resolves members in list order, so artifacts found through them take precedence over the local repositories listed after.",
+				d.Id(), strings.Join(precedingVirtuals, ", ")),
+			AttributePath: cty.GetAttrPath("repositories"),
+		})
+	}
+}
+
+// readRepoLayoutPatterns resolves `repo_layout_ref` after the read to expose the layout's path patterns, when opted in
+// with `read_repo_layout_patterns`. A failed lookup only clears the patterns, they're informational and the credentials
+// may not be allowed to read the layouts.
+func readRepoLayoutPatterns(read schema.ReadContextFunc) schema.ReadContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		diags := read(ctx, d, m)
+		if diags.HasError() || d.Id() == "" {
+			return diags
+		}
+
+		var patterns []interface{}
+		layoutRef := d.Get("repo_layout_ref").(string)
+		if d.Get("read_repo_layout_patterns").(bool) && layoutRef != "" {
+			restyClient, err := repository.RestyClientOf(m, "read_repo_layout_patterns")
+			if err != nil {
+				return append(diags, diag.FromErr(err)...)
+			}
+			layout, err := repository.GetRepoLayout(layoutRef, restyClient)
+			if err != nil {
+				tflog.Warn(ctx, fmt.Sprintf("failed to read repository layout %s: %s", layoutRef, err))
+			} else {
+				patterns = []interface{}{map[string]interface{}{
+					"artifact_path_pattern":               layout.ArtifactPathPattern,
+					"distinctive_descriptor_path_pattern": layout.DistinctiveDescriptorPathPattern,
+					"descriptor_path_pattern":             layout.DescriptorPathPattern,
+				}}
+			}
+		}
+
+		if err := d.Set("repo_layout_patterns", patterns); err != nil {
+			return append(diags, diag.FromErr(err)...)
+		}
+		return diags
+	}
+}
+
+// checkRepoLayoutExists fails the apply when repo_layout_ref, e.g. interpolated from a layout managed elsewhere, isn't
+// a layout of the instance, listing the available ones. The default layout of the package type isn't looked up. The
+// check is skipped when the layouts can't be read, which requires admin permissions, Artifactory then validates it.
+func checkRepoLayoutExists(packageType string, apply func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		layoutRef := d.Get("repo_layout_ref").(string)
+		if layoutRef == "" || (d.Id() != "" && !d.HasChange("repo_layout_ref")) {
+			return apply(ctx, d, m)
+		}
+		if defaultLayout, err := repository.GetDefaultRepoLayoutRef("virtual", packageType)(); err == nil && strings.EqualFold(layoutRef, defaultLayout.(string)) {
+			return apply(ctx, d, m)
+		}
+		restyClient, err := repository.RestyClientOf(m, "repo_layout_ref")
+		if err != nil {
+			return diag.FromErr(err)
+		}
+
+		_, err = repository.GetRepoLayout(layoutRef, restyClient)
+		var notFound *repository.RepoLayoutNotFoundError
+		if errors.As(err, &notFound) {
+			return diag.Diagnostics{{
+				Severity:      diag.Error,
+				Summary:       "Unknown repository layout",
+				Detail:        fmt.Sprintf("repo_layout_ref %s is not a repository layout of the instance, the available layouts are: %s", layoutRef, strings.Join(notFound.Available, ", ")),
+				AttributePath: cty.GetAttrPath("repo_layout_ref"),
+			}}
+		}
+		if err != nil {
+			tflog.Debug(ctx, fmt.Sprintf("failed to check repository layout %s: %s", layoutRef, err))
+		}
+		return apply(ctx, d, m)
+	}
+}
+
+// readAvailableEnvironments exposes the environments of the repository's project after the read. A failed lookup only
+// clears them, like the layout patterns they're informational.
+func readAvailableEnvironments(read schema.ReadContextFunc) schema.ReadContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		diags := read(ctx, d, m)
+		if diags.HasError() || d.Id() == "" {
+			return diags
+		}
+
+		var available []string
+		if projectKey := d.Get("project_key").(string); projectKey != "" {
+			restyClient, err := repository.RestyClientOf(m, "available_environments")
+			if err != nil {
+				return append(diags, diag.FromErr(err)...)
+			}
+			environments, err := repository.GetCachedProjectEnvironments(projectKey, restyClient)
+			if err != nil {
+				tflog.Warn(ctx, fmt.Sprintf("failed to read environments of project %s: %s", projectKey, err))
+			}
+			available = environments
+		}
+
+		if err := d.Set("available_environments", available); err != nil {
+			return append(diags, diag.FromErr(err)...)
+		}
+		return diags
+	}
+}
+
+// RepositoryStorageEndpoint has the storage info of the root folder of a repository, with its timestamps
+const RepositoryStorageEndpoint = "artifactory/api/storage/{repoKey}"
+
+type repositoryStorage struct {
+	Created      string `json:"created"`
+	LastModified string `json:"lastModified"`
+	LastUpdated  string `json:"lastUpdated"`
+}
+
+// readTimestamps exposes the creation and last update timestamps of the repository after the read, when opted in with
+// `read_timestamps`. The repository configuration has none, they're those of the root folder of the repository. The
+// storage info is cached for the client until the repository is written. A failed lookup only clears them.
+func readTimestamps(read schema.ReadContextFunc) schema.ReadContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		diags := read(ctx, d, m)
+		if diags.HasError() || d.Id() == "" {
+			return diags
+		}
+
+		var storage repositoryStorage
+		if d.Get("read_timestamps").(bool) {
+			restyClient, err := repository.RestyClientOf(m, "read_timestamps")
+			if err != nil {
+				return append(diags, diag.FromErr(err)...)
+			}
+			storage, err = repository.CachedLookup(restyClient, repository.RepositoryStorageCache, d.Id(), func() (repositoryStorage, error) {
+				var storage repositoryStorage
+				_, err := restyClient.R().
+					SetPathParam("repoKey", d.Id()).
+					SetResult(&storage).
+					Get(RepositoryStorageEndpoint)
+				return storage, err
+			})
+			if err != nil {
+				tflog.Debug(ctx, fmt.Sprintf("failed to read timestamps of repository %s: %s", d.Id(), err))
+			}
+		}
+
+		lastUpdated := storage.LastUpdated
+		if lastUpdated == "" {
+			lastUpdated = storage.LastModified
+		}
+		if err := d.Set("created", storage.Created); err != nil {
+			return append(diags, diag.FromErr(err)...)
+		}
+		if err := d.Set("last_updated", lastUpdated); err != nil {
+			return append(diags, diag.FromErr(err)...)
+		}
+		return diags
+	}
+}
+
+// packEffectiveExtraAttributes stores the values returned by Artifactory for the `extra_attributes` fields. The params
+// don't model them, they're taken from the fields of the read response.
+func packEffectiveExtraAttributes(repo interface{}, d *schema.ResourceData) error {
+	var served map[string]interface{}
+	if withServed, ok := repo.(interface{ servedFields() map[string]interface{} }); ok {
+		served = withServed.servedFields()
+	}
+	effective := map[string]interface{}{}
+	for name := range d.Get("extra_attributes").(map[string]interface{}) {
+		value, found := served[name]
+		if !found {
+			continue
+		}
+		if text, isString := value.(string); isString {
+			effective[name] = text
+			continue
+		}
+		encoded, _ := json.Marshal(value)
+		effective[name] = string(encoded)
+	}
+
+	if errors := util.MkLens(d)("effective_extra_attributes", effective); len(errors) > 0 {
+		return fmt.Errorf("failed saving effective extra attributes to state %q", errors)
+	}
+	return nil
+}
+
+// patternsLengthDiff fails the plan when the include and exclude patterns together are longer than the provider's
+// `max_patterns_length`. The REST API documentation doesn't state a limit, so there's none unless the limit of the
+// instance is configured. Like ValidateMaxLength, it counts runes.
+func patternsLengthDiff(_ context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	limit := repository.ProviderSettingsOf(m).MaxPatternsLength
+	length := utf8.RuneCountInString(configuredPatterns(diff, "includes_pattern")) + utf8.RuneCountInString(configuredPatterns(diff, "excludes_pattern"))
+	if limit > 0 && length > limit {
+		return fmt.Errorf("includes_pattern and excludes_pattern are %d characters long combined, the provider allows at most %d (max_patterns_length)", length, limit)
+	}
+
+	return nil
+}
+
+// matchAllPatterns are the exclude patterns matching every path
+var matchAllPatterns = []string{"**", "**/*", "**/**", "*/**"}
+
+// allExcludingPatterns returns the exclude patterns that together exclude every path included, or nil when some may be
+// served. It isn't a pattern solver, only the obvious cases are caught: an exclude pattern matching every path, or
+// every include pattern being excluded verbatim or by an exclude pattern covering its directory, e.g. `com/**` for
+// `com/jfrog/**`.
+func allExcludingPatterns(includesPattern, excludesPattern string) []string {
+	excludes := splitPatterns(excludesPattern)
+	for _, exclude := range excludes {
+		if slices.Contains(matchAllPatterns, exclude) {
+			return []string{exclude}
+		}
+	}
+
+	includes := splitPatterns(includesPattern)
+	if len(includes) == 0 || (len(includes) == 1 && slices.Contains(matchAllPatterns, includes[0])) {
+		return nil
+	}
+	var covering []string
+	for _, include := range includes {
+		index := slices.IndexFunc(excludes, func(exclude string) bool {
+			return exclude == include || (strings.HasSuffix(exclude, "/**") && strings.HasPrefix(include, strings.TrimSuffix(exclude, "**")))
+		})
+		if index < 0 {
+			return nil
+		}
+		if !slices.Contains(covering, excludes[index]) {
+			covering = append(covering, excludes[index])
+		}
+	}
+	return covering
+}
+
+// warnOnAllExcludingPatterns warns when `excludes_pattern` clearly excludes everything `includes_pattern` includes, the
+// virtual repository would serve no artifact. Only checked when the patterns change.
+func warnOnAllExcludingPatterns(apply func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		diags := apply(ctx, d, m)
+		if diags.HasError() || !d.HasChanges("includes_pattern", "includes_patterns", "excludes_pattern", "excludes_patterns") {
+			return diags
+		}
+
+		excludes := allExcludingPatterns(configuredPatterns(d, "includes_pattern"), configuredPatterns(d, "excludes_pattern"))
+		if len(excludes) == 0 {
+			return diags
+		}
+		return append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "Exclude patterns exclude every included artifact",
+			Detail: fmt.Sprintf("The exclude patterns %s of the virtual repository %s exclude every artifact matched by includes_pattern, "+
+				"the repository serves no artifact. Narrow excludes_pattern, or remove it to exclude nothing.", strings.Join(excludes, ", "), d.Id()),
+			AttributePath: cty.GetAttrPath("excludes_pattern"),
+		})
+	}
+}
+
+// maxMembersDiff fails the plan when `repositories` has more members than the provider's `max_member_repositories`
+func maxMembersDiff(_ context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	limit := repository.ProviderSettingsOf(m).MaxMemberRepositories
+	if count := len(configuredMembers(diff)); limit > 0 && count > limit {
+		return fmt.Errorf("repositories has %d members, the provider allows at most %d (max_member_repositories)", count, limit)
+	}
+	return nil
+}
+
+// retrievalCachePeriodDefaultDiff plans retrieval_cache_period_seconds when it's unset in the configuration. Package
+// types that cache metadata inherit default_retrieval_cache_period_seconds of the provider, the others keep
+// DefaultRetrievalCachePeriodSecs as the value isn't sent. Planning the default, rather than resolving it on apply,
+// shows it in the plan and updates the repositories in place when the provider default changes.
+func retrievalCachePeriodDefaultDiff(cachesMetadata bool) schema.CustomizeDiffFunc {
+	return func(_ context.Context, diff *schema.ResourceDiff, m interface{}) error {
+		config := diff.GetRawConfig()
+		if config.IsNull() || !config.IsKnown() || !config.Type().IsObjectType() || !config.Type().HasAttribute("retrieval_cache_period_seconds") {
+			return nil
+		}
+		if !config.GetAttr("retrieval_cache_period_seconds").IsNull() {
+			return nil
+		}
+
+		period := DefaultRetrievalCachePeriodSecs
+		if cachesMetadata {
+			if inherited := repository.ProviderSettingsOf(m).DefaultRetrievalCachePeriodSecs; inherited != nil {
+				period = *inherited
+			}
+		}
+		if diff.NewValueKnown("retrieval_cache_period_seconds") && diff.Get("retrieval_cache_period_seconds") == period {
+			return nil
+		}
+		return diff.SetNew("retrieval_cache_period_seconds", period)
+	}
+}
+
+// requestsCanRetrieveRemoteArtifactsDefaultDiff plans artifactory_requests_can_retrieve_remote_artifacts when it's
+// unset in the configuration of a new repository: the default of the package type when it has one, otherwise
+// default_requests_can_retrieve_remote_artifacts of the provider, false unless set. Existing repositories keep the
+// value of the state, so a new default doesn't flip them in place.
+func requestsCanRetrieveRemoteArtifactsDefaultDiff(typeDefault *bool) schema.CustomizeDiffFunc {
+	return func(_ context.Context, diff *schema.ResourceDiff, m interface{}) error {
+		config := diff.GetRawConfig()
+		if diff.Id() != "" || config.IsNull() || !config.IsKnown() || !config.Type().IsObjectType() || !config.Type().HasAttribute("artifactory_requests_can_retrieve_remote_artifacts") {
+			return nil
+		}
+		if !config.GetAttr("artifactory_requests_can_retrieve_remote_artifacts").IsNull() {
+			return nil
+		}
+
+		value := false
+		if typeDefault != nil {
+			value = *typeDefault
+		} else if inherited := repository.ProviderSettingsOf(m).DefaultRequestsCanRetrieveRemoteArtifacts; inherited != nil {
+			value = *inherited
+		}
+		if diff.NewValueKnown("artifactory_requests_can_retrieve_remote_artifacts") && diff.Get("artifactory_requests_can_retrieve_remote_artifacts") == value {
+			return nil
+		}
+		return diff.SetNew("artifactory_requests_can_retrieve_remote_artifacts", value)
+	}
+}
+
+// retrievalCachePeriodUnsupportedDiff fails the plan when `retrieval_cache_period_seconds` is set to another value than
+// its default for a package type that doesn't cache metadata. The attribute is in the schema of every virtual
+// repository, but only sent for the package types that use it, so the value would be silently ignored. The default is
+// accepted, it's what the repository behaves like.
+func retrievalCachePeriodUnsupportedDiff(cachesMetadata bool, packageType string) schema.CustomizeDiffFunc {
+	return func(_ context.Context, diff *schema.ResourceDiff, _ interface{}) error {
+		if cachesMetadata {
+			return nil
+		}
+		config := diff.GetRawConfig()
+		if config.IsNull() || !config.IsKnown() || !config.Type().IsObjectType() || !config.Type().HasAttribute("retrieval_cache_period_seconds") {
+			return nil
+		}
+		if configured := config.GetAttr("retrieval_cache_period_seconds"); configured.IsNull() || !configured.IsKnown() {
+			return nil
+		}
+		if period := diff.Get("retrieval_cache_period_seconds").(int); period != DefaultRetrievalCachePeriodSecs {
+			return fmt.Errorf("retrieval_cache_period_seconds is set to %d, but it isn't supported for %s virtual repositories, which don't cache metadata. "+
+				"Remove it from the configuration", period, packageType)
+		}
+		return nil
+	}
+}
+
+// DefaultUncachedMembersWarningThreshold is the number of members above which a virtual repository caching no metadata
+// is warned about, unless the provider sets uncached_members_warning_threshold
+const DefaultUncachedMembersWarningThreshold = 5
+
+// uncachedMembersWarning explains the upstream load of a virtual repository with `retrieval_cache_period_seconds = 0`
+// and more members than the threshold of the provider, it's empty otherwise. Package types that don't cache metadata
+// aren't concerned, their period isn't sent.
+func uncachedMembersWarning(cachesMetadata bool, key string, period int, members []string, m interface{}) string {
+	threshold := DefaultUncachedMembersWarningThreshold
+	if configured := repository.ProviderSettingsOf(m).UncachedMembersWarningThreshold; configured != nil {
+		threshold = *configured
+	}
+	if !cachesMetadata || period != 0 || threshold == 0 || len(members) <= threshold {
+		return ""
+	}
+	return fmt.Sprintf("retrieval_cache_period_seconds is 0, so the virtual repository %s caches no metadata and resolves every metadata request against its %d members, "+
+		"more than %d (uncached_members_warning_threshold). This puts a heavy load on the upstream repositories, set a nonzero period, e.g. the default %d.",
+		key, len(members), threshold, DefaultRetrievalCachePeriodSecs)
+}
+
+// uncachedMembersDiff logs the warning of uncachedMembersWarning at plan time, CustomizeDiff can't return warnings.
+// warnOnUncachedMembers reports it with the apply.
+func uncachedMembersDiff(cachesMetadata bool) schema.CustomizeDiffFunc {
+	return func(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+		if !diff.NewValueKnown("retrieval_cache_period_seconds") || !membersKnown(diff) {
+			return nil
+		}
+		key, _ := diff.Get("key").(string)
+		if message := uncachedMembersWarning(cachesMetadata, key, diff.Get("retrieval_cache_period_seconds").(int), configuredMembers(diff), m); message != "" {
+			tflog.Warn(ctx, message, map[string]interface{}{"repo_key": key})
+		}
+		return nil
+	}
+}
+
+// warnOnUncachedMembers warns about a virtual repository caching no metadata for many members, see
+// uncachedMembersWarning. It's only a warning, a period of 0 is legitimate e.g. for few or fast members.
+func warnOnUncachedMembers(cachesMetadata bool, apply func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		diags := apply(ctx, d, m)
+		if diags.HasError() {
+			return diags
+		}
+		message := uncachedMembersWarning(cachesMetadata, d.Id(), d.Get("retrieval_cache_period_seconds").(int), configuredMembers(d), m)
+		if message == "" {
+			return diags
+		}
+		return append(diags, diag.Diagnostic{
+			Severity:      diag.Warning,
+			Summary:       "Metadata caching disabled for many members",
+			Detail:        message,
+			AttributePath: cty.GetAttrPath("retrieval_cache_period_seconds"),
+		})
+	}
+}
+
+// retrievalCachePeriodMaxDiff fails the plan when retrieval_cache_period_seconds exceeds the maximum of the provider,
+// DefaultMaxRetrievalCachePeriodSecs unless max_retrieval_cache_period_seconds is set.
+func retrievalCachePeriodMaxDiff(_ context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	limit := DefaultMaxRetrievalCachePeriodSecs
+	if configured := repository.ProviderSettingsOf(m).MaxRetrievalCachePeriodSecs; configured > 0 {
+		limit = configured
+	}
+
+	if period, _ := diff.Get("retrieval_cache_period_seconds").(int); period > limit {
+		return fmt.Errorf("retrieval_cache_period_seconds is %d seconds, the provider allows at most %d (max_retrieval_cache_period_seconds)", period, limit)
+	}
+	return nil
+}
+
+// selfReferenceDiff fails the plan when a virtual repository lists its own key in `repositories`, which Artifactory
+// rejects with a 400 at apply.
+func selfReferenceDiff(_ context.Context, diff *schema.ResourceDiff, _ interface{}) error {
+	key := diff.Get("key").(string)
+	if key == "" {
+		return nil
+	}
+
+	for _, member := range configuredMembers(diff) {
+		if member == key {
+			return fmt.Errorf("a virtual repository cannot include itself: %s is listed in repositories", key)
+		}
+	}
+
+	return nil
+}
+
+// duplicateMembersDiff fails the plan when a member is listed more than once in `repositories`. Artifactory keeps
+// a single entry, so the configuration would show as drift on every plan.
+func duplicateMembersDiff(_ context.Context, diff *schema.ResourceDiff, _ interface{}) error {
+	listed := map[string]int{}
+	for index, member := range diff.Get("repositories").([]interface{}) {
+		key, ok := member.(string)
+		if !ok || key == "" {
+			continue
+		}
+		if first, found := listed[key]; found {
+			return fmt.Errorf("repositories lists %s more than once, at index %d and %d", key, first, index)
+		}
+		listed[key] = index
+	}
+	return nil
+}
+
+// validateOnlyChangeDiff creates the repository when `validate_only` is unset, by replacing the validate-only state, and
+// fails the plan when it is set on an existing repository, as the validation would delete it. The attribute isn't
+// ForceNew, so states written before it existed don't plan a replacement.
+func validateOnlyChangeDiff(_ context.Context, diff *schema.ResourceDiff, _ interface{}) error {
+	if diff.Id() == "" || !diff.HasChange("validate_only") {
+		return nil
+	}
+
+	if wasValidateOnly, _ := diff.GetChange("validate_only"); wasValidateOnly.(bool) {
+		return diff.ForceNew("validate_only")
+	}
+	return fmt.Errorf("validate_only can't be set on the existing repository %s, the validation would delete it. "+
+		"Validate the configuration with another resource, or destroy the repository first", diff.Id())
+}
+
+// pruneOfflineMembers drops offline members from `repositories` before the update is sent, when the user opted in
+// with `prune_offline_members_on_apply`. Members that can't be looked up are kept so Artifactory reports the problem.
+// The state keeps the configured members, keepDroppedMembers has the reads match, and a warning lists the pruned ones.
+func pruneOfflineMembers(update schema.UpdateContextFunc) schema.UpdateContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		if !d.Get("prune_offline_members_on_apply").(bool) {
+			return update(ctx, d, m)
+		}
+
+		configured := configuredMembers(d)
+		var online, offline []string
+		for _, member := range configured {
+			if isOfflineMember(ctx, m, member) {
+				offline = append(offline, member)
+			} else {
+				online = append(online, member)
+			}
+		}
+
+		if len(offline) == 0 {
+			return update(ctx, d, m)
+		}
+
+		diags := diag.Diagnostics{{
+			Severity:      diag.Warning,
+			Summary:       "Offline member repositories pruned",
+			Detail:        fmt.Sprintf("The following member repositories are offline and were not included in the virtual repository %s: %s", d.Id(), strings.Join(offline, ", ")),
+			AttributePath: cty.GetAttrPath("repositories"),
+		}}
+		return append(diags, updateWithMembers(ctx, d, m, update, online, configured)...)
+	}
+}
+
+// isOfflineMember reports whether the member is a remote repository that is offline or blacked out. Members that can't
+// be looked up aren't.
+func isOfflineMember(ctx context.Context, m interface{}, member string) bool {
+	info, _, err := repository.GetMemberInfo(m, member)
+	if err != nil {
+		tflog.Debug(ctx, fmt.Sprintf("failed to check status of member repository %s: %v", member, err))
+		return false
+	}
+	return !info.IsOnline()
+}
+
+// isMissingMember reports whether the member doesn't exist
+func isMissingMember(m interface{}, member string) bool {
+	_, resp, err := repository.GetMemberInfo(m, member)
+	return err != nil && repository.IsNotFound(resp)
+}
+
+// updateWithMembers sends the update with members instead of the configured ones, then sets the configured members back
+// so the state keeps matching the configuration.
+func updateWithMembers(ctx context.Context, d *schema.ResourceData, m interface{}, update schema.UpdateContextFunc, members, configured []string) diag.Diagnostics {
+	if err := setMembers(d, members); err != nil {
+		return diag.FromErr(err)
+	}
+	diags := update(ctx, d, m)
+	if err := setMembers(d, configured); err != nil {
+		return append(diags, diag.FromErr(err)...)
+	}
+	return diags
+}
+
+// setMembers replaces the members sent by the update in the form used. `repositories` is always set, the set form falls
+// back to it once emptied.
+func setMembers(d *schema.ResourceData, members []string) error {
+	if d.Get("member_repositories").(*schema.Set).Len() > 0 {
+		if err := d.Set("member_repositories", members); err != nil {
+			return err
+		}
+	}
+	return d.Set("repositories", members)
+}
+
+// bestEffortMembers retries a rejected update without the members of `repositories` that don't exist, when the user
+// opted in with `best_effort_members`, e.g. after an upstream of a large aggregation was removed. Without such members
+// the error of the update is returned as is. The state keeps the configured members like with pruneOfflineMembers.
+func bestEffortMembers(update schema.UpdateContextFunc) schema.UpdateContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		if !d.Get("best_effort_members").(bool) {
+			return update(ctx, d, m)
+		}
+
+		diags := update(ctx, d, m)
+		if !diags.HasError() {
+			return diags
+		}
+
+		configured := configuredMembers(d)
+		var kept, rejected []string
+		for _, member := range configured {
+			if isMissingMember(m, member) {
+				rejected = append(rejected, member)
+			} else {
+				kept = append(kept, member)
+			}
+		}
+
+		if len(rejected) == 0 {
+			return diags
+		}
+
+		warnings := diag.Diagnostics{{
+			Severity:      diag.Warning,
+			Summary:       "Member repositories dropped",
+			Detail:        fmt.Sprintf("The update of the virtual repository %s was rejected, it was retried without the following member repositories, which don't exist: %s", d.Id(), strings.Join(rejected, ", ")),
+			AttributePath: cty.GetAttrPath("repositories"),
+		}}
+		return append(warnings, updateWithMembers(ctx, d, m, update, kept, configured)...)
+	}
+}
+
+// keepDroppedMembers keeps the members of the state on read when Artifactory only lacks the members
+// pruneOfflineMembers or bestEffortMembers dropped and that still would be, so the plan doesn't show them as changes.
+// Any other difference is read as is.
+func keepDroppedMembers(read schema.ReadContextFunc) schema.ReadContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		prune, bestEffort := d.Get("prune_offline_members_on_apply").(bool), d.Get("best_effort_members").(bool)
+		prior := configuredMembers(d)
+		diags := read(ctx, d, m)
+		if diags.HasError() || d.Id() == "" || (!prune && !bestEffort) {
+			return diags
+		}
+
+		current := configuredMembers(d)
+		if len(current) >= len(prior) || !isSubsequence(current, prior) {
+			return diags
+		}
+		for _, member := range prior {
+			if slices.Contains(current, member) {
+				continue
+			}
+			if !(prune && isOfflineMember(ctx, m, member)) && !(bestEffort && isMissingMember(m, member)) {
+				return diags
+			}
+		}
+		return append(diags, diag.FromErr(setMembers(d, prior))...)
+	}
+}
+
+// isSubsequence reports whether the elements of sub appear in members in the same order
+func isSubsequence(sub, members []string) bool {
+	i := 0
+	for _, member := range members {
+		if i < len(sub) && sub[i] == member {
+			i++
+		}
+	}
+	return i == len(sub)
+}
+
+// validateOnly has create validate the configuration when `validate_only` is set, by creating the repository and
+// deleting it again. Artifactory has no validation-only endpoint for the repository configuration, a create is the only
+// way to have it checked. Updates validate the new configuration the same way, as there's no repository to update.
+// A failed create leaves nothing behind, its key may belong to an existing repository, which must not be deleted.
+func validateOnly(create schema.CreateContextFunc, update schema.UpdateContextFunc) (schema.CreateContextFunc, schema.UpdateContextFunc) {
+	validate := func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		// the update of a validate-only repository creates it anew, on failure the previous state is kept
+		previousID := d.Id()
+		d.SetId("")
+		diags := create(ctx, d, m)
+		if d.Id() == "" {
+			d.SetId(previousID)
+			return diags
+		}
+
+		key := d.Id()
+		resp, err := repository.RepositoryClientOf(m).Delete(key)
+		if err != nil && !repository.IsNotFound(resp) {
+			return append(diags, diag.Errorf("validate_only is set, but the repository %s created to validate the configuration couldn't be deleted, delete it manually: %s", key, err)...)
+		}
+		if diags.HasError() {
+			d.SetId(previousID)
+			return diags
+		}
+		return append(diags, diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "Repository configuration validated",
+			Detail: fmt.Sprintf("validate_only is set, the virtual repository %s was created to have Artifactory validate the configuration and deleted again. "+
+				"Unset validate_only to create it.", key),
+			AttributePath: cty.GetAttrPath("validate_only"),
+		}}...)
+	}
+
+	validatingCreate := func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		if !d.Get("validate_only").(bool) {
+			return create(ctx, d, m)
+		}
+		return validate(ctx, d, m)
+	}
+	validatingUpdate := func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		if !d.Get("validate_only").(bool) {
+			return update(ctx, d, m)
+		}
+		return validate(ctx, d, m)
+	}
+	return validatingCreate, validatingUpdate
+}
+
+// skipIfValidateOnly skips reads and deletes of repositories with `validate_only`, they don't exist in Artifactory.
+// A repository since created out-of-band with the same key is neither adopted nor deleted.
+func skipIfValidateOnly(operation func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		if d.Get("validate_only").(bool) {
+			return nil
+		}
+		return operation(ctx, d, m)
+	}
+}
+
+// preventProtectedDeletion fails the delete of repositories with `deletion_protection`, replacements included as
+// they delete the repository first. The flag is read from the state, so it must be unset in a separate apply.
+func preventProtectedDeletion(delete schema.DeleteContextFunc) schema.DeleteContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		if d.Get("deletion_protection").(bool) {
+			return diag.Diagnostics{{
+				Severity: diag.Error,
+				Summary:  "Repository is protected from deletion",
+				Detail: fmt.Sprintf("The virtual repository %s has deletion_protection set. "+
+					"Set deletion_protection = false and apply before destroying or replacing it.", d.Id()),
+				AttributePath: cty.GetAttrPath("deletion_protection"),
+			}}
+		}
+		return delete(ctx, d, m)
+	}
+}
+
+// protectedReplacementDiff fails the plan replacing a repository with `deletion_protection` in the state, rather than
+// the apply once the replacement gets to preventProtectedDeletion. The ResourceDiff doesn't tell which keys the other
+// diffs forced, so it must run last and check the ForceNew attributes and the project key move itself.
+func protectedReplacementDiff(resourceSchema map[string]*schema.Schema) schema.CustomizeDiffFunc {
+	return func(_ context.Context, diff *schema.ResourceDiff, _ interface{}) error {
+		if protected, _ := diff.GetChange("deletion_protection"); diff.Id() == "" || !protected.(bool) {
+			return nil
+		}
+
+		var replacing []string
+		for key, attribute := range resourceSchema {
+			if attribute.ForceNew && diff.HasChange(key) {
+				replacing = append(replacing, key)
+			}
+		}
+		if repository.ReplacesOnProjectKeyChange(diff) {
+			replacing = append(replacing, "project_key")
+		}
+		if len(replacing) == 0 {
+			return nil
+		}
+		sort.Strings(replacing)
+		return fmt.Errorf("the virtual repository %s has deletion_protection set and the change of %s replaces it. "+
+			"Set deletion_protection = false and apply before replacing it", diff.Id(), strings.Join(replacing, ", "))
+	}
+}
+
+// reportDependentReferences lists the virtual repositories still referencing the repository when the delete fails and
+// the user opted in with `cleanup_dependent_references`. Artifactory's own error doesn't name them.
+func reportDependentReferences(delete schema.DeleteContextFunc) schema.DeleteContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		diags := delete(ctx, d, m)
+		if !diags.HasError() || !d.Get("cleanup_dependent_references").(bool) {
+			return diags
+		}
+		restyClient, err := repository.RestyClientOf(m, "cleanup_dependent_references")
+		if err != nil {
+			return append(diags, diag.FromErr(err)...)
+		}
+
+		referencing, err := findReferencingVirtualRepos(d.Id(), restyClient)
+		if err != nil {
+			return append(diags, diag.Errorf("failed to look up virtual repositories referencing %s: %s", d.Id(), err)...)
+		}
+		if len(referencing) == 0 {
+			return diags
+		}
+
+		return append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Repository is referenced by other virtual repositories",
+			Detail: fmt.Sprintf("The repository %s is still listed in `repositories` of the virtual repositories %s. "+
+				"Remove it from them before deleting it.", d.Id(), strings.Join(referencing, ", ")),
+		})
+	}
+}
+
+// findReferencingVirtualRepos returns the keys of the virtual repositories listing key as a member
+func findReferencingVirtualRepos(key string, restyClient *resty.Client) ([]string, error) {
+	summaries, err := repository.ListVirtualRepositories(restyClient, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	var referencing []string
+	for _, summary := range summaries {
+		if summary.Key != key && slices.Contains(summary.Repositories, key) {
+			referencing = append(referencing, summary.Key)
+		}
+	}
+	return referencing, nil
+}
+
+// membersPackageTypeDiff fails the plan when existing members of `repositories` have a package type the virtual
+// repository can't aggregate, naming them. Members that don't exist yet, e.g. created in the same apply, are skipped.
+func membersPackageTypeDiff(packageType string) schema.CustomizeDiffFunc {
+	return func(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+		if !membersKnown(diff) {
+			return nil
+		}
+
+		members := configuredMembers(diff)
+		for _, diagnostic := range repository.ValidateMembersPackageType(ctx, m, members, packageType) {
+			if diagnostic.Severity == diag.Error {
+				return errors.New(diagnostic.Detail)
+			}
+		}
+		return nil
+	}
+}
+
+// defaultDeploymentRepoDiff fails the plan when `default_deployment_repo` is changed to a repository that isn't a
+// local or federated repository, Artifactory can only deploy to repositories storing artifacts. The lookup is cached like the member lookups on
+// read, so plans sharing the repository only read it once. Repositories that can't be looked up, e.g. created in the
+// same apply, are left for Artifactory to check.
+func defaultDeploymentRepoDiff(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	if !diff.HasChange("default_deployment_repo") || !diff.NewValueKnown("default_deployment_repo") {
+		return nil
+	}
+	target := diff.Get("default_deployment_repo").(string)
+	if target == "" {
+		return nil
+	}
+
+	info, _, err := repository.GetMemberInfo(m, target)
+	if err != nil {
+		tflog.Debug(ctx, fmt.Sprintf("failed to check rclass of default deployment repository %s: %v", target, err))
+		return nil
+	}
+	if info.Rclass != "local" && info.Rclass != "federated" {
+		return fmt.Errorf("default_deployment_repo %s is a %s repository, a virtual repository can only deploy to a local or federated repository", target, info.Rclass)
+	}
+	return nil
+}
+
+// keyChangeDiff warns when `key` changes, which replaces the repository as Artifactory can't rename one. CustomizeDiff
+// can't return warnings, the warning is logged and the plan only marks `key` as forcing the replacement.
+func keyChangeDiff(ctx context.Context, diff *schema.ResourceDiff, _ interface{}) error {
+	if diff.Id() == "" || !diff.HasChange("key") || !diff.NewValueKnown("key") {
+		return nil
+	}
+
+	before, after := diff.GetChange("key")
+	message := fmt.Sprintf("key changes from %s to %s. Artifactory can't rename repositories, the virtual repository %s is deleted and %s is created, "+
+		"clients and virtual repositories resolving through %s fail until they use the new key.", before, after, before, after, before)
+	if target := diff.Get("default_deployment_repo").(string); target != "" {
+		message += fmt.Sprintf(" Deployments through %s to the default deployment repository %s are rejected as well, the artifacts stored in %s are kept.", before, target, target)
+	}
+	tflog.Warn(ctx, message, map[string]interface{}{"repo_key": before})
+	return nil
+}
+
+// repoLayoutRefChangeDiff fails the plan when `repo_layout_ref` changes for one of the LayoutImmutablePackageTypes,
+// instead of the update failing on apply. The repository isn't replaced for it, the user decides to, e.g. with
+// `terraform apply -replace`, which plans a create the check doesn't apply to.
+func repoLayoutRefChangeDiff(packageType string) schema.CustomizeDiffFunc {
+	return func(_ context.Context, diff *schema.ResourceDiff, _ interface{}) error {
+		if diff.Id() == "" || !slices.Contains(repository.LayoutImmutablePackageTypes, packageType) || !diff.HasChange("repo_layout_ref") || !diff.NewValueKnown("repo_layout_ref") {
+			return nil
+		}
+
+		// states without the layout, e.g. written before it was read back, plan the configured one, Artifactory checks it
+		before, after := diff.GetChange("repo_layout_ref")
+		if before.(string) == "" {
+			return nil
+		}
+		return fmt.Errorf("repo_layout_ref of the %s virtual repository %s can't change from %s to %s, the layout of %s repositories is set on create. "+
+			"Replace the repository to change it, e.g. with `terraform apply -replace`, settings not in the configuration are then reset", packageType, diff.Id(), before, after, packageType)
+	}
+}
+
+// listExistingRepositories lists the repositories by lowercased key. The listing isn't cached, the repositories deleted
+// earlier in the apply, e.g. by a replacement, must not be reported.
+func listExistingRepositories(restyClient *resty.Client) (map[string]repository.RepoInfo, error) {
+	var summaries []struct {
+		Key  string `json:"key"`
+		Type string `json:"type"`
+	}
+	_, err := restyClient.R().
+		SetResult(&summaries).
+		Get(strings.TrimSuffix(repository.RepositoriesEndpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	repos := map[string]repository.RepoInfo{}
+	for _, summary := range summaries {
+		repos[strings.ToLower(summary.Key)] = repository.RepoInfo{Key: summary.Key, Rclass: strings.ToLower(summary.Type)}
+	}
+	return repos, nil
+}
+
+// keyAvailableDiff fails the plan when the key of a new repository, or the new key of a renamed one, is already used by
+// a repository of any rclass, which Artifactory rejects at apply with a 409. Keys are unique across rclasses and
+// casing. The check is skipped with `adopt_existing`, and when the repositories can't be listed.
+func keyAvailableDiff(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	if !diff.NewValueKnown("key") || (diff.Id() != "" && !diff.HasChange("key")) || diff.Get("adopt_existing").(bool) {
+		return nil
+	}
+	key := diff.Get("key").(string)
+	if key == "" {
+		return nil
+	}
+	restyClient, err := repository.RestyClientOf(m, "the check that key is available")
+	if err != nil {
+		return err
+	}
+
+	repos, err := listExistingRepositories(restyClient)
+	if err != nil {
+		tflog.Debug(ctx, fmt.Sprintf("failed to list repositories to check that key %s is available: %v", key, err))
+		return nil
+	}
+	if existing, found := repos[strings.ToLower(key)]; found {
+		return fmt.Errorf("key %s is already used by the %s repository %s, repository keys are unique across local, remote, virtual and federated repositories", key, existing.Rclass, existing.Key)
+	}
+	return nil
+}
+
+// getProjectRepositories returns the keys of the repositories assigned to a project, cached per client, so planning
+// many virtual repositories of a project lists them once.
+func getProjectRepositories(projectKey string, restyClient *resty.Client) ([]string, error) {
+	return repository.CachedLookup(restyClient, repository.ProjectRepositoriesCache, projectKey, func() ([]string, error) {
+		var summaries []struct {
+			Key string `json:"key"`
+		}
+		_, err := restyClient.R().
+			SetQueryParam("project", projectKey).
+			SetResult(&summaries).
+			Get(strings.TrimSuffix(repository.RepositoriesEndpoint, "/"))
+		if err != nil {
+			return nil, err
+		}
+
+		keys := []string{}
+		for _, summary := range summaries {
+			keys = append(keys, summary.Key)
+		}
+		return keys, nil
+	})
+}
+
+// projectMembersDiff warns when `repositories` lists repositories of the project of `project_key`, which Artifactory
+// may aggregate for the project on its own, so listing them shows as drift. CustomizeDiff can't return warnings, the
+// warning is logged. It's only checked when project_key or repositories change, and skipped when the repositories of
+// the project can't be listed.
+func projectMembersDiff(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	if !diff.NewValueKnown("project_key") || !membersKnown(diff) || !diff.HasChanges("project_key", "repositories", "member_repositories") {
+		return nil
+	}
+	projectKey := diff.Get("project_key").(string)
+	if projectKey == "" {
+		return nil
+	}
+	restyClient, err := repository.RestyClientOf(m, "the check of members assigned to project_key")
+	if err != nil {
+		return err
+	}
+
+	keys, err := getProjectRepositories(projectKey, restyClient)
+	if err != nil {
+		tflog.Debug(ctx, fmt.Sprintf("failed to list repositories of project %s: %v", projectKey, err))
+		return nil
+	}
+
+	var listed []string
+	for _, member := range configuredMembers(diff) {
+		if slices.Contains(keys, member) {
+			listed = append(listed, member)
+		}
+	}
+	if len(listed) == 0 {
+		return nil
+	}
+
+	tflog.Warn(ctx, fmt.Sprintf("repositories lists %s, assigned to the project %s of the virtual repository. Artifactory may aggregate the repositories of the project on its own, "+
+		"listing them shows as drift, consider letting the project manage them.", strings.Join(listed, ", "), projectKey), map[string]interface{}{"project_key": projectKey})
+	return nil
+}
+
+// defaultDeploymentRepoMemberDiff fails the plan when `repositories` drops the member `default_deployment_repo` still
+// points at. Artifactory rejects the update as the default deployment repository must be a member, so
+// default_deployment_repo has to be changed or reset in the same change. auto_default_deployment_repo resolves a new
+// default on apply instead.
+func defaultDeploymentRepoMemberDiff(_ context.Context, diff *schema.ResourceDiff, _ interface{}) error {
+	if !diff.HasChanges("repositories", "member_repositories") || !membersKnown(diff) || !diff.NewValueKnown("default_deployment_repo") || diff.Get("auto_default_deployment_repo").(bool) {
+		return nil
+	}
+	target := diff.Get("default_deployment_repo").(string)
+	if target == "" {
+		return nil
+	}
+
+	// the state has the members in `repositories` whichever form is used
+	before, _ := diff.GetChange("repositories")
+	if !slices.Contains(util.CastToStringArr(before.([]interface{})), target) || slices.Contains(configuredMembers(diff), target) {
+		return nil
+	}
+	return fmt.Errorf("default_deployment_repo %s is removed from repositories, but the virtual repository %s still deploys to it. "+
+		"Change or reset default_deployment_repo in the same change, or keep %s a member", target, diff.Get("key"), target)
+}
+
+// warnOnIncompatibleMembers warns on read about members whose package type no longer matches the virtual
+// repository's, e.g. after a member was recreated out-of-band with another package type. Artifactory keeps such members
+// listed but can't resolve artifacts through them. It's the check of membersPackageTypeDiff, as a warning since the
+// configuration isn't at fault.
+func warnOnIncompatibleMembers(read schema.ReadContextFunc) schema.ReadContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		diags := read(ctx, d, m)
+		if diags.HasError() || d.Id() == "" {
+			return diags
+		}
+
+		members := (&util.ResourceData{d}).GetList("repositories")
+		for _, diagnostic := range repository.ValidateMembersPackageType(ctx, m, members, d.Get("package_type").(string)) {
+			diagnostic.Severity = diag.Warning
+			diags = append(diags, diagnostic)
+		}
+		return diags
+	}
+}
+
+// clearMissingDefaultDeploymentRepo clears `default_deployment_repo` in the state on read when the repository it
+// references was deleted out-of-band. Artifactory keeps the reference, but rejects updates sending it, so the next plan
+// shows the difference and the apply either resets it or sets it again once the repository exists. Lookup errors other
+// than a missing repository keep the reference.
+func clearMissingDefaultDeploymentRepo(read schema.ReadContextFunc) schema.ReadContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		diags := read(ctx, d, m)
+		target := d.Get("default_deployment_repo").(string)
+		if diags.HasError() || d.Id() == "" || target == "" {
+			return diags
+		}
+
+		_, resp, err := repository.GetMemberInfo(m, target)
+		if err == nil || !repository.IsNotFound(resp) {
+			if err != nil {
+				tflog.Debug(ctx, fmt.Sprintf("failed to look up default deployment repository %s: %v", target, err))
+			}
+			return diags
+		}
+
+		if err := d.Set("default_deployment_repo", ""); err != nil {
+			return append(diags, diag.FromErr(err)...)
+		}
+		return append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "Default deployment repository not found",
+			Detail: fmt.Sprintf("The default deployment repository %s of the virtual repository %s doesn't exist, e.g. it was deleted out-of-band. "+
+				"It is cleared in the state, the next apply resets default_deployment_repo, or sets it again when it's still configured.", target, d.Id()),
+			AttributePath: cty.GetAttrPath("default_deployment_repo"),
+		})
+	}
+}
+
+// DefaultWaitForReadyTimeout bounds the `wait_for_ready` poll unless the create timeout is set
+const DefaultWaitForReadyTimeout = 5 * time.Minute
+
+// waitForReady polls the repository configuration before the read following the create when `wait_for_ready` is set.
+// On clustered deployments the node serving the read may not know about the repository yet, and the read would
+// otherwise remove it from the state. The poll goes through the configured URL, nodes can't be addressed one by one.
+func waitForReady(read schema.ReadContextFunc) schema.ReadContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		if !d.Get("wait_for_ready").(bool) {
+			return read(ctx, d, m)
+		}
+
+		err := resource.RetryContext(ctx, d.Timeout(schema.TimeoutCreate), func() *resource.RetryError {
+			resp, err := repository.RepositoryClientOf(m).Get(d.Id(), &repository.RepoInfo{})
+			if err != nil {
+				if repository.IsNotFound(resp) {
+					return resource.RetryableError(fmt.Errorf("expected repository %s to be created, but currently not found", d.Id()))
+				}
+				return resource.NonRetryableError(err)
+			}
+			return nil
+		})
+		if err != nil {
+			return diag.Errorf("repository %s is not ready: %s", d.Id(), err)
+		}
+
+		return read(ctx, d, m)
+	}
+}
+
+// importProjectScopedKey accepts either the repository key or `project_key:repo_key` as import ID. With the plain key,
+// `project_key` is left for the read to populate. Repositories of another rclass are refused.
+func importProjectScopedKey(_ context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	parts := strings.Split(d.Id(), ":")
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		projectKey, key := parts[0], parts[1]
+		if !strings.HasPrefix(key, projectKey+"-") {
+			return nil, fmt.Errorf("unexpected import ID %q: the repository key of a project repository must be prefixed with the project key and a dash, e.g. %s:%s-%s", d.Id(), projectKey, projectKey, key)
+		}
+		d.SetId(key)
+		if err := d.Set("key", key); err != nil {
+			return nil, err
+		}
+		if err := d.Set("project_key", projectKey); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unexpected import ID %q: expected the repository key or project_key:repo_key", d.Id())
+	}
+
+	if err := checkImportedRclass(d.Id(), m); err != nil {
+		return nil, err
+	}
+	return []*schema.ResourceData{d}, nil
+}
+
+// checkImportedRclass refuses to import a repository of another rclass, naming the resource type to import it with.
+// The read refuses it as well, the import only tells it earlier. Lookup failures are left to the read.
+func checkImportedRclass(key string, m interface{}) error {
+	info, _, err := repository.GetMemberInfo(m, key)
+	if err != nil || info.Rclass == "virtual" {
+		return nil
+	}
+	return fmt.Errorf("repository %s is a %s repository and can't be imported as a virtual repository, import it with artifactory_%s_%s_repository",
+		key, info.Rclass, info.Rclass, strings.ToLower(info.PackageType))
+}
+
+// adoptExisting adopts the repository of the same key when the create fails and `adopt_existing` is set, as long as its
+// configuration matches the one of the resource. It's the import that would otherwise be needed, e.g. to re-run a
+// partial apply. Only the fields the resource sends are compared, fields Artifactory omits match their zero value.
+// Adopting is skipped with validate_only, which would delete the adopted repository.
+func adoptExisting(unpack repository.UnpackFunc, read schema.ReadContextFunc, create schema.CreateContextFunc) schema.CreateContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		diags := create(ctx, d, m)
+		if !diags.HasError() || !d.Get("adopt_existing").(bool) || d.Get("validate_only").(bool) {
+			return diags
+		}
+
+		repo, key, err := unpack(d)
+		if err != nil {
+			return diags
+		}
+		existing := map[string]interface{}{}
+		if _, err := repository.RepositoryClientOf(m).Get(key, &existing); err != nil || existing["key"] != key {
+			// the create failed for another reason, or on a key only differing by case
+			return diags
+		}
+
+		encoded, err := json.Marshal(repo)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		desired := map[string]interface{}{}
+		if err := json.Unmarshal(encoded, &desired); err != nil {
+			return diag.FromErr(err)
+		}
+
+		var mismatches []string
+		for _, drift := range repository.DiffConfig(desired, existing) {
+			if drift.Actual == "" && slices.Contains([]string{`""`, "false", "0", "[]", "{}", "null"}, drift.Expected) {
+				continue
+			}
+			actual := drift.Actual
+			if actual == "" {
+				actual = "unset"
+			}
+			mismatches = append(mismatches, fmt.Sprintf("%s is %s instead of %s", drift.Field, actual, drift.Expected))
+		}
+		if len(mismatches) > 0 {
+			return diag.Errorf("repository %s already exists and its configuration doesn't match, adopt_existing only adopts matching repositories: %s",
+				key, strings.Join(mismatches, ", "))
+		}
+
+		d.SetId(key)
+		return append(diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "Existing repository adopted",
+			Detail:   fmt.Sprintf("The repository %s already existed with a matching configuration, it was adopted instead of created.", key),
+		}}, read(ctx, d, m)...)
+	}
+}
+
+// copyFrom creates the repository on top of the configuration of the `copy_from` repository. The fields managed by the
+// resource are sent as configured, even when left at their defaults, so the copied configuration never drifts from
+// Terraform's. What's inherited are the settings the provider doesn't model, sent like `extra_attributes` by the
+// repository create. The create is built by createWith for the unpacker in use, so both go through the same chain,
+// e.g. adopt_existing.
+func copyFrom(unpack repository.UnpackFunc, createWith func(repository.UnpackFunc) schema.CreateContextFunc) schema.CreateContextFunc {
+	create := createWith(unpack)
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		source := d.Get("copy_from").(string)
+		if source == "" {
+			return create(ctx, d, m)
+		}
+
+		current := map[string]interface{}{}
+		_, err := repository.RepositoryClientOf(m).Get(source, &current)
+		if err != nil {
+			return diag.Errorf("failed to read copy_from repository %s: %s", source, err)
+		}
+
+		inheriting := func(d *schema.ResourceData) (interface{}, string, error) {
+			repo, key, err := unpack(d)
+			if err != nil {
+				return nil, "", err
+			}
+			// most unpackers return the params by value, the inherited fields are set on a copy
+			if reflect.TypeOf(repo).Kind() != reflect.Ptr {
+				copied := reflect.New(reflect.TypeOf(repo))
+				copied.Elem().Set(reflect.ValueOf(repo))
+				repo = copied.Interface()
+			}
+			inheritor, ok := repo.(interface {
+				packageType() string
+				inherit(map[string]interface{})
+			})
+			if !ok {
+				return nil, "", fmt.Errorf("copy_from isn't supported by the virtual repository params %T", repo)
+			}
+			packageType := inheritor.packageType()
+			if current["rclass"] != "virtual" || current["packageType"] != packageType {
+				return nil, "", fmt.Errorf("copy_from repository %s is a %v %v repository, expected a %v virtual repository", source, current["packageType"], current["rclass"], packageType)
+			}
+			inheritor.inherit(repository.UnmanagedFields(current, repo))
+			return repo, key, nil
+		}
+		return createWith(inheriting)(ctx, d, m)
+	}
 }
@@ -0,0 +1,284 @@
+package virtual_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-log/tfsdklog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+func TestVirtualRepositoryCrudLogsRepoKey(t *testing.T) {
+	restyClient, _ := mockRepositories(t, map[string]string{})
+
+	// the provider root logger writes to os.Stderr, captured when the logger is created
+	logFile, err := os.CreateTemp(t.TempDir(), "tflog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = logFile
+	ctx := tfsdklog.NewRootProviderLogger(context.Background())
+	os.Stderr = stderr
+
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+		"key": "foo",
+	})
+	if diags := repoResource.CreateContext(ctx, d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	logs, err := os.ReadFile(logFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	operations := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(string(logs)), "\n") {
+		entry := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("failed to decode log line %q: %s", line, err)
+		}
+		if entry["repo_key"] == "foo" && entry["package_type"] == "generic" {
+			operations[entry["operation"].(string)] = true
+		}
+	}
+	if !operations["create"] || !operations["read"] {
+		t.Fatalf("expected create and read log lines with repo_key foo, got:\n%s", logs)
+	}
+}
+
+// the checks reading more of the Artifactory API than a RepositoryClient offers fail instead of being skipped
+func TestVirtualRepositoryChecksNeedArtifactoryAPI(t *testing.T) {
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	fake := &fakeRepositoryClient{repos: map[string][]byte{}}
+
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": "foo", "repo_layout_ref": "maven-2-default"})
+	diags := repoResource.CreateContext(context.Background(), d, fake)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "repo_layout_ref needs the Artifactory API") {
+		t.Fatalf("expected the layout check to fail, got %v", diags)
+	}
+	if len(fake.calls) != 0 {
+		t.Fatalf("expected the repository not to be created, got %v", fake.calls)
+	}
+
+	testCases := map[string]struct {
+		config        map[string]interface{}
+		expectedError string
+	}{
+		"key":         {map[string]interface{}{"key": "foo"}, "the check that key is available needs the Artifactory API"},
+		"project_key": {map[string]interface{}{"key": "proj-foo", "project_key": "proj", "adopt_existing": true}, "the check of members assigned to project_key needs the Artifactory API"},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := repoResource.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(testCase.config), fake)
+			if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+				t.Fatalf("expected error %q, got %v", testCase.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryCrudWithFakeClient(t *testing.T) {
+	const existing = `{"key":"foo","rclass":"virtual","packageType":"generic","description":"existing"}`
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	crud := map[string]func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics{
+		"create": repoResource.CreateContext,
+		"read":   repoResource.ReadContext,
+		"update": repoResource.UpdateContext,
+		"delete": repoResource.DeleteContext,
+	}
+
+	testCases := map[string]struct {
+		operation     string
+		status        int
+		expectedCalls []string
+		expectedError string
+		expectedID    string
+		expectedRepo  string
+	}{
+		"create":              {operation: "create", expectedCalls: []string{"create foo", "get foo"}, expectedID: "foo", expectedRepo: "configured"},
+		"create forbidden":    {operation: "create", status: http.StatusForbidden, expectedCalls: []string{"create foo"}, expectedError: "permission denied to create"},
+		"read":                {operation: "read", expectedCalls: []string{"get foo"}, expectedID: "foo", expectedRepo: "existing"},
+		"read not found":      {operation: "read", status: http.StatusNotFound, expectedCalls: []string{"get foo"}},
+		"read failure":        {operation: "read", status: http.StatusInternalServerError, expectedCalls: []string{"get foo"}, expectedError: "Internal Server Error", expectedID: "foo"},
+		"read bad request":    {operation: "read", status: http.StatusBadRequest, expectedCalls: []string{"get foo"}, expectedError: "Bad Request", expectedID: "foo"},
+		"update":              {operation: "update", expectedCalls: []string{"get foo", "create foo", "get foo"}, expectedID: "foo", expectedRepo: "configured"},
+		"update failure":      {operation: "update", status: http.StatusInternalServerError, expectedCalls: []string{"get foo"}, expectedError: "Internal Server Error", expectedID: "foo"},
+		"delete":              {operation: "delete", expectedCalls: []string{"delete foo"}, expectedID: "foo"},
+		"delete not found":    {operation: "delete", status: http.StatusNotFound, expectedCalls: []string{"delete foo"}},
+		"delete in conflict":  {operation: "delete", status: http.StatusConflict, expectedCalls: []string{"delete foo"}, expectedError: "Conflict", expectedID: "foo"},
+		"delete bad request":  {operation: "delete", status: http.StatusBadRequest, expectedCalls: []string{"delete foo"}, expectedError: "Bad Request", expectedID: "foo"},
+		"delete in read-only": {operation: "delete", status: http.StatusForbidden, expectedCalls: []string{"delete foo"}, expectedError: "permission denied to delete", expectedID: "foo"},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			fake := &fakeRepositoryClient{repos: map[string][]byte{"foo": []byte(existing)}, status: testCase.status}
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+				"key":         "foo",
+				"description": "configured",
+			})
+			if testCase.operation != "create" {
+				d.SetId("foo")
+			}
+
+			diags := crud[testCase.operation](context.Background(), d, fake)
+
+			if testCase.expectedError == "" && diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if testCase.expectedError != "" && (!diags.HasError() || !strings.Contains(diags[0].Summary, testCase.expectedError)) {
+				t.Fatalf("expected error %q, got %v", testCase.expectedError, diags)
+			}
+			if !reflect.DeepEqual(fake.calls, testCase.expectedCalls) {
+				t.Fatalf("expected calls %v, got %v", testCase.expectedCalls, fake.calls)
+			}
+			if d.Id() != testCase.expectedID {
+				t.Fatalf("expected id %q, got %q", testCase.expectedID, d.Id())
+			}
+			if testCase.expectedRepo != "" && d.Get("description") != testCase.expectedRepo {
+				t.Fatalf("expected description %q in state, got %q", testCase.expectedRepo, d.Get("description"))
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryErrorsIncludeSupportHeaders(t *testing.T) {
+	restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/artifactory/api/repositories/traced" {
+			w.Header().Set("X-Request-Id", "4f2a9c1e")
+			w.Header().Set("X-Artifactory-Id", "a1b2c3")
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"errors":[{"status":500,"message":"Internal Server Error"}]}`))
+	}))
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+
+	operations := map[string]func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics{
+		"create": repoResource.CreateContext,
+		"read":   repoResource.ReadContext,
+		"update": repoResource.UpdateContext,
+		"delete": repoResource.DeleteContext,
+	}
+
+	for name, operation := range operations {
+		t.Run(name, func(t *testing.T) {
+			for key, expectHeaders := range map[string]bool{"traced": true, "untraced": false} {
+				d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": key})
+				d.SetId(key)
+
+				diags := operation(context.Background(), d, restyClient)
+				if !diags.HasError() {
+					t.Fatalf("expected an error for %s", key)
+				}
+				detail := diags[len(diags)-1].Detail
+				hasHeaders := strings.Contains(detail, "X-Request-Id: 4f2a9c1e") && strings.Contains(detail, "X-Artifactory-Id: a1b2c3")
+				if hasHeaders != expectHeaders {
+					t.Fatalf("expected support headers %t in the detail of %s, got %q", expectHeaders, key, detail)
+				}
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryResponseWarnings(t *testing.T) {
+	testCases := map[string]struct {
+		response         string
+		expectedWarnings []string
+	}{
+		"strings":    {`{"warnings":["repoLayoutRef maven-2-default is deprecated","artifactoryRequestsCanRetrieveRemoteArtifacts is ignored"]}`, []string{"repoLayoutRef maven-2-default is deprecated", "artifactoryRequestsCanRetrieveRemoteArtifacts is ignored"}},
+		"objects":    {`{"warnings":[{"message":"repoLayoutRef maven-2-default is deprecated"}]}`, []string{"repoLayoutRef maven-2-default is deprecated"}},
+		"no warning": {`{"warnings":[]}`, nil},
+		"plain text": {`Successfully created repository 'foo'`, nil},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodPut, http.MethodPost:
+					_, _ = w.Write([]byte(testCase.response))
+				case http.MethodGet:
+					w.Header().Set("Content-Type", "application/json")
+					_, _ = w.Write([]byte(`{"key":"foo","rclass":"virtual","packageType":"generic"}`))
+				}
+			}))
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+
+			operations := map[string]func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics{
+				"create": repoResource.CreateContext,
+				"update": repoResource.UpdateContext,
+			}
+			for operationName, operation := range operations {
+				d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": "foo"})
+				d.SetId("foo")
+
+				diags := operation(context.Background(), d, restyClient)
+				if diags.HasError() {
+					t.Fatalf("unexpected error on %s: %v", operationName, diags)
+				}
+				var warnings []string
+				for _, diagnostic := range diags {
+					if diagnostic.Severity == diag.Warning && diagnostic.Summary == "Artifactory returned a warning" {
+						warnings = append(warnings, diagnostic.Detail)
+					}
+				}
+				if !reflect.DeepEqual(warnings, testCase.expectedWarnings) {
+					t.Fatalf("expected the warnings %v on %s, got %v", testCase.expectedWarnings, operationName, diags)
+				}
+			}
+		})
+	}
+}
+
+func TestRepositoryWritesEvictCachedRepoInfo(t *testing.T) {
+	restyClient, _ := mockRepositories(t, map[string]string{
+		"foo": `{"key":"foo","rclass":"local","packageType":"generic"}`,
+	})
+
+	info, _, err := repository.GetMemberInfo(restyClient, "foo")
+	if err != nil || info.Rclass != "local" {
+		t.Fatalf("expected foo to be looked up as a local repository, got %v, %v", info, err)
+	}
+
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": "foo"})
+	if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	info, _, err = repository.GetMemberInfo(restyClient, "foo")
+	if err != nil || info.Rclass != "virtual" {
+		t.Fatalf("expected the write of foo to evict its cached lookup, got %v, %v", info, err)
+	}
+}
+
+func TestVirtualRepositoryMemberLookupsWithFakeClient(t *testing.T) {
+	fake := &fakeRepositoryClient{repos: map[string][]byte{
+		"foo":       []byte(`{"key":"foo","rclass":"virtual","packageType":"npm","repositories":["foo-local"]}`),
+		"foo-local": []byte(`{"key":"foo-local","rclass":"local","packageType":"maven"}`),
+	}}
+	repoResource := virtual.ResourceArtifactoryVirtualNpmRepository()
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": "foo"})
+	d.SetId("foo")
+
+	diags := repoResource.ReadContext(context.Background(), d, fake)
+	if diags.HasError() || len(diags) != 1 || diags[0].Severity != diag.Warning || !strings.Contains(diags[0].Detail, "foo-local (maven)") {
+		t.Fatalf("expected a warning naming the maven member, got %v", diags)
+	}
+	if !reflect.DeepEqual(fake.calls, []string{"get foo", "get foo-local"}) {
+		t.Fatalf("expected the member to be looked up through the fake client, got %v", fake.calls)
+	}
+}
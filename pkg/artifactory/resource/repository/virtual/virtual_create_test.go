@@ -0,0 +1,373 @@
+package virtual_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+func TestVirtualRepositoryCreateWithReadOnlyToken(t *testing.T) {
+	restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors":[{"status":403,"message":"Forbidden"}]}`))
+	}))
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"jfrt@01/users/reader","scp":"applied-permissions/groups:readers"}`))
+	restyClient.SetAuthToken("eyJhbGciOiJSUzI1NiJ9." + claims + ".c2lnbmF0dXJl")
+
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": "foo-virtual"})
+
+	diags := repoResource.CreateContext(context.Background(), d, restyClient)
+	if !diags.HasError() {
+		t.Fatal("expected the create to fail")
+	}
+	for _, expected := range []string{"permission denied to create", `"applied-permissions/groups:readers"`, "'Manage' permission"} {
+		if !strings.Contains(diags[0].Summary, expected) {
+			t.Fatalf("expected error to contain %s, got %s", expected, diags[0].Summary)
+		}
+	}
+}
+
+func TestVirtualRepositoryCreateInMaintenanceMode(t *testing.T) {
+	testCases := map[string]struct {
+		body            string
+		expectedMessage string
+	}{
+		"maintenance mode":  {`{"errors":[{"status":503,"message":"Artifactory is in maintenance mode"}]}`, "read-only or maintenance mode"},
+		"other unavailable": {`{"errors":[{"status":503,"message":"Service Unavailable"}]}`, "Service Unavailable"},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte(testCase.body))
+			}))
+
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": "foo-virtual"})
+
+			diags := repoResource.CreateContext(context.Background(), d, restyClient)
+			if !diags.HasError() || !strings.Contains(diags[0].Summary, testCase.expectedMessage) {
+				t.Fatalf("expected error to contain %s, got %v", testCase.expectedMessage, diags)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryWaitForReady(t *testing.T) {
+	for _, wait := range []bool{true, false} {
+		t.Run(fmt.Sprintf("wait=%t", wait), func(t *testing.T) {
+			created := ""
+			reads := 0
+			restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPut {
+					body, _ := io.ReadAll(r.Body)
+					created = string(body)
+					return
+				}
+				// the first reads hit a node the repository hasn't propagated to yet
+				reads++
+				if reads <= 2 {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(created))
+			}))
+
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+				"key":            "foo",
+				"wait_for_ready": wait,
+			})
+			if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			// without waiting, the read straight after the create sees a 404 and drops the repository from the state
+			expectedID := ""
+			if wait {
+				expectedID = "foo"
+			}
+			if d.Id() != expectedID {
+				t.Fatalf("expected ID %q, got %q", expectedID, d.Id())
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryWaitForReadyTimesOut(t *testing.T) {
+	restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+		"key":            "foo",
+		"wait_for_ready": true,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	diags := repoResource.CreateContext(ctx, d, restyClient)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "not ready") {
+		t.Fatalf("expected a not ready error, got %v", diags)
+	}
+}
+
+func TestVirtualRepositoryCopyFrom(t *testing.T) {
+	restyClient, sent := mockRepositories(t, map[string]string{
+		"template-npm": `{"key":"template-npm","rclass":"virtual","packageType":"npm","description":"template","repositories":["npm-remote"],"externalDependenciesRewriteRemote":true}`,
+		"template-go":  `{"key":"template-go","rclass":"virtual","packageType":"go"}`,
+	})
+	repoResource := virtual.ResourceArtifactoryVirtualNpmRepository()
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+		"key":          "foo-npm",
+		"copy_from":    "template-npm",
+		"description":  "copied",
+		"repositories": []interface{}{"npm-local"},
+	})
+
+	if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	created := sent["foo-npm"]
+	if created["key"] != "foo-npm" || created["description"] != "copied" || !reflect.DeepEqual(created["repositories"], []interface{}{"npm-local"}) {
+		t.Fatalf("expected the configured attributes to override the source, got %v", created)
+	}
+	if created["externalDependenciesRewriteRemote"] != true {
+		t.Fatalf("expected the unmanaged settings to be inherited from the source, got %v", created)
+	}
+	if _, ok := sent["template-npm"]; ok {
+		t.Fatal("expected the source repository to be left untouched")
+	}
+
+	d = schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+		"key":       "bar-npm",
+		"copy_from": "template-go",
+	})
+	diags := repoResource.CreateContext(context.Background(), d, restyClient)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "expected a npm virtual repository") {
+		t.Fatalf("expected a package type mismatch error, got %v", diags)
+	}
+
+	// the source is only copied on create, changing it plans nothing
+	state := &terraform.InstanceState{ID: "foo-npm", Attributes: map[string]string{"id": "foo-npm", "key": "foo-npm", "copy_from": "template-npm"}}
+	planned, err := repoResource.SimpleDiff(context.Background(), state, terraform.NewResourceConfigRaw(map[string]interface{}{"key": "foo-npm", "copy_from": "template-go"}), restyClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attribute, ok := planned.Attributes["copy_from"]; ok {
+		t.Fatalf("expected no change of copy_from to be planned, got %v", attribute)
+	}
+}
+
+// the params of most package types are unpacked by value, they inherit the source settings all the same
+// the copy goes through the same create as other repositories, an existing repository is adopted with adopt_existing
+func TestVirtualRepositoryCopyFromAdoptsExisting(t *testing.T) {
+	writes := 0
+	restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPut || r.Method == http.MethodPost:
+			writes++
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"errors":[{"status":409,"message":"Repository foo already exists"}]}`))
+		case r.URL.Path == "/"+repository.RepositoriesEndpoint+"template":
+			_, _ = w.Write([]byte(`{"key":"template","rclass":"virtual","packageType":"generic","artifactoryRequestsCanRetrieveRemoteArtifacts":true}`))
+		case r.URL.Path == "/"+repository.RepositoriesEndpoint+"foo":
+			_, _ = w.Write([]byte(`{"key":"foo","rclass":"virtual","packageType":"generic","description":"copied","repositories":[],"includesPattern":"**/*","repoLayoutRef":"simple-default"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+		"key":            "foo",
+		"copy_from":      "template",
+		"description":    "copied",
+		"adopt_existing": true,
+	})
+
+	diags := repoResource.CreateContext(context.Background(), d, restyClient)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if d.Id() != "foo" || writes != 1 {
+		t.Fatalf("expected foo to be adopted after a single create, got ID %q and %d writes", d.Id(), writes)
+	}
+	adopted := false
+	for _, diagnostic := range diags {
+		adopted = adopted || diagnostic.Summary == "Existing repository adopted"
+	}
+	if !adopted {
+		t.Fatalf("expected the adoption to be reported, got %v", diags)
+	}
+}
+
+func TestVirtualRepositoryCreateSendsSingleWrite(t *testing.T) {
+	repos := map[string]string{
+		"npm-local":  `{"key":"npm-local","rclass":"local","packageType":"npm"}`,
+		"npm-remote": `{"key":"npm-remote","rclass":"remote","packageType":"npm"}`,
+	}
+	var writes []string
+	var created map[string]interface{}
+	restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/"+repository.RepositoriesEndpoint)
+		if r.Method != http.MethodGet {
+			writes = append(writes, r.Method+" "+key)
+			if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+				t.Errorf("failed to decode request body: %s", err)
+			}
+			encoded, _ := json.Marshal(created)
+			repos[key] = string(encoded)
+			return
+		}
+		repo, ok := repos[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(repo))
+	}))
+
+	repoResource := virtual.ResourceArtifactoryVirtualNpmRepository()
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+		"key":                               "foo-npm",
+		"repositories":                      []interface{}{"npm-local", "npm-remote"},
+		"description":                       "all fields",
+		"default_deployment_repo":           "npm-local",
+		"retrieval_cache_period_seconds":    3600,
+		"external_dependencies_enabled":     true,
+		"external_dependencies_patterns":    []interface{}{"**/github.com/**"},
+		"external_dependencies_remote_repo": "npm-remote",
+	})
+
+	if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if !reflect.DeepEqual(writes, []string{"PUT foo-npm"}) {
+		t.Fatalf("expected the create to be a single PUT, got %v", writes)
+	}
+	expected := map[string]interface{}{
+		"description":                     "all fields",
+		"defaultDeploymentRepo":           "npm-local",
+		"virtualRetrievalCachePeriodSecs": float64(3600),
+		"externalDependenciesEnabled":     true,
+		"externalDependenciesPatterns":    []interface{}{"**/github.com/**"},
+		"externalDependenciesRemoteRepo":  "npm-remote",
+	}
+	for field, value := range expected {
+		if !reflect.DeepEqual(created[field], value) {
+			t.Fatalf("expected %s to be %v in the create, got %v", field, value, created[field])
+		}
+	}
+}
+
+func TestVirtualRepositoryAdoptExisting(t *testing.T) {
+	config := map[string]interface{}{
+		"key":          "foo",
+		"description":  "managed by terraform",
+		"repositories": []interface{}{"foo-local"},
+	}
+	// the configuration the resource sends, as Artifactory would return it
+	restyClient, sent := mockRepositories(t, map[string]string{})
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	if diags := repoResource.CreateContext(context.Background(), schema.TestResourceDataRaw(t, repoResource.Schema, config), restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	matching := sent["foo"]
+
+	testCases := map[string]struct {
+		adopt         bool
+		existing      map[string]interface{}
+		expectedError string
+	}{
+		"matching":             {true, matching, ""},
+		"matching with extras": {true, map[string]interface{}{"notes": "", "keyPair": ""}, ""},
+		"mismatching":          {true, map[string]interface{}{"description": "created by hand"}, `description is "created by hand" instead of "managed by terraform"`},
+		"mismatching members":  {true, map[string]interface{}{"repositories": []interface{}{}}, `repositories is [] instead of ["foo-local"]`},
+		"not adopting":         {false, matching, "409"},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			existing := map[string]interface{}{}
+			for field, value := range matching {
+				existing[field] = value
+			}
+			for field, value := range testCase.existing {
+				existing[field] = value
+			}
+			encoded, _ := json.Marshal(existing)
+
+			writes := 0
+			restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.Method {
+				case http.MethodPut, http.MethodPost:
+					writes++
+					w.WriteHeader(http.StatusConflict)
+					_, _ = w.Write([]byte(`{"errors":[{"status":409,"message":"Repository foo already exists"}]}`))
+				case http.MethodGet:
+					if r.URL.Path != "/"+repository.RepositoriesEndpoint+"foo" {
+						w.WriteHeader(http.StatusBadRequest)
+						return
+					}
+					_, _ = w.Write(encoded)
+				}
+			}))
+			adoptConfig := map[string]interface{}{"adopt_existing": testCase.adopt}
+			for attribute, value := range config {
+				adoptConfig[attribute] = value
+			}
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, adoptConfig)
+
+			diags := repoResource.CreateContext(context.Background(), d, restyClient)
+			if testCase.expectedError != "" {
+				if !diags.HasError() || !strings.Contains(fmt.Sprint(diags), testCase.expectedError) {
+					t.Fatalf("expected an error containing %s, got %v", testCase.expectedError, diags)
+				}
+				if d.Id() != "" {
+					t.Fatalf("expected the repository not to be adopted, got ID %s", d.Id())
+				}
+				return
+			}
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if d.Id() != "foo" || d.Get("description") != "managed by terraform" {
+				t.Fatalf("expected foo to be adopted, got ID %q and description %q", d.Id(), d.Get("description"))
+			}
+			if writes != 1 {
+				t.Fatalf("expected the failed create to be the only write, got %d", writes)
+			}
+			adopted := false
+			for _, diagnostic := range diags {
+				adopted = adopted || diagnostic.Summary == "Existing repository adopted"
+			}
+			if !adopted {
+				t.Fatalf("expected an adoption warning, got %v", diags)
+			}
+		})
+	}
+}
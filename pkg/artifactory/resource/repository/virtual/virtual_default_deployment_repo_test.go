@@ -0,0 +1,229 @@
+package virtual_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+func TestVirtualRepositoryClearsMissingDefaultDeploymentRepo(t *testing.T) {
+	repos := map[string]string{
+		"foo-virtual": `{"key":"foo-virtual","rclass":"virtual","packageType":"generic","repositories":["foo-local"],"defaultDeploymentRepo":"foo-local"}`,
+		"foo-local":   `{"key":"foo-local","rclass":"local","packageType":"generic"}`,
+	}
+	restyClient, _ := mockRepositories(t, repos)
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+
+	read := func() (*schema.ResourceData, diag.Diagnostics) {
+		d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": "foo-virtual"})
+		d.SetId("foo-virtual")
+		diags := repoResource.ReadContext(context.Background(), d, restyClient)
+		if diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+		return d, diags
+	}
+
+	d, diags := read()
+	if len(diags) != 0 || d.Get("default_deployment_repo") != "foo-local" {
+		t.Fatalf("expected foo-local without diagnostics, got %q and %v", d.Get("default_deployment_repo"), diags)
+	}
+
+	// the deployment target is deleted out-of-band, the virtual repository still references it
+	if _, err := restyClient.R().Delete(repository.RepositoriesEndpoint + "foo-local"); err != nil {
+		t.Fatal(err)
+	}
+	// lookups are cached for a run of the provider, the next run reads again
+	restyClient, _ = mockRepositories(t, repos)
+	d, diags = read()
+	if len(diags) != 1 || diags[0].Severity != diag.Warning || !strings.Contains(diags[0].Detail, "foo-local") {
+		t.Fatalf("expected a warning naming foo-local, got %v", diags)
+	}
+	if got := d.Get("default_deployment_repo"); got != "" {
+		t.Fatalf("expected default_deployment_repo to be cleared, got %q", got)
+	}
+}
+
+func TestVirtualRepositoryAutoDefaultDeploymentRepo(t *testing.T) {
+	testCases := map[string]struct {
+		config          map[string]interface{}
+		expectedDefault string
+		expectedWarning bool
+	}{
+		"first local member": {
+			config:          map[string]interface{}{"auto_default_deployment_repo": true, "repositories": []interface{}{"remote-a", "local-a", "local-b"}},
+			expectedDefault: "local-a",
+		},
+		"configured default": {
+			config:          map[string]interface{}{"auto_default_deployment_repo": true, "repositories": []interface{}{"local-a", "local-b"}, "default_deployment_repo": "local-b"},
+			expectedDefault: "local-b",
+		},
+		"no local member": {
+			config:          map[string]interface{}{"auto_default_deployment_repo": true, "repositories": []interface{}{"remote-a"}},
+			expectedWarning: true,
+		},
+		"disabled": {
+			config: map[string]interface{}{"repositories": []interface{}{"remote-a", "local-a"}},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, sent := mockRepositories(t, map[string]string{
+				"local-a":  `{"key":"local-a","rclass":"local","packageType":"generic"}`,
+				"local-b":  `{"key":"local-b","rclass":"local","packageType":"generic"}`,
+				"remote-a": `{"key":"remote-a","rclass":"remote","packageType":"generic"}`,
+			})
+			testCase.config["key"] = "foo"
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, testCase.config)
+
+			diags := repoResource.CreateContext(context.Background(), d, restyClient)
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			// defaultDeploymentRepo is omitted when empty
+			if actual, _ := sent["foo"]["defaultDeploymentRepo"].(string); actual != testCase.expectedDefault {
+				t.Fatalf("expected default deployment repository %q to be sent, got %v", testCase.expectedDefault, sent["foo"]["defaultDeploymentRepo"])
+			}
+			if actual := d.Get("default_deployment_repo"); actual != testCase.expectedDefault {
+				t.Fatalf("expected default deployment repository %q in state, got %q", testCase.expectedDefault, actual)
+			}
+			warned := false
+			for _, diagnostic := range diags {
+				warned = warned || diagnostic.Summary == "No local member to deploy to"
+			}
+			if warned != testCase.expectedWarning {
+				t.Fatalf("expected warning %t, got %v", testCase.expectedWarning, diags)
+			}
+		})
+	}
+
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"auto_default_deployment_repo": true})
+	if !repoResource.Schema["default_deployment_repo"].DiffSuppressFunc("default_deployment_repo", "local-a", "", d) {
+		t.Fatal("expected the resolved default deployment repository not to be planned for removal")
+	}
+}
+
+func TestVirtualRepositoryDefaultDeploymentRepoRclass(t *testing.T) {
+	testCases := map[string]struct {
+		state           map[string]string
+		configured      string
+		expectedError   string
+		expectedLookups int
+	}{
+		"local target":     {map[string]string{}, "target-local", "", 1},
+		"federated target": {map[string]string{}, "target-federated", "", 1},
+		"remote target":    {map[string]string{}, "target-remote", "default_deployment_repo target-remote is a remote repository", 1},
+		"reset":            {map[string]string{"default_deployment_repo": "target-remote"}, "", "", 0},
+		"unchanged":        {map[string]string{"default_deployment_repo": "target-remote"}, "target-remote", "", 0},
+		// lookups that fail aren't cached, the repository may be created in the meantime
+		"unknown": {map[string]string{}, "new-local", "", 2},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			lookups := 0
+			restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				lookups++
+				w.Header().Set("Content-Type", "application/json")
+				switch strings.TrimPrefix(r.URL.Path, "/"+repository.RepositoriesEndpoint) {
+				case "target-local":
+					_, _ = w.Write([]byte(`{"key":"target-local","rclass":"local","packageType":"generic"}`))
+				case "target-federated":
+					_, _ = w.Write([]byte(`{"key":"target-federated","rclass":"federated","packageType":"generic"}`))
+				case "target-remote":
+					_, _ = w.Write([]byte(`{"key":"target-remote","rclass":"remote","packageType":"generic"}`))
+				default:
+					w.WriteHeader(http.StatusBadRequest)
+				}
+			}))
+
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			attributes := map[string]string{"id": "foo-virtual", "key": "foo-virtual"}
+			for attribute, value := range testCase.state {
+				attributes[attribute] = value
+			}
+			state := &terraform.InstanceState{ID: "foo-virtual", Attributes: attributes}
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":                     "foo-virtual",
+				"default_deployment_repo": testCase.configured,
+			})
+
+			// the second plan is served from the cache
+			for i := 0; i < 2; i++ {
+				_, err := repoResource.Diff(context.Background(), state, config, restyClient)
+				if testCase.expectedError != "" {
+					if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+						t.Fatalf("expected error to contain %s, got %v", testCase.expectedError, err)
+					}
+				} else if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			}
+			if lookups != testCase.expectedLookups {
+				t.Fatalf("expected %d lookups, got %d", testCase.expectedLookups, lookups)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryDefaultDeploymentRepoMember(t *testing.T) {
+	testCases := map[string]struct {
+		repositories  []interface{}
+		configured    string
+		auto          bool
+		expectedError string
+	}{
+		"deployment repo removed":       {[]interface{}{"other-local"}, "target-local", false, "default_deployment_repo target-local is removed from repositories"},
+		"last member removed":           {[]interface{}{}, "target-local", false, "default_deployment_repo target-local is removed from repositories"},
+		"other member removed":          {[]interface{}{"target-local"}, "target-local", false, ""},
+		"deployment repo reset":         {[]interface{}{"other-local"}, "", false, ""},
+		"deployment repo changed":       {[]interface{}{"other-local"}, "other-local", false, ""},
+		"auto default deployment repo":  {[]interface{}{"other-local"}, "target-local", true, ""},
+		"deployment repo moved in list": {[]interface{}{"other-local", "target-local"}, "target-local", false, ""},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, _ := mockRepositories(t, map[string]string{
+				"target-local": `{"key":"target-local","rclass":"local","packageType":"generic"}`,
+				"other-local":  `{"key":"other-local","rclass":"local","packageType":"generic"}`,
+			})
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			state := &terraform.InstanceState{ID: "foo-virtual", Attributes: map[string]string{
+				"id":                      "foo-virtual",
+				"key":                     "foo-virtual",
+				"repositories.#":          "2",
+				"repositories.0":          "target-local",
+				"repositories.1":          "other-local",
+				"default_deployment_repo": "target-local",
+			}}
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":                          "foo-virtual",
+				"repositories":                 testCase.repositories,
+				"default_deployment_repo":      testCase.configured,
+				"auto_default_deployment_repo": testCase.auto,
+			})
+
+			_, err := repoResource.Diff(context.Background(), state, config, restyClient)
+			if testCase.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+					t.Fatalf("expected error to contain %s, got %v", testCase.expectedError, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package virtual_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+func TestVirtualRepositoryReadRemovesDeletedRepository(t *testing.T) {
+	testCases := map[string]struct {
+		status  int
+		removed bool
+	}{
+		"not found":    {status: http.StatusNotFound, removed: true},
+		"bad request":  {status: http.StatusBadRequest, removed: false},
+		"server error": {status: http.StatusInternalServerError, removed: false},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+			}))
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": "foo"})
+			d.SetId("foo")
+
+			diags := repoResource.ReadContext(context.Background(), d, restyClient)
+
+			if tc.removed {
+				if diags.HasError() {
+					t.Fatalf("expected no error, got %v", diags)
+				}
+				if d.Id() != "" {
+					t.Fatalf("expected resource to be removed from state, got id %q", d.Id())
+				}
+			} else {
+				if !diags.HasError() {
+					t.Fatal("expected error to be returned")
+				}
+				if d.Id() != "foo" {
+					t.Fatalf("expected resource to stay in state, got id %q", d.Id())
+				}
+			}
+		})
+	}
+}
+
+func TestAccVirtualRepository_deletedOutOfBand(t *testing.T) {
+	_, fqrn, name := acctest.MkNames("foo", "artifactory_virtual_generic_repository")
+	config := fmt.Sprintf(`
+		resource "artifactory_virtual_generic_repository" "%s" {
+			key = "%s"
+		}
+	`, name, name)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { acctest.PreCheck(t) },
+		ProviderFactories: acctest.ProviderFactories,
+		CheckDestroy:      acctest.CheckRepoDestroy(t, fqrn),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check:  resource.TestCheckResourceAttr(fqrn, "key", name),
+			},
+			{
+				PreConfig:          func() { acctest.DeleteRepo(t, name) },
+				Config:             config,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+func TestVirtualRepositoryDeleteListsDependentReferences(t *testing.T) {
+	repos := map[string]string{
+		"foo": `{"key":"foo","rclass":"virtual","packageType":"generic","repositories":["local-a","bar"]}`,
+		"bar": `{"key":"bar","rclass":"virtual","packageType":"generic","repositories":["local-a"]}`,
+	}
+	updated := false
+	restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"errors":[{"status":409,"message":"Repository bar is used by other virtual repositories"}]}`))
+		case r.Method == http.MethodPost:
+			updated = true
+		case r.URL.Path == "/artifactory/api/repositories":
+			_, _ = w.Write([]byte(`[{"key":"foo","type":"VIRTUAL"},{"key":"bar","type":"VIRTUAL"}]`))
+		default:
+			_, _ = w.Write([]byte(repos[strings.TrimPrefix(r.URL.Path, "/"+repository.RepositoriesEndpoint)]))
+		}
+	}))
+
+	for _, cleanup := range []bool{true, false} {
+		t.Run(fmt.Sprintf("cleanup=%t", cleanup), func(t *testing.T) {
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+				"key":                          "bar",
+				"cleanup_dependent_references": cleanup,
+			})
+			d.SetId("bar")
+
+			diags := repoResource.DeleteContext(context.Background(), d, restyClient)
+			if !diags.HasError() {
+				t.Fatal("expected the delete to fail")
+			}
+
+			listed := false
+			for _, diagnostic := range diags {
+				if strings.Contains(diagnostic.Detail, "virtual repositories foo.") {
+					listed = true
+				}
+			}
+			if listed != cleanup {
+				t.Fatalf("expected foo to be listed: %t, got %v", cleanup, diags)
+			}
+			if updated {
+				t.Fatal("expected the referencing virtual repositories to be left untouched")
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryDeletionProtection(t *testing.T) {
+	deletes := 0
+	restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deletes++
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"key":"foo","rclass":"virtual","packageType":"generic"}`))
+	}))
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+		"key":                 "foo",
+		"deletion_protection": true,
+	})
+	d.SetId("foo")
+	diags := repoResource.DeleteContext(context.Background(), d, restyClient)
+	if !diags.HasError() || !strings.Contains(diags[0].Detail, "Set deletion_protection = false and apply") {
+		t.Fatalf("expected the delete to be refused, got %v", diags)
+	}
+	if deletes != 0 {
+		t.Fatalf("expected no delete to be sent, got %d", deletes)
+	}
+
+	// protection is turned off by updating the flag, the repository itself isn't changed
+	d = schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+		"key":                 "foo",
+		"deletion_protection": false,
+	})
+	d.SetId("foo")
+	if diags := repoResource.UpdateContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if diags := repoResource.DeleteContext(context.Background(), repoResource.Data(d.State()), restyClient); diags.HasError() {
+		t.Fatalf("expected the delete to be allowed after unsetting deletion_protection, got %v", diags)
+	}
+	if deletes != 1 {
+		t.Fatalf("expected the repository to be deleted once, got %d", deletes)
+	}
+}
+
+func TestVirtualRepositoryDeletionProtectionReplacement(t *testing.T) {
+	testCases := map[string]struct {
+		state         map[string]string
+		config        map[string]interface{}
+		expectedError string
+	}{
+		"key change": {
+			map[string]string{"deletion_protection": "true"},
+			map[string]interface{}{"key": "bar", "deletion_protection": true},
+			"the change of key replaces it",
+		},
+		"project move": {
+			map[string]string{"deletion_protection": "true", "project_key": "proja", "replace_on_project_key_change": "true"},
+			map[string]interface{}{"key": "foo", "deletion_protection": true, "project_key": "projb", "replace_on_project_key_change": true},
+			"the change of project_key replaces it",
+		},
+		// the state is checked, unsetting it in the same change doesn't allow the replacement
+		"unset with key change": {
+			map[string]string{"deletion_protection": "true"},
+			map[string]interface{}{"key": "bar"},
+			"the change of key replaces it",
+		},
+		"in place update": {
+			map[string]string{"deletion_protection": "true"},
+			map[string]interface{}{"key": "foo", "deletion_protection": true, "description": "updated"},
+			"",
+		},
+		"unprotected key change": {
+			map[string]string{},
+			map[string]interface{}{"key": "bar"},
+			"",
+		},
+	}
+
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			attributes := map[string]string{"id": "foo", "key": "foo", "package_type": "generic"}
+			for attribute, value := range testCase.state {
+				attributes[attribute] = value
+			}
+			state := &terraform.InstanceState{ID: "foo", Attributes: attributes}
+			_, err := repoResource.Diff(context.Background(), state, terraform.NewResourceConfigRaw(testCase.config), acctest.NewMockClient(t, http.NotFoundHandler()))
+			if testCase.expectedError == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+				t.Fatalf("expected error to contain %s, got %v", testCase.expectedError, err)
+			}
+		})
+	}
+}
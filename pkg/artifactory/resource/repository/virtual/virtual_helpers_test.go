@@ -0,0 +1,123 @@
+package virtual_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	ctyjson "github.com/hashicorp/go-cty/cty/json"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+)
+
+// mockRepositories serves the given repository configurations by key and stores any repository created or updated
+// through it, so the virtual repository read after a write sees what was sent.
+func mockRepositories(t *testing.T, repos map[string]string) (*resty.Client, map[string]map[string]interface{}) {
+	sent := map[string]map[string]interface{}{}
+	restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/"+repository.RepositoriesEndpoint)
+		switch r.Method {
+		case http.MethodPut, http.MethodPost:
+			body := map[string]interface{}{}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("failed to decode request body: %s", err)
+			}
+			sent[key] = body
+			encoded, _ := json.Marshal(body)
+			repos[key] = string(encoded)
+		case http.MethodGet:
+			repo, ok := repos[key]
+			if !ok && !strings.HasPrefix(r.URL.Path, "/"+repository.RepositoriesEndpoint) {
+				// other lookups, e.g. of projects, fail without saying the object is missing
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(repo))
+		case http.MethodDelete:
+			delete(repos, key)
+		}
+	}))
+	return restyClient, sent
+}
+
+// fakeRepositoryClient keeps repository configurations in memory, failing every call with status when it is set
+type fakeRepositoryClient struct {
+	repos  map[string][]byte
+	status int
+	calls  []string
+}
+
+func (f *fakeRepositoryClient) respond(call string) (*resty.Response, error) {
+	f.calls = append(f.calls, call)
+	if f.status == 0 {
+		return &resty.Response{RawResponse: &http.Response{StatusCode: http.StatusOK}}, nil
+	}
+	return &resty.Response{RawResponse: &http.Response{StatusCode: f.status}}, fmt.Errorf("%s: %s", call, http.StatusText(f.status))
+}
+
+func (f *fakeRepositoryClient) Create(key string, repo interface{}) (*resty.Response, error) {
+	resp, err := f.respond("create " + key)
+	if err == nil {
+		f.repos[key], err = json.Marshal(repo)
+	}
+	return resp, err
+}
+
+func (f *fakeRepositoryClient) Get(key string, repo interface{}) (*resty.Response, error) {
+	resp, err := f.respond("get " + key)
+	if err == nil {
+		err = json.Unmarshal(f.repos[key], repo)
+	}
+	return resp, err
+}
+
+func (f *fakeRepositoryClient) Update(key string, repo interface{}) (*resty.Response, error) {
+	return f.Create(key, repo)
+}
+
+func (f *fakeRepositoryClient) Delete(key string) (*resty.Response, error) {
+	resp, err := f.respond("delete " + key)
+	if err == nil {
+		delete(f.repos, key)
+	}
+	return resp, err
+}
+
+// planWithRawConfig plans config on top of state like Terraform does, with the raw configuration the resource checks
+// for unset attributes
+func planWithRawConfig(t *testing.T, repoResource *schema.Resource, state *terraform.InstanceState, config map[string]interface{}, m interface{}) (*schema.ResourceData, *terraform.InstanceDiff) {
+	t.Helper()
+	encoded, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state == nil {
+		state = &terraform.InstanceState{}
+	}
+	state.RawConfig, err = ctyjson.Unmarshal(encoded, repoResource.CoreConfigSchema().ImpliedType())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Terraform plans with SimpleDiff, Diff recomputes the diff of new resources without the raw configuration
+	planned, err := repoResource.SimpleDiff(context.Background(), state, terraform.NewResourceConfigRaw(config), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := schema.InternalMap(repoResource.Schema).Data(state, planned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d, planned
+}
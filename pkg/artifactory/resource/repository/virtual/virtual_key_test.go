@@ -0,0 +1,404 @@
+package virtual_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-log/tfsdklog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/local"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+func TestVirtualRepositoryKeyValidation(t *testing.T) {
+	testCases := map[string]bool{
+		"libs-virtual":      true,
+		"cache-virtual":     true,
+		"libs-cached":       true,
+		"libs-cache":        false,
+		"libs-remote-CACHE": false,
+	}
+
+	for key, valid := range testCases {
+		t.Run(key, func(t *testing.T) {
+			_, errs := virtual.RepoKeyValidator(key, "key")
+			if valid && len(errs) > 0 {
+				t.Fatalf("expected %s to be valid, got %v", key, errs)
+			}
+			if !valid && len(errs) == 0 {
+				t.Fatalf("expected %s to be rejected", key)
+			}
+		})
+	}
+}
+
+func TestValidateRepoKey(t *testing.T) {
+	testCases := []struct {
+		key           string
+		expectedError string
+	}{
+		{key: "libs-release-local"},
+		{key: "libs-virtual"},
+		{key: "Libs_2.x-virtual"},
+		{key: "1libs-virtual", expectedError: "must not begin with a number"},
+		{key: "libs virtual", expectedError: "must not contain whitespace"},
+		{key: "libs\tvirtual", expectedError: "must not contain whitespace"},
+		{key: "libs@virtual", expectedError: "illegal character '@'"},
+		{key: "libs/virtual", expectedError: "illegal character '/'"},
+		{key: "libs-virtuál", expectedError: "illegal character 'á'"},
+		{key: "libs-cache", expectedError: "cannot end with '-cache'"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			diags := virtual.ValidateRepoKey(testCase.key, cty.GetAttrPath("key"))
+			if testCase.expectedError == "" {
+				if diags.HasError() {
+					t.Fatalf("expected %q to be valid, got %v", testCase.key, diags)
+				}
+				return
+			}
+			if !diags.HasError() {
+				t.Fatalf("expected %q to be rejected", testCase.key)
+			}
+			message := diags[0].Summary + " " + diags[0].Detail
+			if !strings.Contains(message, testCase.expectedError) {
+				t.Fatalf("expected error containing %q, got %q", testCase.expectedError, message)
+			}
+		})
+	}
+}
+
+func TestValidateMaxLength(t *testing.T) {
+	testCases := map[string]struct {
+		value string
+		valid bool
+	}{
+		"empty":                {"", true},
+		"at limit":             {strings.Repeat("a", virtual.MaxDescriptionLength), true},
+		"over limit":           {strings.Repeat("a", virtual.MaxDescriptionLength+1), false},
+		"multibyte at limit":   {strings.Repeat("é", virtual.MaxDescriptionLength), true},
+		"multibyte over limit": {strings.Repeat("日", virtual.MaxDescriptionLength+1), false},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			diags := virtual.ValidateMaxLength(virtual.MaxDescriptionLength)(testCase.value, cty.GetAttrPath("description"))
+			if testCase.valid && diags.HasError() {
+				t.Fatalf("expected %s to be valid, got %v", name, diags)
+			}
+			if !testCase.valid && !diags.HasError() {
+				t.Fatalf("expected %s to be rejected", name)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryCreateCaseCollision(t *testing.T) {
+	testCases := map[string]struct {
+		existing        string
+		expectedMessage string
+	}{
+		"case collision": {`[{"key":"Libs-Release","type":"VIRTUAL","packageType":"Generic"}]`, "collides with the existing repository Libs-Release"},
+		"other conflict": {`[{"key":"libs-snapshot","type":"VIRTUAL","packageType":"Generic"}]`, "Repository libs-release already exists"},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if r.Method == http.MethodGet && r.URL.Path == "/artifactory/api/repositories" {
+					_, _ = w.Write([]byte(testCase.existing))
+					return
+				}
+				w.WriteHeader(http.StatusConflict)
+				_, _ = w.Write([]byte(`{"errors":[{"status":409,"message":"Repository libs-release already exists"}]}`))
+			}))
+
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": "libs-release"})
+
+			diags := repoResource.CreateContext(context.Background(), d, restyClient)
+			if !diags.HasError() || !strings.Contains(diags[len(diags)-1].Summary, testCase.expectedMessage) {
+				t.Fatalf("expected error to contain %s, got %v", testCase.expectedMessage, diags)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryImportRefusesOtherRclass(t *testing.T) {
+	testCases := map[string]struct {
+		id            string
+		expectedError string
+	}{
+		"virtual":         {"foo-virtual", ""},
+		"project virtual": {"proj:proj-virtual", ""},
+		"local":           {"foo-local", "repository foo-local is a local repository and can't be imported as a virtual repository, import it with artifactory_local_npm_repository"},
+		"missing":         {"foo-missing", ""},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, _ := mockRepositories(t, map[string]string{
+				"foo-virtual":  `{"key":"foo-virtual","rclass":"virtual","packageType":"npm"}`,
+				"proj-virtual": `{"key":"proj-virtual","rclass":"virtual","packageType":"npm","projectKey":"proj"}`,
+				"foo-local":    `{"key":"foo-local","rclass":"local","packageType":"npm"}`,
+			})
+			repoResource := virtual.ResourceArtifactoryVirtualNpmRepository()
+			d := repoResource.Data(nil)
+			d.SetId(testCase.id)
+
+			imported, err := repoResource.Importer.StateContext(context.Background(), d, restyClient)
+			if testCase.expectedError != "" {
+				if err == nil || err.Error() != testCase.expectedError {
+					t.Fatalf("expected error %q, got %v", testCase.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(imported) != 1 {
+				t.Fatalf("expected one imported resource, got %d", len(imported))
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryReadRefusesOtherRclass(t *testing.T) {
+	testCases := map[string]struct {
+		rclass        string
+		expectedError bool
+	}{
+		"virtual": {"virtual", false},
+		"local":   {"local", true},
+		"remote":  {"remote", true},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, _ := mockRepositories(t, map[string]string{
+				"foo": fmt.Sprintf(`{"key":"foo","rclass":%q,"packageType":"generic"}`, testCase.rclass),
+			})
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": "foo"})
+			d.SetId("foo")
+
+			diags := repoResource.ReadContext(context.Background(), d, restyClient)
+			if diags.HasError() != testCase.expectedError {
+				t.Fatalf("expected error to be %t, got %v", testCase.expectedError, diags)
+			}
+			if testCase.expectedError && !strings.Contains(diags[0].Summary, "is a "+testCase.rclass+" repository") {
+				t.Fatalf("expected the error to name the %s class, got %v", testCase.rclass, diags)
+			}
+			if d.Id() != "foo" {
+				t.Fatalf("expected the repository to be kept in the state, got ID %q", d.Id())
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryWarnsOnKeySharedWithLocalRepository(t *testing.T) {
+	restyClient, _ := mockRepositories(t, map[string]string{})
+	hasWarning := func(diags diag.Diagnostics) bool {
+		for _, diagnostic := range diags {
+			if diagnostic.Severity == diag.Warning && diagnostic.Summary == "Repository key used by several repository types" {
+				return strings.Contains(diagnostic.Detail, "local generic repository and a virtual generic repository")
+			}
+		}
+		return false
+	}
+
+	localResource := local.ResourceArtifactoryLocalGenericRepository("generic")
+	localData := schema.TestResourceDataRaw(t, localResource.Schema, map[string]interface{}{"key": "shared"})
+	diags := localResource.CreateContext(context.Background(), localData, restyClient)
+	if diags.HasError() || hasWarning(diags) {
+		t.Fatalf("expected the first repository to be created without warning, got %v", diags)
+	}
+	if diags := localResource.ReadContext(context.Background(), localData, restyClient); hasWarning(diags) {
+		t.Fatalf("expected no warning when the same resource reads its key again, got %v", diags)
+	}
+
+	virtualResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	virtualData := schema.TestResourceDataRaw(t, virtualResource.Schema, map[string]interface{}{"key": "shared"})
+	diags = virtualResource.CreateContext(context.Background(), virtualData, restyClient)
+	if !hasWarning(diags) {
+		t.Fatalf("expected a warning about the shared key, got %v", diags)
+	}
+}
+
+func TestVirtualRepositoryKeyChangeWarning(t *testing.T) {
+	testCases := map[string]struct {
+		key              string
+		expectedWarnings []string
+	}{
+		"renamed":   {"bar", []string{"key changes from foo to bar", "the artifacts stored in foo-local are kept"}},
+		"unchanged": {"foo", nil},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, _ := mockRepositories(t, map[string]string{
+				"foo-local": `{"key":"foo-local","rclass":"local","packageType":"generic"}`,
+			})
+			// the provider root logger writes to os.Stderr, captured when the logger is created
+			logFile, err := os.CreateTemp(t.TempDir(), "tflog")
+			if err != nil {
+				t.Fatal(err)
+			}
+			stderr := os.Stderr
+			os.Stderr = logFile
+			ctx := tfsdklog.NewRootProviderLogger(context.Background())
+			os.Stderr = stderr
+
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			state := &terraform.InstanceState{ID: "foo", Attributes: map[string]string{
+				"id":                      "foo",
+				"key":                     "foo",
+				"repositories.#":          "1",
+				"repositories.0":          "foo-local",
+				"default_deployment_repo": "foo-local",
+			}}
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":                     testCase.key,
+				"repositories":            []interface{}{"foo-local"},
+				"default_deployment_repo": "foo-local",
+			})
+			planned, err := repoResource.Diff(ctx, state, config, restyClient)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if testCase.key != "foo" && !planned.RequiresNew() {
+				t.Fatalf("expected a replacement, got %v", planned)
+			}
+
+			logs, err := os.ReadFile(logFile.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var warnings []string
+			for _, line := range strings.Split(strings.TrimSpace(string(logs)), "\n") {
+				entry := map[string]interface{}{}
+				if line == "" || json.Unmarshal([]byte(line), &entry) != nil {
+					continue
+				}
+				if entry["@level"] == "warn" && strings.HasPrefix(fmt.Sprint(entry["@message"]), "key changes") {
+					warnings = append(warnings, entry["@message"].(string))
+				}
+			}
+			if testCase.expectedWarnings == nil {
+				if len(warnings) != 0 {
+					t.Fatalf("expected no warning, got %v", warnings)
+				}
+				return
+			}
+			if len(warnings) == 0 {
+				t.Fatalf("expected a key change warning, got:\n%s", logs)
+			}
+			for _, expected := range testCase.expectedWarnings {
+				if !strings.Contains(warnings[0], expected) {
+					t.Fatalf("expected the warning to contain %q, got %q", expected, warnings[0])
+				}
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryKeyAvailable(t *testing.T) {
+	testCases := map[string]struct {
+		config        map[string]interface{}
+		expectedError string
+	}{
+		"colliding local":       {map[string]interface{}{"key": "libs-local"}, "key libs-local is already used by the local repository libs-local"},
+		"colliding by casing":   {map[string]interface{}{"key": "Libs-Remote"}, "key Libs-Remote is already used by the remote repository libs-remote"},
+		"free key":              {map[string]interface{}{"key": "libs-virtual"}, ""},
+		"adopting the existing": {map[string]interface{}{"key": "libs-local", "adopt_existing": true}, ""},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			listings := 0
+			restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/artifactory/api/repositories" || r.URL.Query().Has("project") {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				listings++
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`[{"key":"libs-local","type":"LOCAL"},{"key":"libs-remote","type":"REMOTE"}]`))
+			}))
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			config := terraform.NewResourceConfigRaw(testCase.config)
+
+			_, err := repoResource.SimpleDiff(context.Background(), nil, config, restyClient)
+			if testCase.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+					t.Fatalf("expected error to contain %q, got %v", testCase.expectedError, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			expectedListings := 1
+			if testCase.config["adopt_existing"] == true {
+				expectedListings = 0
+			}
+			if listings != expectedListings {
+				t.Fatalf("expected %d listings, got %d", expectedListings, listings)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryKeyFreedByReplacement(t *testing.T) {
+	listing := `[{"key":"foo","type":"VIRTUAL"}]`
+	restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/artifactory/api/repositories" || r.URL.Query().Has("project") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(listing))
+	}))
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{"key": "foo"})
+
+	if _, err := repoResource.SimpleDiff(context.Background(), nil, config, restyClient); err == nil {
+		t.Fatal("expected the key of the existing repository to collide")
+	}
+
+	// a replacement deletes the repository, then plans its create again without a prior state
+	listing = `[]`
+	if _, err := repoResource.SimpleDiff(context.Background(), nil, config, restyClient); err != nil {
+		t.Fatalf("expected the key freed by the delete to be accepted, got %s", err)
+	}
+}
+
+func TestVirtualRepositoryRenamedKeyAvailable(t *testing.T) {
+	restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/artifactory/api/repositories" && !r.URL.Query().Has("project") {
+			_, _ = w.Write([]byte(`[{"key":"foo","type":"VIRTUAL"},{"key":"libs-local","type":"LOCAL"}]`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	state := &terraform.InstanceState{ID: "foo", Attributes: map[string]string{"id": "foo", "key": "foo"}}
+
+	if _, err := repoResource.SimpleDiff(context.Background(), state, terraform.NewResourceConfigRaw(map[string]interface{}{"key": "foo", "description": "changed"}), restyClient); err != nil {
+		t.Fatalf("expected the key of the repository itself to be accepted, got %s", err)
+	}
+	_, err := repoResource.SimpleDiff(context.Background(), state, terraform.NewResourceConfigRaw(map[string]interface{}{"key": "libs-local"}), restyClient)
+	if err == nil || !strings.Contains(err.Error(), "key libs-local is already used by the local repository libs-local") {
+		t.Fatalf("expected the renamed key to collide, got %v", err)
+	}
+}
@@ -0,0 +1,659 @@
+package virtual_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+// mkVirtualMembersOfOwnTypeTestCase creates, updates and deletes a virtual repository of repoType aggregating a remote
+// repository of the same type, then checks that a generic member fails the plan
+func mkVirtualMembersOfOwnTypeTestCase(repoType string, t *testing.T) (*testing.T, resource.TestCase) {
+	_, fqrn, name := acctest.MkNames(fmt.Sprintf("virtual-%s-repo", repoType), fmt.Sprintf("artifactory_virtual_%s_repository", repoType))
+	_, _, remoteName := acctest.MkNames(fmt.Sprintf("%s-remote", repoType), fmt.Sprintf("artifactory_remote_%s_repository", repoType))
+	_, _, genericName := acctest.MkNames("generic-local", "artifactory_local_generic_repository")
+	const template = `
+		resource "artifactory_remote_{{ .repoType }}_repository" "{{ .remoteName }}" {
+		  key = "{{ .remoteName }}"
+		  url = "http://tempurl.org"
+		}
+
+		resource "artifactory_local_generic_repository" "{{ .genericName }}" {
+		  key = "{{ .genericName }}"
+		}
+
+		resource "artifactory_virtual_{{ .repoType }}_repository" "{{ .name }}" {
+		  key          = "{{ .name }}"
+		  description  = "{{ .description }}"
+		  repositories = [{{ range .members }}{{ . }}.key, {{ end }}]
+		}
+	`
+	config := func(description string, members ...string) string {
+		return acctest.ExecuteTemplate(name, template, map[string]interface{}{
+			"repoType":    repoType,
+			"name":        name,
+			"remoteName":  remoteName,
+			"genericName": genericName,
+			"description": description,
+			"members":     members,
+		})
+	}
+	remoteMember := fmt.Sprintf("artifactory_remote_%s_repository.%s", repoType, remoteName)
+	genericMember := fmt.Sprintf("artifactory_local_generic_repository.%s", genericName)
+
+	return t, resource.TestCase{
+		PreCheck:          func() { acctest.PreCheck(t) },
+		ProviderFactories: acctest.ProviderFactories,
+		CheckDestroy:      acctest.VerifyDeleted(fqrn, acctest.CheckRepo),
+		Steps: []resource.TestStep{
+			{
+				Config: config("before", remoteMember),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(fqrn, "package_type", repoType),
+					resource.TestCheckResourceAttr(fqrn, "description", "before"),
+					resource.TestCheckResourceAttr(fqrn, "repositories.#", "1"),
+					resource.TestCheckResourceAttr(fqrn, "repositories.0", remoteName),
+				),
+			},
+			{
+				Config: config("after", remoteMember),
+				Check:  resource.TestCheckResourceAttr(fqrn, "description", "after"),
+			},
+			{
+				Config:      config("after", remoteMember, genericMember),
+				ExpectError: regexp.MustCompile(fmt.Sprintf("can only aggregate %s repositories", repoType)),
+			},
+		},
+	}
+}
+
+func TestVirtualRepositoryMembersOfOwnType(t *testing.T) {
+	restyClient, _ := mockRepositories(t, map[string]string{
+		"puppet-remote": `{"key":"puppet-remote","rclass":"remote","packageType":"puppet"}`,
+		"pub-remote":    `{"key":"pub-remote","rclass":"remote","packageType":"pub"}`,
+		"generic-local": `{"key":"generic-local","rclass":"local","packageType":"generic"}`,
+		"gitlfs-local":  `{"key":"gitlfs-local","rclass":"local","packageType":"gitlfs"}`,
+	})
+
+	testCases := map[string]struct {
+		repoResource *schema.Resource
+		members      []interface{}
+		err          string
+	}{
+		"puppet":         {virtual.ResourceArtifactoryVirtualPuppetRepository(), []interface{}{"puppet-remote"}, ""},
+		"puppet generic": {virtual.ResourceArtifactoryVirtualPuppetRepository(), []interface{}{"puppet-remote", "generic-local"}, "can only aggregate puppet repositories, these members are not: generic-local (generic)"},
+		"pub":            {virtual.ResourceArtifactoryVirtualPubRepository(), []interface{}{"pub-remote"}, ""},
+		"pub puppet":     {virtual.ResourceArtifactoryVirtualPubRepository(), []interface{}{"pub-remote", "puppet-remote"}, "can only aggregate pub repositories, these members are not: puppet-remote (puppet)"},
+		"gitlfs":         {virtual.ResourceArtifactoryVirtualGitlfsRepository(), []interface{}{"gitlfs-local"}, ""},
+		"gitlfs generic": {virtual.ResourceArtifactoryVirtualGitlfsRepository(), []interface{}{"gitlfs-local", "generic-local"}, "can only aggregate gitlfs repositories, these members are not: generic-local (generic)"},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":          "foo",
+				"repositories": testCase.members,
+			})
+			_, err := testCase.repoResource.Diff(context.Background(), nil, config, restyClient)
+			if testCase.err == "" && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if testCase.err != "" && (err == nil || !strings.Contains(err.Error(), testCase.err)) {
+				t.Fatalf("expected error containing %q, got %v", testCase.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateMembersPackageType(t *testing.T) {
+	lookups := map[string]int{}
+	restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/"+repository.RepositoriesEndpoint)
+		lookups[key]++
+		packageTypes := map[string]string{"maven-local": "maven", "gradle-remote": "gradle", "npm-remote": "npm", "generic-local": "generic"}
+		packageType, ok := packageTypes[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"key":%q,"rclass":"local","packageType":%q}`, key, packageType)
+	}))
+
+	members := []string{"maven-local", "npm-remote", "gradle-remote", "missing", "generic-local"}
+	diags := repository.ValidateMembersPackageType(context.Background(), restyClient, members, "maven")
+	if len(diags) != 1 || !diags.HasError() {
+		t.Fatalf("expected one error, got %v", diags)
+	}
+	if expected := "a maven virtual repository can only aggregate maven repositories, these members are not: npm-remote (npm), generic-local (generic)"; diags[0].Detail != expected {
+		t.Fatalf("expected %q, got %q", expected, diags[0].Detail)
+	}
+
+	if diags := repository.ValidateMembersPackageType(context.Background(), restyClient, []string{"maven-local", "gradle-remote"}, "gradle"); diags != nil {
+		t.Fatalf("expected no diagnostics, got %v", diags)
+	}
+	if diags := repository.ValidateMembersPackageType(context.Background(), restyClient, []string{"generic-local"}, "p2"); diags != nil {
+		t.Fatalf("expected no diagnostics for a generic member of a p2 repository, got %v", diags)
+	}
+
+	for _, member := range []string{"maven-local", "gradle-remote", "generic-local"} {
+		if lookups[member] != 1 {
+			t.Errorf("expected %s to be looked up once, got %d", member, lookups[member])
+		}
+	}
+	if lookups["missing"] != 1 {
+		t.Errorf("expected missing to be looked up once, got %d", lookups["missing"])
+	}
+}
+
+func TestVirtualRepositoryNullRepositories(t *testing.T) {
+	resourceSchema := virtual.ResourceArtifactoryVirtualGenericRepository("generic").Schema
+	packer := repository.DefaultPacker(resourceSchema)
+
+	for name, body := range map[string]string{
+		"null":    `{"key":"foo","rclass":"virtual","packageType":"generic","repositories":null}`,
+		"empty":   `{"key":"foo","rclass":"virtual","packageType":"generic","repositories":[]}`,
+		"missing": `{"key":"foo","rclass":"virtual","packageType":"generic"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			repo := virtual.VirtualRepositoryBaseParams{}
+			if err := json.Unmarshal([]byte(body), &repo); err != nil {
+				t.Fatalf("failed to decode %s: %s", body, err)
+			}
+			if len(repo.Repositories) != 0 {
+				t.Fatalf("expected no repositories, got %v", repo.Repositories)
+			}
+
+			d := schema.TestResourceDataRaw(t, resourceSchema, map[string]interface{}{})
+			if err := packer(&repo, d); err != nil {
+				t.Fatalf("failed to pack: %s", err)
+			}
+			repositories, ok := d.Get("repositories").([]interface{})
+			if !ok || len(repositories) != 0 {
+				t.Fatalf("expected an empty repositories list, got %#v", d.Get("repositories"))
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryPruneOfflineMembers(t *testing.T) {
+	members := []interface{}{"local-a", "remote-offline", "remote-online"}
+
+	for _, prune := range []bool{true, false} {
+		t.Run(fmt.Sprintf("prune=%t", prune), func(t *testing.T) {
+			restyClient, sent := mockRepositories(t, map[string]string{
+				"foo":            `{"key":"foo","rclass":"virtual","packageType":"generic"}`,
+				"local-a":        `{"key":"local-a","rclass":"local","packageType":"generic"}`,
+				"remote-offline": `{"key":"remote-offline","rclass":"remote","packageType":"generic","offline":true}`,
+				"remote-online":  `{"key":"remote-online","rclass":"remote","packageType":"generic"}`,
+			})
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+				"key":                            "foo",
+				"repositories":                   members,
+				"prune_offline_members_on_apply": prune,
+			})
+			d.SetId("foo")
+
+			diags := repoResource.UpdateContext(context.Background(), d, restyClient)
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			expected := members
+			if prune {
+				expected = []interface{}{"local-a", "remote-online"}
+				if len(diags) != 1 || diags[0].Severity != diag.Warning || !strings.Contains(diags[0].Detail, "remote-offline") {
+					t.Fatalf("expected a warning naming remote-offline, got %v", diags)
+				}
+			} else if len(diags) != 0 {
+				t.Fatalf("expected no diagnostics, got %v", diags)
+			}
+
+			if !reflect.DeepEqual(sent["foo"]["repositories"], expected) {
+				t.Fatalf("expected repositories %v to be sent, got %v", expected, sent["foo"]["repositories"])
+			}
+			if got := d.Get("repositories"); !reflect.DeepEqual(got, members) {
+				t.Fatalf("expected the configured members in the state, got %v", got)
+			}
+
+			// the refresh reads the pruned members, the state keeps matching the configuration
+			if diags := repoResource.ReadContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if got := d.Get("repositories"); !reflect.DeepEqual(got, members) {
+				t.Fatalf("expected the configured members after the refresh, got %v", got)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryBestEffortMembers(t *testing.T) {
+	members := []interface{}{"local-a", "remote-removed", "remote-b"}
+
+	for _, bestEffort := range []bool{true, false} {
+		t.Run(fmt.Sprintf("best_effort_members=%t", bestEffort), func(t *testing.T) {
+			repos := map[string]string{
+				"foo":      `{"key":"foo","rclass":"virtual","packageType":"generic","repositories":["local-a"]}`,
+				"local-a":  `{"key":"local-a","rclass":"local","packageType":"generic"}`,
+				"remote-b": `{"key":"remote-b","rclass":"remote","packageType":"generic"}`,
+			}
+			var updates [][]interface{}
+			restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				key := strings.TrimPrefix(r.URL.Path, "/"+repository.RepositoriesEndpoint)
+				switch r.Method {
+				case http.MethodPut, http.MethodPost:
+					body := map[string]interface{}{}
+					if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+						t.Errorf("failed to decode request body: %s", err)
+					}
+					sentMembers, _ := body["repositories"].([]interface{})
+					updates = append(updates, sentMembers)
+					for _, member := range sentMembers {
+						if _, ok := repos[member.(string)]; !ok {
+							w.Header().Set("Content-Type", "application/json")
+							w.WriteHeader(http.StatusBadRequest)
+							_, _ = fmt.Fprintf(w, `{"errors":[{"status":400,"message":"Could not find repository '%s'"}]}`, member)
+							return
+						}
+					}
+					encoded, _ := json.Marshal(body)
+					repos[key] = string(encoded)
+				case http.MethodGet:
+					repo, ok := repos[key]
+					if !ok {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					w.Header().Set("Content-Type", "application/json")
+					_, _ = w.Write([]byte(repo))
+				}
+			}))
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+				"key":                 "foo",
+				"repositories":        members,
+				"best_effort_members": bestEffort,
+			})
+			d.SetId("foo")
+
+			diags := repoResource.UpdateContext(context.Background(), d, restyClient)
+
+			if !bestEffort {
+				if !diags.HasError() {
+					t.Fatalf("expected the update to fail, got %v", diags)
+				}
+				if len(updates) != 1 {
+					t.Fatalf("expected a single update, got %v", updates)
+				}
+				if !strings.Contains(repos["foo"], `"repositories":["local-a"]`) {
+					t.Fatalf("expected the repository to be unchanged, got %s", repos["foo"])
+				}
+				return
+			}
+
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if len(diags) != 1 || diags[0].Severity != diag.Warning || !strings.HasSuffix(diags[0].Detail, "which don't exist: remote-removed") {
+				t.Fatalf("expected a warning naming remote-removed, got %v", diags)
+			}
+			expected := [][]interface{}{members, {"local-a", "remote-b"}}
+			if !reflect.DeepEqual(updates, expected) {
+				t.Fatalf("expected updates %v, got %v", expected, updates)
+			}
+			if got := d.Get("repositories"); !reflect.DeepEqual(got, members) {
+				t.Fatalf("expected the configured members in the state, got %v", got)
+			}
+
+			// the refresh reads the kept members, the state keeps matching the configuration
+			if diags := repoResource.ReadContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if got := d.Get("repositories"); !reflect.DeepEqual(got, members) {
+				t.Fatalf("expected the configured members after the refresh, got %v", got)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositorySelfReference(t *testing.T) {
+	testCases := map[string]struct {
+		repositories  []interface{}
+		expectedError bool
+	}{
+		"self reference": {[]interface{}{"foo-local", "foo-virtual"}, true},
+		"other members":  {[]interface{}{"foo-local", "foo-remote"}, false},
+		"no members":     {[]interface{}{}, false},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":          "foo-virtual",
+				"repositories": testCase.repositories,
+			})
+
+			_, err := repoResource.Diff(context.Background(), nil, config, acctest.NewMockClient(t, http.NotFoundHandler()))
+			if testCase.expectedError && (err == nil || !strings.Contains(err.Error(), "a virtual repository cannot include itself")) {
+				t.Fatalf("expected a self reference error, got %v", err)
+			}
+			if !testCase.expectedError && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryDuplicateMembers(t *testing.T) {
+	testCases := map[string]struct {
+		repositories  []interface{}
+		expectedError string
+	}{
+		"duplicated member": {[]interface{}{"foo-local", "foo-remote", "foo-local"}, "repositories lists foo-local more than once, at index 0 and 2"},
+		"clean list":        {[]interface{}{"foo-local", "foo-remote"}, ""},
+		"no members":        {[]interface{}{}, ""},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":          "foo-virtual",
+				"repositories": testCase.repositories,
+			})
+
+			_, err := repoResource.Diff(context.Background(), nil, config, acctest.NewMockClient(t, http.NotFoundHandler()))
+			if testCase.expectedError != "" && (err == nil || !strings.Contains(err.Error(), testCase.expectedError)) {
+				t.Fatalf("expected error %q, got %v", testCase.expectedError, err)
+			}
+			if testCase.expectedError == "" && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryWarnsOnEmptyMembers(t *testing.T) {
+	testCases := map[string]struct {
+		packageType     string
+		repositories    []interface{}
+		expectedWarning bool
+	}{
+		"docker without members":  {"docker", []interface{}{}, true},
+		"docker with members":     {"docker", []interface{}{"docker-local"}, false},
+		"generic without members": {"generic", []interface{}{}, false},
+		"pypi without members":    {"pypi", []interface{}{}, true},
+		"pypi with members":       {"pypi", []interface{}{"docker-local"}, false},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, _ := mockRepositories(t, map[string]string{
+				"docker-local": `{"key":"docker-local","rclass":"local","packageType":"docker"}`,
+			})
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository(testCase.packageType)
+			switch testCase.packageType {
+			case "docker":
+				repoResource = virtual.ResourceArtifactoryVirtualDockerRepository()
+			case "pypi":
+				repoResource = virtual.ResourceArtifactoryVirtualPypiRepository()
+			}
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+				"key":          "foo-virtual",
+				"repositories": testCase.repositories,
+			})
+
+			diags := repoResource.CreateContext(context.Background(), d, restyClient)
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			warned := len(diags) == 1 && diags[0].Severity == diag.Warning && diags[0].Summary == "Virtual repository has no members"
+			if warned != testCase.expectedWarning {
+				t.Fatalf("expected warning to be %t, got %v", testCase.expectedWarning, diags)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryWarnsOnVirtualMembersBeforeLocals(t *testing.T) {
+	testCases := map[string]struct {
+		repositories    []interface{}
+		expectedWarning bool
+	}{
+		"virtual before local": {[]interface{}{"nested-virtual", "foo-remote", "foo-local"}, true},
+		"local before virtual": {[]interface{}{"foo-local", "nested-virtual", "foo-remote"}, false},
+		"no virtual members":   {[]interface{}{"foo-remote", "foo-local"}, false},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, _ := mockRepositories(t, map[string]string{
+				"nested-virtual": `{"key":"nested-virtual","rclass":"virtual","packageType":"generic"}`,
+				"foo-local":      `{"key":"foo-local","rclass":"local","packageType":"generic"}`,
+				"foo-remote":     `{"key":"foo-remote","rclass":"remote","packageType":"generic"}`,
+			})
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+				"key":          "foo-virtual",
+				"repositories": testCase.repositories,
+			})
+
+			diags := repoResource.CreateContext(context.Background(), d, restyClient)
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			warned := len(diags) == 1 && diags[0].Severity == diag.Warning && strings.Contains(diags[0].Detail, "nested-virtual")
+			if warned != testCase.expectedWarning {
+				t.Fatalf("expected warning to be %t, got %v", testCase.expectedWarning, diags)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryWarnsOnIncompatibleMembersOnRead(t *testing.T) {
+	repos := map[string]string{
+		"foo-virtual": `{"key":"foo-virtual","rclass":"virtual","packageType":"maven","repositories":["foo-local","foo-gradle","foo-changed"]}`,
+		"foo-local":   `{"key":"foo-local","rclass":"local","packageType":"maven"}`,
+		"foo-gradle":  `{"key":"foo-gradle","rclass":"local","packageType":"gradle"}`,
+		"foo-changed": `{"key":"foo-changed","rclass":"local","packageType":"maven"}`,
+	}
+	restyClient, _ := mockRepositories(t, repos)
+	repoResource := virtual.ResourceArtifactoryVirtualJavaRepository("maven")
+
+	read := func() diag.Diagnostics {
+		d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": "foo-virtual"})
+		d.SetId("foo-virtual")
+		diags := repoResource.ReadContext(context.Background(), d, restyClient)
+		if diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+		return diags
+	}
+
+	if diags := read(); len(diags) != 0 {
+		t.Fatalf("expected no diagnostics for maven and gradle members, got %v", diags)
+	}
+
+	// the member is recreated as npm out-of-band, a new provider run sees it on its first lookup
+	restyClient, _ = mockRepositories(t, repos)
+	repos["foo-changed"] = `{"key":"foo-changed","rclass":"local","packageType":"npm"}`
+	diags := read()
+	if len(diags) != 1 || diags[0].Severity != diag.Warning || !strings.Contains(diags[0].Detail, "foo-changed (npm)") {
+		t.Fatalf("expected a warning naming foo-changed, got %v", diags)
+	}
+}
+
+func TestVirtualRepositoryUpdateClearsMembers(t *testing.T) {
+	restyClient, sent := mockRepositories(t, map[string]string{
+		"foo": `{"key":"foo","rclass":"virtual","packageType":"generic","repositories":["local-a","local-b"]}`,
+	})
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+		"key":          "foo",
+		"repositories": []interface{}{},
+	})
+	d.SetId("foo")
+
+	if diags := repoResource.UpdateContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	members, ok := sent["foo"]["repositories"]
+	if !ok || !reflect.DeepEqual(members, []interface{}{}) {
+		t.Fatalf("expected an empty repositories list to be sent, got %v (sent: %t)", members, ok)
+	}
+	if repositories := d.Get("repositories").([]interface{}); len(repositories) != 0 {
+		t.Fatalf("expected no members after the update, got %v", repositories)
+	}
+}
+
+func TestVirtualRepositoryMaxMemberRepositories(t *testing.T) {
+	const limit = 3
+	testCases := map[string]struct {
+		members       int
+		expectedError bool
+	}{
+		"below the limit": {limit - 1, false},
+		"at the limit":    {limit, false},
+		"above the limit": {limit + 1, true},
+	}
+
+	restyClient := resty.New()
+	repository.SetProviderSettings(restyClient, repository.ProviderSettings{MaxMemberRepositories: limit})
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			members := make([]interface{}, testCase.members)
+			for i := range members {
+				members[i] = fmt.Sprintf("member-%d", i)
+			}
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":          "foo-virtual",
+				"repositories": members,
+			})
+
+			_, err := repoResource.Diff(context.Background(), nil, config, restyClient)
+			if testCase.expectedError && (err == nil || !strings.Contains(err.Error(), fmt.Sprintf("%d members, the provider allows at most %d", testCase.members, limit))) {
+				t.Fatalf("expected an error naming the count and limit, got %v", err)
+			}
+			if !testCase.expectedError && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryMemberRepositoriesSet(t *testing.T) {
+	restyClient, sent := mockRepositories(t, map[string]string{
+		"a-local": `{"key":"a-local","rclass":"local","packageType":"generic"}`,
+		"b-local": `{"key":"b-local","rclass":"local","packageType":"generic"}`,
+		"c-local": `{"key":"c-local","rclass":"local","packageType":"generic"}`,
+	})
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	config := map[string]interface{}{
+		"key":                 "foo",
+		"member_repositories": []interface{}{"c-local", "a-local", "b-local"},
+	}
+
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, config)
+	if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	expected := []interface{}{"a-local", "b-local", "c-local"}
+	if !reflect.DeepEqual(sent["foo"]["repositories"], expected) {
+		t.Fatalf("expected the members to be sent sorted as %v, got %v", expected, sent["foo"]["repositories"])
+	}
+	if members := d.Get("member_repositories").(*schema.Set).Len(); members != 3 {
+		t.Fatalf("expected the 3 members to be read back in member_repositories, got %d", members)
+	}
+
+	// the members stored in repositories by the read, and the order of the set, don't plan a change
+	config["member_repositories"] = []interface{}{"b-local", "c-local", "a-local"}
+	planned, err := repoResource.Diff(context.Background(), d.State(), terraform.NewResourceConfigRaw(config), restyClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if planned != nil && len(planned.Attributes) > 0 {
+		t.Fatalf("expected no changes after the create, got %v", planned.Attributes)
+	}
+
+	diags := repoResource.Validate(terraform.NewResourceConfigRaw(map[string]interface{}{
+		"key":                 "foo",
+		"repositories":        []interface{}{"a-local"},
+		"member_repositories": []interface{}{"b-local"},
+	}))
+	if !diags.HasError() || !strings.Contains(fmt.Sprint(diags), "conflicts with") {
+		t.Fatalf("expected repositories and member_repositories to conflict, got %v", diags)
+	}
+}
+
+func TestVirtualRepositoryWarnsOnUncachedMembers(t *testing.T) {
+	threshold := 2
+	disabled := 0
+	testCases := map[string]struct {
+		repoResource *schema.Resource
+		packageType  string
+		members      int
+		period       int
+		threshold    *int
+		expectedWarn bool
+	}{
+		"above threshold":         {virtual.ResourceArtifactoryVirtualNpmRepository(), "npm", 6, 0, nil, true},
+		"at threshold":            {virtual.ResourceArtifactoryVirtualNpmRepository(), "npm", 5, 0, nil, false},
+		"caching enabled":         {virtual.ResourceArtifactoryVirtualNpmRepository(), "npm", 6, 600, nil, false},
+		"provider threshold":      {virtual.ResourceArtifactoryVirtualNpmRepository(), "npm", 3, 0, &threshold, true},
+		"warning disabled":        {virtual.ResourceArtifactoryVirtualNpmRepository(), "npm", 6, 0, &disabled, false},
+		"generic like with cache": {virtual.ResourceArtifactoryVirtualRepositoryWithRetrievalCachePeriodSecs("conda"), "conda", 6, 0, nil, true},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			repos := map[string]string{}
+			var members []interface{}
+			for i := 0; i < testCase.members; i++ {
+				member := fmt.Sprintf("member-%d", i)
+				repos[member] = fmt.Sprintf(`{"key":%q,"rclass":"remote","packageType":%q}`, member, testCase.packageType)
+				members = append(members, member)
+			}
+			restyClient, _ := mockRepositories(t, repos)
+			repository.SetProviderSettings(restyClient, repository.ProviderSettings{UncachedMembersWarningThreshold: testCase.threshold})
+
+			d, _ := planWithRawConfig(t, testCase.repoResource, nil, map[string]interface{}{
+				"key":                            "foo",
+				"repositories":                   members,
+				"retrieval_cache_period_seconds": testCase.period,
+			}, restyClient)
+			diags := testCase.repoResource.CreateContext(context.Background(), d, restyClient)
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			warned := false
+			for _, diagnostic := range diags {
+				if diagnostic.Severity == diag.Warning && diagnostic.Summary == "Metadata caching disabled for many members" {
+					warned = strings.Contains(diagnostic.Detail, fmt.Sprintf("its %d members", testCase.members))
+				}
+			}
+			if warned != testCase.expectedWarn {
+				t.Fatalf("expected a warning: %t, got %v", testCase.expectedWarn, diags)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package virtual_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+	"github.com/jfrog/terraform-provider-shared/test"
+)
+
+func TestAccVirtualRepositoryPackageTypeKnownAtPlan(t *testing.T) {
+	id := test.RandomInt()
+	npmName := fmt.Sprintf("npm%d", id)
+	genericName := fmt.Sprintf("generic%d", id)
+	npmFqrn := fmt.Sprintf("artifactory_virtual_npm_repository.%s", npmName)
+	genericFqrn := fmt.Sprintf("artifactory_virtual_generic_repository.%s.0", genericName)
+	// count must be known at plan time, the plan fails if package_type is only known after the apply
+	const virtualRepositoryPackageTypeCount = `
+		resource "artifactory_virtual_npm_repository" "%[1]s" {
+			key = "%[1]s"
+		}
+
+		resource "artifactory_virtual_generic_repository" "%[2]s" {
+			count       = artifactory_virtual_npm_repository.%[1]s.package_type == "npm" ? 1 : 0
+			key         = "%[2]s"
+			description = "companion of a ${artifactory_virtual_npm_repository.%[1]s.package_type} repository"
+		}
+	`
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { acctest.PreCheck(t) },
+		ProviderFactories: acctest.ProviderFactories,
+		CheckDestroy:      acctest.VerifyDeleted(npmFqrn, acctest.CheckRepo),
+
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(virtualRepositoryPackageTypeCount, npmName, genericName),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(npmFqrn, "package_type", "npm"),
+					resource.TestCheckResourceAttr(genericFqrn, "package_type", "generic"),
+					resource.TestCheckResourceAttr(genericFqrn, "description", "companion of a npm repository"),
+				),
+			},
+		},
+	})
+}
+
+func TestVirtualRepositoryPackageTypeKnownAtPlan(t *testing.T) {
+	testCases := map[string]*schema.Resource{
+		"generic": virtual.ResourceArtifactoryVirtualGenericRepository("generic"),
+		"npm":     virtual.ResourceArtifactoryVirtualNpmRepository(),
+	}
+
+	for packageType, repoResource := range testCases {
+		t.Run(packageType, func(t *testing.T) {
+			planned, err := repoResource.SimpleDiff(context.Background(), nil, terraform.NewResourceConfigRaw(map[string]interface{}{"key": "foo"}), acctest.NewMockClient(t, http.NotFoundHandler()))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			attribute := planned.Attributes["package_type"]
+			if attribute == nil || attribute.NewComputed || attribute.New != packageType {
+				t.Fatalf("expected package_type to be planned as %s, got %+v", packageType, attribute)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryPackageTypeChange(t *testing.T) {
+	restyClient, _ := mockRepositories(t, map[string]string{
+		"foo-pypi": `{"key":"foo-pypi","rclass":"virtual","packageType":"npm"}`,
+	})
+	repoResource := virtual.ResourceArtifactoryVirtualPypiRepository()
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": "foo-pypi"})
+	d.SetId("foo-pypi")
+
+	diags := repoResource.ReadContext(context.Background(), d, restyClient)
+	if diags.HasError() || len(diags) != 1 || diags[0].Summary != "Repository will be destroyed and recreated" {
+		t.Fatalf("expected a recreate warning, got %v", diags)
+	}
+
+	state := d.State()
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{"key": "foo-pypi"})
+	instanceDiff, err := repoResource.Diff(context.Background(), state, config, restyClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// the replacement recomputes package_type, set on create from the resource type
+	if instanceDiff == nil || instanceDiff.Attributes["package_type"] == nil || !instanceDiff.Attributes["package_type"].RequiresNew {
+		t.Fatalf("expected the package type change to require a replacement, got %v", instanceDiff)
+	}
+}
+
+func TestVirtualRepositoryPackageTypeUnchanged(t *testing.T) {
+	restyClient, _ := mockRepositories(t, map[string]string{
+		"foo-pypi": `{"key":"foo-pypi","rclass":"virtual","packageType":"pypi"}`,
+	})
+	repoResource := virtual.ResourceArtifactoryVirtualPypiRepository()
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": "foo-pypi"})
+	d.SetId("foo-pypi")
+
+	if diags := repoResource.ReadContext(context.Background(), d, restyClient); len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %v", diags)
+	}
+	instanceDiff, err := repoResource.Diff(context.Background(), d.State(), terraform.NewResourceConfigRaw(map[string]interface{}{"key": "foo-pypi"}), restyClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if instanceDiff != nil && instanceDiff.RequiresNew() {
+		t.Fatalf("expected no replacement, got %v", instanceDiff)
+	}
+}
+
+func TestVirtualRepositoryPackageTypeCase(t *testing.T) {
+	restyClient, _ := mockRepositories(t, map[string]string{
+		"foo-maven": `{"key":"foo-maven","rclass":"virtual","packageType":"Maven","includesPattern":"**/*","repoLayoutRef":"maven-2-default"}`,
+	})
+	repoResource := virtual.ResourceArtifactoryVirtualJavaRepository("maven")
+	config := map[string]interface{}{"key": "foo-maven"}
+
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, config)
+	d.SetId("foo-maven")
+	diags := repoResource.ReadContext(context.Background(), d, restyClient)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if len(diags) != 0 {
+		t.Fatalf("expected no warning, got %v", diags)
+	}
+	if packageType := d.Get("package_type"); packageType != "maven" {
+		t.Fatalf("expected the package type to be read as maven, got %v", packageType)
+	}
+
+	planned, err := repoResource.Diff(context.Background(), d.State(), terraform.NewResourceConfigRaw(config), restyClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if planned != nil && !planned.Empty() {
+		t.Fatalf("expected a clean plan, got %v", planned)
+	}
+}
+
+func TestPackageTypesAreLowercase(t *testing.T) {
+	lists := map[string][]string{
+		"RepoTypesSupported":                                      repository.RepoTypesSupported,
+		"GradleLikeRepoTypes":                                     repository.GradleLikeRepoTypes,
+		"VirtualRepoTypesLikeGeneric":                             virtual.VirtualRepoTypesLikeGeneric,
+		"VirtualRepoTypesLikeGenericWithRetrievalCachePeriodSecs": virtual.VirtualRepoTypesLikeGenericWithRetrievalCachePeriodSecs,
+	}
+	for name, packageTypes := range lists {
+		for _, packageType := range packageTypes {
+			if packageType != strings.ToLower(packageType) {
+				t.Errorf("the package type %s of %s isn't lowercase", packageType, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package virtual_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+func TestValidatePatternList(t *testing.T) {
+	testCases := map[string]bool{
+		"":                     true,
+		"**/*":                 true,
+		"com/acme/**,org/**":   true,
+		"com/acme/**, org/**":  true,
+		"com/acme/**,":         false,
+		",com/acme/**":         false,
+		"com/acme/**,,org/**":  false,
+		"com/acme/**, ,org/**": false,
+	}
+
+	for patterns, valid := range testCases {
+		t.Run(patterns, func(t *testing.T) {
+			diags := virtual.ValidatePatternList(patterns, cty.GetAttrPath("includes_pattern"))
+			if valid && diags.HasError() {
+				t.Fatalf("expected %q to be valid, got %v", patterns, diags)
+			}
+			if !valid && !diags.HasError() {
+				t.Fatalf("expected %q to be rejected", patterns)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryPatternsLength(t *testing.T) {
+	const limit = 64
+	half := strings.Repeat("a", limit/2)
+	testCases := map[string]struct {
+		includes      string
+		excludes      string
+		limit         int
+		expectedError bool
+	}{
+		"at the limit":   {half, half, limit, false},
+		"over the limit": {half, half + "a", limit, true},
+		"includes only":  {strings.Repeat("a", limit+1), "", limit, true},
+		// characters are counted, not bytes
+		"multibyte at the limit": {half, strings.Repeat("é", limit/2), limit, false},
+		"default patterns":       {"**/*", "", limit, false},
+		"no limit":               {strings.Repeat("a", 4096), strings.Repeat("a", 4096), 0, false},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient := acctest.NewMockClient(t, http.NotFoundHandler())
+			repository.SetProviderSettings(restyClient, repository.ProviderSettings{MaxPatternsLength: testCase.limit})
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":              "foo-virtual",
+				"includes_pattern": testCase.includes,
+				"excludes_pattern": testCase.excludes,
+			})
+
+			_, err := repoResource.Diff(context.Background(), nil, config, restyClient)
+			if testCase.expectedError && (err == nil || !strings.Contains(err.Error(), fmt.Sprintf("the provider allows at most %d (max_patterns_length)", limit))) {
+				t.Fatalf("expected a pattern length error, got %v", err)
+			}
+			if !testCase.expectedError && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryEffectivePatterns(t *testing.T) {
+	const configured = "com/acme/** , org/acme/**"
+	restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			return
+		}
+		// Artifactory stores the patterns without the whitespace around the commas
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"key":"foo","rclass":"virtual","packageType":"generic","includesPattern":"com/acme/**,org/acme/**","excludesPattern":"com/acme/internal/**"}`))
+	}))
+
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+		"key":              "foo",
+		"includes_pattern": configured,
+		"excludes_pattern": "com/acme/internal/**",
+	})
+	if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if effective := d.Get("effective_includes_pattern"); effective != "com/acme/**,org/acme/**" || effective == configured {
+		t.Fatalf("expected the normalized include patterns, got %q", effective)
+	}
+	if effective := d.Get("effective_excludes_pattern"); effective != "com/acme/internal/**" {
+		t.Fatalf("expected the stored exclude patterns, got %q", effective)
+	}
+}
+
+func TestVirtualRepositoryPatternsLists(t *testing.T) {
+	testCases := map[string]struct {
+		config           map[string]interface{}
+		expectedIncludes string
+		expectedExcludes interface{}
+	}{
+		"list form": {
+			config: map[string]interface{}{
+				"includes_patterns": []interface{}{"com/acme/**", "org/acme/**"},
+				"excludes_patterns": []interface{}{"com/acme/internal/**"},
+			},
+			expectedIncludes: "com/acme/**,org/acme/**",
+			expectedExcludes: "com/acme/internal/**",
+		},
+		"string form": {
+			config: map[string]interface{}{
+				"includes_pattern": "com/acme/**,org/acme/**",
+			},
+			expectedIncludes: "com/acme/**,org/acme/**",
+		},
+		"defaults": {
+			config:           map[string]interface{}{},
+			expectedIncludes: "**/*",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, sent := mockRepositories(t, map[string]string{})
+			testCase.config["key"] = "foo"
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, testCase.config)
+
+			if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if sent["foo"]["includesPattern"] != testCase.expectedIncludes || sent["foo"]["excludesPattern"] != testCase.expectedExcludes {
+				t.Fatalf("expected patterns %q and %v to be sent, got %v and %v", testCase.expectedIncludes, testCase.expectedExcludes, sent["foo"]["includesPattern"], sent["foo"]["excludesPattern"])
+			}
+			if includes, ok := testCase.config["includes_patterns"]; ok && !reflect.DeepEqual(d.Get("includes_patterns"), includes) {
+				t.Fatalf("expected the list form to be read back as %v, got %v", includes, d.Get("includes_patterns"))
+			}
+
+			// the string form stored by the read doesn't plan a change back to its default
+			planned, err := repoResource.Diff(context.Background(), d.State(), terraform.NewResourceConfigRaw(testCase.config), restyClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if planned != nil && len(planned.Attributes) > 0 {
+				t.Fatalf("expected no changes after the create, got %v", planned.Attributes)
+			}
+		})
+	}
+
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	diags := repoResource.Validate(terraform.NewResourceConfigRaw(map[string]interface{}{
+		"key":               "foo",
+		"includes_pattern":  "com/acme/**",
+		"includes_patterns": []interface{}{"org/acme/**"},
+	}))
+	if !diags.HasError() || !strings.Contains(fmt.Sprint(diags), "conflicts with") {
+		t.Fatalf("expected includes_pattern and includes_patterns to conflict, got %v", diags)
+	}
+}
+
+func TestVirtualRepositoryNormalizesPatterns(t *testing.T) {
+	testCases := map[string]struct {
+		includesPattern  string
+		excludesPattern  string
+		expectedIncludes string
+		expectedExcludes string
+		expectedError    string
+	}{
+		"spaced": {
+			includesPattern:  "com/acme/** , org/acme/**",
+			excludesPattern:  " com/acme/internal/**",
+			expectedIncludes: "com/acme/**,org/acme/**",
+			expectedExcludes: "com/acme/internal/**",
+		},
+		"clean": {
+			includesPattern:  "com/acme/**,org/acme/**",
+			excludesPattern:  "com/acme/internal/**",
+			expectedIncludes: "com/acme/**,org/acme/**",
+			expectedExcludes: "com/acme/internal/**",
+		},
+		"trailing comma": {
+			includesPattern: "com/acme/**",
+			excludesPattern: "com/acme/internal/**,",
+			expectedError:   `excludes_pattern: pattern 2 of "com/acme/internal/**," is empty`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, sent := mockRepositories(t, map[string]string{})
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			config := map[string]interface{}{
+				"key":              "foo",
+				"includes_pattern": testCase.includesPattern,
+				"excludes_pattern": testCase.excludesPattern,
+			}
+
+			_, err := repoResource.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), restyClient)
+			if testCase.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+					t.Fatalf("expected error %q, got %v", testCase.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, config)
+			if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if sent["foo"]["includesPattern"] != testCase.expectedIncludes || sent["foo"]["excludesPattern"] != testCase.expectedExcludes {
+				t.Fatalf("expected patterns %q and %q to be sent, got %v and %v", testCase.expectedIncludes, testCase.expectedExcludes, sent["foo"]["includesPattern"], sent["foo"]["excludesPattern"])
+			}
+
+			// the patterns stored by Artifactory don't plan a change back to the spaced configuration
+			planned, err := repoResource.Diff(context.Background(), d.State(), terraform.NewResourceConfigRaw(config), restyClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if planned != nil && len(planned.Attributes) > 0 {
+				t.Fatalf("expected no changes after the create, got %v", planned.Attributes)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryAllExcludingPatterns(t *testing.T) {
+	testCases := map[string]struct {
+		config          map[string]interface{}
+		expectedPattern string
+	}{
+		"exclude all":                {map[string]interface{}{"excludes_pattern": "com/google/**,**/*"}, "**/*"},
+		"exclude all included":       {map[string]interface{}{"includes_pattern": "com/jfrog/**,org/jfrog/**", "excludes_pattern": "org/jfrog/**,com/**"}, "com/**, org/jfrog/**"},
+		"exclude all included lists": {map[string]interface{}{"includes_patterns": []interface{}{"com/jfrog/**"}, "excludes_patterns": []interface{}{"com/jfrog/**"}}, "com/jfrog/**"},
+		"non-overlapping":            {map[string]interface{}{"includes_pattern": "com/jfrog/**", "excludes_pattern": "com/google/**"}, ""},
+		"partially excluded":         {map[string]interface{}{"includes_pattern": "com/jfrog/**,org/jfrog/**", "excludes_pattern": "com/**"}, ""},
+		"default include":            {map[string]interface{}{"excludes_pattern": "com/**"}, ""},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, _ := mockRepositories(t, map[string]string{})
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			config := map[string]interface{}{"key": "foo"}
+			for attribute, value := range testCase.config {
+				config[attribute] = value
+			}
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, config)
+
+			diags := repoResource.CreateContext(context.Background(), d, restyClient)
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			var warnings diag.Diagnostics
+			for _, diagnostic := range diags {
+				if diagnostic.Summary == "Exclude patterns exclude every included artifact" {
+					warnings = append(warnings, diagnostic)
+				}
+			}
+			if testCase.expectedPattern == "" {
+				if len(warnings) != 0 {
+					t.Fatalf("expected no warning, got %v", warnings)
+				}
+				return
+			}
+			if len(warnings) != 1 || !strings.Contains(warnings[0].Detail, "The exclude patterns "+testCase.expectedPattern+" of") {
+				t.Fatalf("expected a warning naming %s, got %v", testCase.expectedPattern, diags)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryEffectiveIncludesPatternFallback(t *testing.T) {
+	testCases := map[string]struct {
+		config   map[string]interface{}
+		expected string
+	}{
+		"configured":      {map[string]interface{}{"includes_pattern": "com/acme/**"}, "com/acme/**"},
+		"configured list": {map[string]interface{}{"includes_patterns": []interface{}{"com/acme/**", "org/acme/**"}}, "com/acme/**,org/acme/**"},
+		"default":         {map[string]interface{}{}, "**/*"},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet {
+					return
+				}
+				// an Artifactory that doesn't return the resolved patterns
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"key":"foo","rclass":"virtual","packageType":"generic"}`))
+			}))
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			config := map[string]interface{}{"key": "foo"}
+			for attribute, value := range testCase.config {
+				config[attribute] = value
+			}
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, config)
+
+			if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if effective := d.Get("effective_includes_pattern"); effective != testCase.expected {
+				t.Fatalf("expected the configured include patterns %q, got %q", testCase.expected, effective)
+			}
+			if effective := d.Get("effective_excludes_pattern"); effective != "" {
+				t.Fatalf("expected no effective exclude patterns, got %q", effective)
+			}
+		})
+	}
+}
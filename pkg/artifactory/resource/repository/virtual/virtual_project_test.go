@@ -0,0 +1,761 @@
+package virtual_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-log/tfsdklog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+func TestValidateProjectKey(t *testing.T) {
+	testCases := map[string]struct {
+		projectKey    string
+		expectedError string
+	}{
+		"valid":            {"proj42", ""},
+		"uppercase":        {"MyProj", `project_key "MyProj" contains the uppercase letter 'M', use "myproj" instead`},
+		"too short":        {"pr", `project_key "pr" is 2 characters long`},
+		"too long":         {"projectkey11", `project_key "projectkey11" is 12 characters long`},
+		"non-alphanumeric": {"my-proj", `project_key "my-proj" contains the character '-'`},
+		"empty":            {"", `project_key "" is 0 characters long`},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			diags := repository.ValidateProjectKey(testCase.projectKey, cty.GetAttrPath("project_key"))
+			if testCase.expectedError == "" {
+				if diags.HasError() {
+					t.Fatalf("expected %q to be valid, got %v", testCase.projectKey, diags)
+				}
+				return
+			}
+			if !diags.HasError() {
+				t.Fatalf("expected %q to be rejected", testCase.projectKey)
+			}
+			detail := diags[0].Detail
+			if !strings.Contains(detail, testCase.expectedError) || !strings.Contains(detail, "project keys are 3 to 10 lowercase letters or digits") {
+				t.Fatalf("expected error containing %q and the rule, got %q", testCase.expectedError, detail)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryWarnsOnProjectEnvironmentsWithoutProject(t *testing.T) {
+	testCases := map[string]struct {
+		projectKey      string
+		expectedWarning bool
+	}{
+		"project key removed": {"", true},
+		"project key set":     {"proj", false},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, _ := mockRepositories(t, map[string]string{
+				"proj-virtual": `{"key":"proj-virtual","rclass":"virtual","packageType":"generic","projectKey":"proj","environments":["DEV"]}`,
+			})
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+				"key":                  "proj-virtual",
+				"project_key":          testCase.projectKey,
+				"project_environments": []interface{}{"DEV"},
+			})
+			d.SetId("proj-virtual")
+
+			diags := repoResource.UpdateContext(context.Background(), d, restyClient)
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			warned := false
+			for _, diagnostic := range diags {
+				if diagnostic.Severity == diag.Warning && diagnostic.Summary == "Project environments ignored" {
+					warned = true
+				}
+			}
+			if warned != testCase.expectedWarning {
+				t.Fatalf("expected warning to be %t, got %v", testCase.expectedWarning, diags)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryImportProjectScopedKey(t *testing.T) {
+	testCases := map[string]struct {
+		expectedKey        string
+		expectedProjectKey string
+		expectedError      bool
+	}{
+		"proj-virtual":      {"proj-virtual", "", false},
+		"proj:proj-virtual": {"proj-virtual", "proj", false},
+		"proj:other":        {expectedError: true},
+		"proj:":             {expectedError: true},
+		":proj-virtual":     {expectedError: true},
+		"a:b:c":             {expectedError: true},
+	}
+
+	for id, testCase := range testCases {
+		t.Run(id, func(t *testing.T) {
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			d := repoResource.Data(nil)
+			d.SetId(id)
+
+			fake := &fakeRepositoryClient{repos: map[string][]byte{"proj-virtual": []byte(`{"key":"proj-virtual","rclass":"virtual"}`)}}
+			imported, err := repoResource.Importer.StateContext(context.Background(), d, fake)
+			if testCase.expectedError {
+				if err == nil {
+					t.Fatalf("expected import ID %q to be rejected", id)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(imported) != 1 || imported[0].Id() != testCase.expectedKey {
+				t.Fatalf("expected ID %q, got %v", testCase.expectedKey, imported)
+			}
+			if key := imported[0].Get("key"); testCase.expectedProjectKey != "" && key != testCase.expectedKey {
+				t.Fatalf("expected key %q, got %q", testCase.expectedKey, key)
+			}
+			if projectKey := imported[0].Get("project_key"); projectKey != testCase.expectedProjectKey {
+				t.Fatalf("expected project_key %q, got %q", testCase.expectedProjectKey, projectKey)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryUnassignsRemovedProject(t *testing.T) {
+	repos := map[string]map[string]interface{}{}
+	unassigned := []string{}
+	restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/"+repository.ProjectAttachEndpoint) {
+			if r.Method != http.MethodDelete {
+				t.Errorf("expected the project to be unassigned with DELETE, got %s", r.Method)
+			}
+			key := strings.TrimPrefix(r.URL.Path, "/"+repository.ProjectAttachEndpoint)
+			unassigned = append(unassigned, key)
+			repos[key]["projectKey"] = ""
+			return
+		}
+
+		key := strings.TrimPrefix(r.URL.Path, "/"+repository.RepositoriesEndpoint)
+		switch r.Method {
+		case http.MethodPut, http.MethodPost:
+			body := map[string]interface{}{}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("failed to decode request body: %s", err)
+			}
+			// like Artifactory, an emptied projectKey doesn't remove the assignment
+			if current, ok := repos[key]; ok && body["projectKey"] == "" {
+				body["projectKey"] = current["projectKey"]
+			}
+			repos[key] = body
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(repos[key])
+		}
+	}))
+
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+		"key":         "proj-generic",
+		"project_key": "proj",
+	})
+	if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if d.Get("project_key") != "proj" {
+		t.Fatalf("expected the repository to be assigned to proj, got %q", d.Get("project_key"))
+	}
+
+	// the update receives the removal as part of the planned diff
+	state := d.State()
+	planned, err := repoResource.Diff(context.Background(), state, terraform.NewResourceConfigRaw(map[string]interface{}{"key": "proj-generic"}), restyClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err = schema.InternalMap(repoResource.Schema).Data(state, planned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags := repoResource.UpdateContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if !reflect.DeepEqual(unassigned, []string{"proj-generic"}) {
+		t.Fatalf("expected proj-generic to be unassigned from its project, got %v", unassigned)
+	}
+	if repos["proj-generic"]["projectKey"] != "" || d.Get("project_key") != "" {
+		t.Fatalf("expected no project after the update, got %q in Artifactory and %q in state", repos["proj-generic"]["projectKey"], d.Get("project_key"))
+	}
+
+	// unchanged project assignments don't call the Access API
+	if diags := repoResource.UpdateContext(context.Background(), repoResource.Data(d.State()), restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if len(unassigned) != 1 {
+		t.Fatalf("expected a single unassign call, got %v", unassigned)
+	}
+}
+
+func TestVirtualRepositoryAvailableEnvironments(t *testing.T) {
+	restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/access/api/v1/projects/proj/environments":
+			_, _ = w.Write([]byte(`[{"name":"DEV"},{"name":"PROD"},{"name":"proj-QA"}]`))
+		case "/" + repository.RepositoriesEndpoint + "proj-generic":
+			_, _ = w.Write([]byte(`{"key":"proj-generic","rclass":"virtual","packageType":"generic","projectKey":"proj","environments":["DEV"]}`))
+		case "/" + repository.RepositoriesEndpoint + "foo-generic":
+			_, _ = w.Write([]byte(`{"key":"foo-generic","rclass":"virtual","packageType":"generic"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+
+	testCases := map[string]struct {
+		key      string
+		expected []interface{}
+	}{
+		"project-scoped": {"proj-generic", []interface{}{"DEV", "PROD", "proj-QA"}},
+		"no project":     {"foo-generic", []interface{}{}},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": testCase.key})
+			d.SetId(testCase.key)
+
+			if diags := repoResource.ReadContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if available := d.Get("available_environments"); !reflect.DeepEqual(available, testCase.expected) {
+				t.Fatalf("expected available environments %v, got %v", testCase.expected, available)
+			}
+		})
+	}
+
+	// the environments are read through the Access API, a RepositoryClient can't read them
+	fake := &fakeRepositoryClient{repos: map[string][]byte{"proj-generic": []byte(`{"key":"proj-generic","rclass":"virtual","packageType":"generic","projectKey":"proj"}`)}}
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": "proj-generic"})
+	d.SetId("proj-generic")
+	diags := repoResource.ReadContext(context.Background(), d, fake)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "available_environments needs the Artifactory API") {
+		t.Fatalf("expected the read of the environments to fail, got %v", diags)
+	}
+}
+
+func TestVirtualRepositoryProjectEnvironmentsOrdering(t *testing.T) {
+	config := map[string]interface{}{
+		"key":                  "proj-generic",
+		"project_key":          "proj",
+		"project_environments": []interface{}{"PROD", "DEV"},
+	}
+
+	for name, returned := range map[string][]interface{}{"sorted": {"DEV", "PROD"}, "reversed": {"PROD", "DEV"}} {
+		t.Run(name, func(t *testing.T) {
+			repos := map[string]string{}
+			restyClient, sent := mockRepositories(t, repos)
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, config)
+			if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if environments := sent["proj-generic"]["environments"]; !reflect.DeepEqual(environments, []interface{}{"DEV", "PROD"}) {
+				t.Fatalf("expected the environments to be sent sorted, got %v", environments)
+			}
+
+			// Artifactory returns the stored configuration, with the environments in either order
+			stored := sent["proj-generic"]
+			stored["environments"] = returned
+			encoded, _ := json.Marshal(stored)
+			repos["proj-generic"] = string(encoded)
+			if diags := repoResource.ReadContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			planned, err := repoResource.Diff(context.Background(), d.State(), terraform.NewResourceConfigRaw(config), restyClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if planned != nil && !planned.Empty() {
+				t.Fatalf("expected a clean second plan, got %v", planned)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryProjectKeyChange(t *testing.T) {
+	testCases := map[string]struct {
+		from, to        string
+		replace         bool
+		expectedNew     bool
+		expectedWarning bool
+	}{
+		"empty to set":              {from: "", to: "proja"},
+		"set to empty":              {from: "proja", to: ""},
+		"set to different":          {from: "proja", to: "projb", expectedWarning: true},
+		"set to different replaced": {from: "proja", to: "projb", replace: true, expectedNew: true},
+		"empty to set replaced":     {from: "", to: "proja", replace: true},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, _ := mockRepositories(t, map[string]string{})
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			config := func(projectKey string) map[string]interface{} {
+				return map[string]interface{}{
+					"key":                           "foo",
+					"project_key":                   projectKey,
+					"replace_on_project_key_change": testCase.replace,
+				}
+			}
+
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, config(testCase.from))
+			if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			state := d.State()
+			planned, err := repoResource.Diff(context.Background(), state, terraform.NewResourceConfigRaw(config(testCase.to)), restyClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if planned.RequiresNew() != testCase.expectedNew {
+				t.Fatalf("expected replacement %t, got %v", testCase.expectedNew, planned)
+			}
+			if testCase.expectedNew {
+				return
+			}
+
+			d, err = schema.InternalMap(repoResource.Schema).Data(state, planned)
+			if err != nil {
+				t.Fatal(err)
+			}
+			diags := repoResource.UpdateContext(context.Background(), d, restyClient)
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			warned := false
+			for _, diagnostic := range diags {
+				warned = warned || diagnostic.Summary == "Repository reassigned to another project in place"
+			}
+			if warned != testCase.expectedWarning {
+				t.Fatalf("expected reassignment warning %t, got %v", testCase.expectedWarning, diags)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryMembersOfOtherProjects(t *testing.T) {
+	members := []interface{}{"projb-libs-local", "proja-libs-local", "libs-remote"}
+	restyClient, sent := mockRepositories(t, map[string]string{
+		"projb-libs-local": `{"key":"projb-libs-local","rclass":"local","packageType":"generic","projectKey":"projb"}`,
+		"proja-libs-local": `{"key":"proja-libs-local","rclass":"local","packageType":"generic","projectKey":"proja"}`,
+		"libs-remote":      `{"key":"libs-remote","rclass":"remote","packageType":"generic"}`,
+	})
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	config := map[string]interface{}{
+		"key":          "proja-libs",
+		"project_key":  "proja",
+		"repositories": members,
+	}
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, config)
+	if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if !reflect.DeepEqual(sent["proja-libs"]["repositories"], members) {
+		t.Fatalf("expected the member keys to be sent verbatim, got %v", sent["proja-libs"]["repositories"])
+	}
+	if !reflect.DeepEqual(d.Get("repositories"), members) {
+		t.Fatalf("expected the member keys to be read back verbatim, got %v", d.Get("repositories"))
+	}
+
+	planned, err := repoResource.Diff(context.Background(), d.State(), terraform.NewResourceConfigRaw(config), restyClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if planned != nil && !planned.Empty() {
+		t.Fatalf("expected a clean plan, got %v", planned)
+	}
+}
+
+func TestVirtualRepositoryClearedProjectEnvironments(t *testing.T) {
+	repos := map[string]string{}
+	restyClient, sent := mockRepositories(t, repos)
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	config := map[string]interface{}{
+		"key":         "proj-generic",
+		"project_key": "proj",
+	}
+
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, config)
+	if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	// unset environments are sent as null, leaving the defaults of Artifactory
+	if environments, ok := sent["proj-generic"]["environments"]; !ok || environments != nil {
+		t.Fatalf("expected unset environments to be sent as null, got %v", sent["proj-generic"])
+	}
+
+	for _, step := range []struct {
+		environments []interface{}
+		expected     interface{}
+	}{
+		{[]interface{}{"PROD"}, []interface{}{"PROD"}},
+		{[]interface{}{}, []interface{}{}},
+	} {
+		config["project_environments"] = step.environments
+		planned, err := repoResource.Diff(context.Background(), d.State(), terraform.NewResourceConfigRaw(config), restyClient)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err = schema.InternalMap(repoResource.Schema).Data(d.State(), planned)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diags := repoResource.UpdateContext(context.Background(), d, restyClient); diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+
+		if environments := sent["proj-generic"]["environments"]; !reflect.DeepEqual(environments, step.expected) {
+			t.Fatalf("expected the environments %v to be sent, got %v", step.expected, environments)
+		}
+		stored := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(repos["proj-generic"]), &stored); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(stored["environments"], step.expected) {
+			t.Fatalf("expected the server to store the environments %v, got %v", step.expected, stored["environments"])
+		}
+		if environments := d.Get("project_environments").(*schema.Set).Len(); environments != len(step.environments) {
+			t.Fatalf("expected %d environments in the state, got %d", len(step.environments), environments)
+		}
+	}
+}
+
+func TestVirtualRepositoryProjectMembersWarning(t *testing.T) {
+	testCases := map[string]struct {
+		repositories    []interface{}
+		expectedWarning string
+	}{
+		"project repository listed": {[]interface{}{"proj-libs-local", "shared-remote"}, "repositories lists proj-libs-local, assigned to the project proj of the virtual repository"},
+		"no project repository":     {[]interface{}{"shared-remote"}, ""},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			listings := 0
+			restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case r.URL.Path == "/artifactory/api/repositories" && r.URL.Query().Get("project") == "proj":
+					listings++
+					_, _ = w.Write([]byte(`[{"key":"proj-libs-local","type":"LOCAL"},{"key":"proj-generic","type":"VIRTUAL"}]`))
+				case r.URL.Path == "/access/api/v1/projects/proj":
+					_, _ = w.Write([]byte(`{"project_key":"proj"}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			// the provider root logger writes to os.Stderr, captured when the logger is created
+			logFile, err := os.CreateTemp(t.TempDir(), "tflog")
+			if err != nil {
+				t.Fatal(err)
+			}
+			stderr := os.Stderr
+			os.Stderr = logFile
+			ctx := tfsdklog.NewRootProviderLogger(context.Background())
+			os.Stderr = stderr
+
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":          "proj-generic",
+				"project_key":  "proj",
+				"repositories": testCase.repositories,
+			})
+			// the second plan is served from the cache
+			for i := 0; i < 2; i++ {
+				if _, err := repoResource.SimpleDiff(ctx, nil, config, restyClient); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			}
+			if listings != 1 {
+				t.Fatalf("expected the repositories of the project to be listed once, got %d", listings)
+			}
+
+			logs, err := os.ReadFile(logFile.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var warnings []string
+			for _, line := range strings.Split(strings.TrimSpace(string(logs)), "\n") {
+				entry := map[string]interface{}{}
+				if line == "" || json.Unmarshal([]byte(line), &entry) != nil {
+					continue
+				}
+				if entry["@level"] == "warn" && strings.HasPrefix(fmt.Sprint(entry["@message"]), "repositories lists") {
+					warnings = append(warnings, entry["@message"].(string))
+				}
+			}
+			if testCase.expectedWarning == "" {
+				if len(warnings) != 0 {
+					t.Fatalf("expected no warning, got %v", warnings)
+				}
+				return
+			}
+			if len(warnings) == 0 || !strings.HasPrefix(warnings[0], testCase.expectedWarning) {
+				t.Fatalf("expected a warning starting with %q, got:\n%s", testCase.expectedWarning, logs)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryProjectExists(t *testing.T) {
+	testCases := map[string]struct {
+		projectKey      string
+		expectedError   string
+		expectedLookups int
+	}{
+		"existing project": {"proj", "", 1},
+		"missing project":  {"nope", "project 'nope' does not exist", 2},
+		"no project":       {"", "", 0},
+		// credentials that can't read projects don't fail the plan
+		"forbidden": {"secret", "", 2},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			lookups := 0
+			restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasPrefix(r.URL.Path, "/access/api/v1/projects/") {
+					lookups++
+				}
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case "/access/api/v1/projects/proj":
+					_, _ = w.Write([]byte(`{"project_key":"proj","display_name":"Project"}`))
+				case "/access/api/v1/projects/secret":
+					w.WriteHeader(http.StatusForbidden)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`{"errors":[{"code":"NOT_FOUND","message":"Could not find project"}]}`))
+				}
+			}))
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":         testCase.projectKey + "-generic",
+				"project_key": testCase.projectKey,
+			})
+
+			// the second plan is served from the cache when the project exists. Like Terraform, SimpleDiff plans a new
+			// resource once, Diff runs CustomizeDiff again
+			for i := 0; i < 2; i++ {
+				_, err := repoResource.SimpleDiff(context.Background(), nil, config, restyClient)
+				if testCase.expectedError != "" {
+					if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+						t.Fatalf("expected error to contain %s, got %v", testCase.expectedError, err)
+					}
+				} else if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			}
+			if lookups != testCase.expectedLookups {
+				t.Fatalf("expected %d lookups, got %d", testCase.expectedLookups, lookups)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryProjectEnvironmentsDefinedInProject(t *testing.T) {
+	testCases := map[string]struct {
+		projectKey    string
+		environments  []interface{}
+		expectedError string
+	}{
+		"custom environment":    {"proj", []interface{}{"proj-QA"}, ""},
+		"global environment":    {"proj", []interface{}{"PROD"}, ""},
+		"undefined environment": {"proj", []interface{}{"proj-UAT", "proj-STAGING"}, "project_environment proj-STAGING, proj-UAT not allowed, the environments of project proj are: DEV, PROD, proj-QA"},
+		"no project":            {"", []interface{}{"proj-QA"}, "project_environment proj-QA not allowed"},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			lookups := 0
+			restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case "/access/api/v1/projects/proj":
+					_, _ = w.Write([]byte(`{"project_key":"proj","display_name":"Project"}`))
+				case "/access/api/v1/projects/proj/environments":
+					lookups++
+					_, _ = w.Write([]byte(`[{"name":"DEV"},{"name":"PROD"},{"name":"proj-QA"}]`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":                  "proj-generic",
+				"project_key":          testCase.projectKey,
+				"project_environments": testCase.environments,
+			})
+
+			// the environments of the project are listed once per provider run
+			for i := 0; i < 2; i++ {
+				_, err := repoResource.SimpleDiff(context.Background(), nil, config, restyClient)
+				if testCase.expectedError != "" {
+					if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+						t.Fatalf("expected error to contain %s, got %v", testCase.expectedError, err)
+					}
+				} else if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			}
+			expectedLookups := 1
+			if testCase.projectKey == "" {
+				expectedLookups = 0
+			}
+			if lookups != expectedLookups {
+				t.Fatalf("expected %d lookups of the project environments, got %d", expectedLookups, lookups)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryWithoutEnvironmentsSupport(t *testing.T) {
+	repos := map[string][]byte{}
+	var writes []map[string]interface{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/"+repository.RepositoriesEndpoint)
+		switch r.Method {
+		case http.MethodPut, http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			fields := map[string]interface{}{}
+			if err := json.Unmarshal(body, &fields); err != nil {
+				t.Errorf("failed to decode request body: %s", err)
+			}
+			writes = append(writes, fields)
+			// older versions of Artifactory don't know the field
+			if _, ok := fields["environments"]; ok {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":[{"status":400,"message":"Unrecognized field \"environments\" (class org.artifactory.api.rest.repo.VirtualRepositoryConfiguration), not marked as ignorable"}]}`))
+				return
+			}
+			repos[key] = body
+		case http.MethodGet:
+			repo, ok := repos[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(repo)
+		}
+	})
+	restyClient := acctest.NewMockClient(t, handler)
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	ctx := context.Background()
+
+	unsupportedWarning := func(diags diag.Diagnostics) string {
+		for _, diagnostic := range diags {
+			if diagnostic.Severity == diag.Warning && diagnostic.Summary == "Artifactory doesn't support environments" {
+				return diagnostic.Detail
+			}
+		}
+		return ""
+	}
+
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+		"key":                  "foo-virtual",
+		"project_key":          "myproj",
+		"project_environments": []interface{}{"DEV"},
+	})
+	diags := repoResource.CreateContext(ctx, d, restyClient)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if detail := unsupportedWarning(diags); !strings.Contains(detail, "foo-virtual was created") || !strings.Contains(detail, "DEV") {
+		t.Fatalf("expected a warning that the environments of foo-virtual aren't assigned, got %v", diags)
+	}
+	if len(writes) != 2 {
+		t.Fatalf("expected the create to be retried once, got %d writes", len(writes))
+	}
+	if _, ok := writes[1]["environments"]; ok || writes[1]["projectKey"] != "myproj" {
+		t.Fatalf("expected the retry to only leave out environments, got %v", writes[1])
+	}
+
+	// the result is cached, later writes leave the field out up front. Artifactory didn't store the environments, so
+	// the next plan sets them again
+	if err := d.Set("project_environments", []interface{}{"DEV"}); err != nil {
+		t.Fatal(err)
+	}
+	diags = repoResource.UpdateContext(ctx, d, restyClient)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if len(writes) != 3 || unsupportedWarning(diags) == "" {
+		t.Fatalf("expected a single update and a warning, got %d writes and %v", len(writes)-2, diags)
+	}
+
+	other := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": "bar-virtual"})
+	diags = repoResource.CreateContext(ctx, other, restyClient)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if len(writes) != 4 || unsupportedWarning(diags) != "" {
+		t.Fatalf("expected a single create without warning for a repository without environments, got %d writes and %v", len(writes)-3, diags)
+	}
+
+	// the first write of another provider run has the empty field rejected, nothing is lost so nothing is warned about
+	writes = nil
+	restyClient = acctest.NewMockClient(t, handler)
+	first := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": "baz-virtual"})
+	diags = repoResource.CreateContext(ctx, first, restyClient)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if len(writes) != 2 || unsupportedWarning(diags) != "" {
+		t.Fatalf("expected the create to be retried without warning for a repository without environments, got %d writes and %v", len(writes), diags)
+	}
+}
+
+func TestVirtualRepositoryUnassignWithFakeClient(t *testing.T) {
+	fake := &fakeRepositoryClient{repos: map[string][]byte{
+		"proj-foo": []byte(`{"key":"proj-foo","rclass":"virtual","packageType":"generic","projectKey":"proj"}`),
+	}}
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	state := &terraform.InstanceState{
+		ID: "proj-foo",
+		Attributes: map[string]string{
+			"id":          "proj-foo",
+			"key":         "proj-foo",
+			"project_key": "proj",
+		},
+	}
+	d, _ := planWithRawConfig(t, repoResource, state, map[string]interface{}{"key": "proj-foo"}, fake)
+
+	// the project assignment is only reachable through the Access API, the update must not skip it
+	diags := repoResource.UpdateContext(context.Background(), d, fake)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "needs the Artifactory API") {
+		t.Fatalf("expected the unassign to fail without the Artifactory API, got %v", diags)
+	}
+	if !reflect.DeepEqual(fake.calls, []string{"get proj-foo"}) {
+		t.Fatalf("expected nothing to be written, got %v", fake.calls)
+	}
+}
@@ -0,0 +1,84 @@
+package virtual_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+func TestVirtualRepositoryRequestsCanRetrieveRemoteArtifactsDefault(t *testing.T) {
+	enabled, disabled := true, false
+	testCases := map[string]struct {
+		repoResource    *schema.Resource
+		providerDefault *bool
+		config          map[string]interface{}
+		expected        bool
+		expectedSent    interface{}
+	}{
+		"generic":                       {virtual.ResourceArtifactoryVirtualGenericRepository("generic"), nil, map[string]interface{}{}, false, nil},
+		"docker":                        {virtual.ResourceArtifactoryVirtualDockerRepository(), nil, map[string]interface{}{}, true, true},
+		"docker overridden":             {virtual.ResourceArtifactoryVirtualDockerRepository(), nil, map[string]interface{}{"artifactory_requests_can_retrieve_remote_artifacts": false}, false, nil},
+		"generic overridden":            {virtual.ResourceArtifactoryVirtualGenericRepository("generic"), nil, map[string]interface{}{"artifactory_requests_can_retrieve_remote_artifacts": true}, true, true},
+		"generic inherits provider":     {virtual.ResourceArtifactoryVirtualGenericRepository("generic"), &enabled, map[string]interface{}{}, true, true},
+		"generic overrides provider":    {virtual.ResourceArtifactoryVirtualGenericRepository("generic"), &enabled, map[string]interface{}{"artifactory_requests_can_retrieve_remote_artifacts": false}, false, nil},
+		"docker ignores provider":       {virtual.ResourceArtifactoryVirtualDockerRepository(), &disabled, map[string]interface{}{}, true, true},
+		"npm inherits provider":         {virtual.ResourceArtifactoryVirtualNpmRepository(), &enabled, map[string]interface{}{}, true, true},
+		"npm provider default disabled": {virtual.ResourceArtifactoryVirtualNpmRepository(), &disabled, map[string]interface{}{}, false, nil},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, sent := mockRepositories(t, map[string]string{})
+			repository.SetProviderSettings(restyClient, repository.ProviderSettings{DefaultRequestsCanRetrieveRemoteArtifacts: testCase.providerDefault})
+			config := map[string]interface{}{"key": "foo"}
+			for attribute, value := range testCase.config {
+				config[attribute] = value
+			}
+
+			d, planned := planWithRawConfig(t, testCase.repoResource, nil, config, restyClient)
+			if value := planned.Attributes["artifactory_requests_can_retrieve_remote_artifacts"]; value == nil || value.NewComputed || value.New != fmt.Sprint(testCase.expected) {
+				t.Fatalf("expected artifactory_requests_can_retrieve_remote_artifacts %t to be planned, got %v", testCase.expected, value)
+			}
+			if diags := testCase.repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			if sent["foo"]["artifactoryRequestsCanRetrieveRemoteArtifacts"] != testCase.expectedSent {
+				t.Fatalf("expected artifactoryRequestsCanRetrieveRemoteArtifacts %v to be sent, got %v", testCase.expectedSent, sent["foo"])
+			}
+			if value := d.Get("artifactory_requests_can_retrieve_remote_artifacts"); value != testCase.expected {
+				t.Fatalf("expected artifactory_requests_can_retrieve_remote_artifacts %t in the state, got %v", testCase.expected, value)
+			}
+
+			_, planned = planWithRawConfig(t, testCase.repoResource, d.State(), config, restyClient)
+			if planned != nil && !planned.Empty() {
+				t.Fatalf("expected a clean plan, got %v", planned)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryRequestsCanRetrieveRemoteArtifactsKeptOnUpgrade(t *testing.T) {
+	repoResource := virtual.ResourceArtifactoryVirtualDockerRepository()
+	// a docker repository created before the type default, with the attribute unset
+	state := &terraform.InstanceState{
+		ID: "foo",
+		Attributes: map[string]string{
+			"id":  "foo",
+			"key": "foo",
+			"artifactory_requests_can_retrieve_remote_artifacts": "false",
+		},
+	}
+
+	_, planned := planWithRawConfig(t, repoResource, state, map[string]interface{}{"key": "foo"}, acctest.NewMockClient(t, http.NotFoundHandler()))
+	if value, ok := planned.Attributes["artifactory_requests_can_retrieve_remote_artifacts"]; ok && value.New != "false" {
+		t.Fatalf("expected the existing repository to keep false, got %v", value)
+	}
+}
@@ -0,0 +1,429 @@
+package virtual_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+func TestVirtualRepositoryRepoLayoutRefIgnoresCase(t *testing.T) {
+	testCases := map[string]struct {
+		configured    string
+		expectedDrift bool
+	}{
+		"same casing":      {"maven-2-default", false},
+		"different casing": {"Maven-2-Default", false},
+		"different layout": {"simple-default", true},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			repoResource := virtual.ResourceArtifactoryVirtualJavaRepository("maven")
+			state := &terraform.InstanceState{
+				ID: "foo-virtual",
+				Attributes: map[string]string{
+					"id":              "foo-virtual",
+					"key":             "foo-virtual",
+					"repo_layout_ref": "maven-2-default",
+				},
+			}
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":             "foo-virtual",
+				"repo_layout_ref": testCase.configured,
+			})
+
+			diff, err := repoResource.Diff(context.Background(), state, config, acctest.NewMockClient(t, http.NotFoundHandler()))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			_, drift := diff.Attributes["repo_layout_ref"]
+			if drift != testCase.expectedDrift {
+				t.Fatalf("expected repo_layout_ref drift to be %t, got %v", testCase.expectedDrift, diff)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryRepoLayoutExists(t *testing.T) {
+	testCases := map[string]struct {
+		layoutRef       string
+		layoutsReadable bool
+		expectedError   string
+	}{
+		"existing layout":      {"maven-2-default", true, ""},
+		"different casing":     {"Maven-2-Default", true, ""},
+		"unknown layout":       {"does-not-exist", true, "repo_layout_ref does-not-exist is not a repository layout of the instance, the available layouts are: simple-default, maven-2-default"},
+		"layouts not readable": {"does-not-exist", false, ""},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			repos := map[string]string{}
+			restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/artifactory/api/system/configuration" {
+					if !testCase.layoutsReadable {
+						w.WriteHeader(http.StatusForbidden)
+						return
+					}
+					w.Header().Set("Content-Type", "application/xml")
+					_, _ = w.Write([]byte(`<config><repoLayouts>
+						<repoLayout><name>simple-default</name></repoLayout>
+						<repoLayout><name>maven-2-default</name></repoLayout>
+					</repoLayouts></config>`))
+					return
+				}
+
+				key := strings.TrimPrefix(r.URL.Path, "/"+repository.RepositoriesEndpoint)
+				switch r.Method {
+				case http.MethodPut:
+					body, _ := io.ReadAll(r.Body)
+					repos[key] = string(body)
+				case http.MethodGet:
+					repo, ok := repos[key]
+					if !ok {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					w.Header().Set("Content-Type", "application/json")
+					_, _ = w.Write([]byte(repo))
+				}
+			}))
+
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+				"key":             "foo",
+				"repo_layout_ref": testCase.layoutRef,
+			})
+
+			diags := repoResource.CreateContext(context.Background(), d, restyClient)
+			if testCase.expectedError == "" {
+				if diags.HasError() {
+					t.Fatalf("unexpected error: %v", diags)
+				}
+				if _, ok := repos["foo"]; !ok {
+					t.Fatal("expected the repository to be created")
+				}
+				return
+			}
+
+			if !diags.HasError() || diags[0].Detail != testCase.expectedError {
+				t.Fatalf("expected error %q, got %v", testCase.expectedError, diags)
+			}
+			if _, ok := repos["foo"]; ok {
+				t.Fatal("expected the repository not to be created")
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryRepoLayoutPatterns(t *testing.T) {
+	configReads := 0
+	restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/artifactory/api/system/configuration":
+			configReads++
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte(`<config>
+				<repoLayouts>
+					<repoLayout>
+						<name>simple-default</name>
+						<artifactPathPattern>[orgPath]/[module]/[module]-[baseRev].[ext]</artifactPathPattern>
+						<distinctiveDescriptorPathPattern>false</distinctiveDescriptorPathPattern>
+					</repoLayout>
+					<repoLayout>
+						<name>maven-2-default</name>
+						<artifactPathPattern>[orgPath]/[module]/[baseRev](-[folderItegRev])/[module]-[baseRev](-[fileItegRev])(-[classifier]).[ext]</artifactPathPattern>
+						<distinctiveDescriptorPathPattern>true</distinctiveDescriptorPathPattern>
+						<descriptorPathPattern>[orgPath]/[module]/[baseRev](-[folderItegRev])/[module]-[baseRev](-[fileItegRev])(-[classifier]).pom</descriptorPathPattern>
+					</repoLayout>
+				</repoLayouts>
+			</config>`))
+		case "/" + repository.RepositoriesEndpoint + "foo-virtual":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"key":"foo-virtual","rclass":"virtual","packageType":"maven","repoLayoutRef":"Maven-2-Default"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	repoResource := virtual.ResourceArtifactoryVirtualJavaRepository("maven")
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": "foo-virtual"})
+	d.SetId("foo-virtual")
+	if diags := repoResource.ReadContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if configReads != 0 || len(d.Get("repo_layout_patterns").([]interface{})) != 0 {
+		t.Fatalf("expected the layouts not to be read unless opted in, got %d reads and %v", configReads, d.Get("repo_layout_patterns"))
+	}
+
+	d = schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": "foo-virtual", "read_repo_layout_patterns": true})
+	d.SetId("foo-virtual")
+	// the system configuration is read once, the second refresh is served from the cache
+	for i := 0; i < 2; i++ {
+		if diags := repoResource.ReadContext(context.Background(), d, restyClient); diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+	}
+	if configReads != 1 {
+		t.Fatalf("expected the system configuration to be read once, got %d", configReads)
+	}
+
+	// opting in needs the Artifactory API
+	diags := repoResource.ReadContext(context.Background(), d, &fakeRepositoryClient{repos: map[string][]byte{
+		"foo-virtual": []byte(`{"key":"foo-virtual","rclass":"virtual","packageType":"maven","repoLayoutRef":"Maven-2-Default"}`),
+	}})
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "read_repo_layout_patterns needs the Artifactory API") {
+		t.Fatalf("expected the read to fail without the Artifactory API, got %v", diags)
+	}
+
+	expected := []interface{}{map[string]interface{}{
+		"artifact_path_pattern":               "[orgPath]/[module]/[baseRev](-[folderItegRev])/[module]-[baseRev](-[fileItegRev])(-[classifier]).[ext]",
+		"distinctive_descriptor_path_pattern": true,
+		"descriptor_path_pattern":             "[orgPath]/[module]/[baseRev](-[folderItegRev])/[module]-[baseRev](-[fileItegRev])(-[classifier]).pom",
+	}}
+	if !reflect.DeepEqual(d.Get("repo_layout_patterns"), expected) {
+		t.Fatalf("expected repo_layout_patterns %v, got %v", expected, d.Get("repo_layout_patterns"))
+	}
+}
+
+func TestVirtualRepositoryEmptyRepoLayoutRefMatchesDefault(t *testing.T) {
+	testCases := map[string]struct {
+		repoResource  *schema.Resource
+		serverLayout  string
+		expectedDrift bool
+	}{
+		"maven":               {virtual.ResourceArtifactoryVirtualJavaRepository("maven"), "maven-2-default", false},
+		"npm":                 {virtual.ResourceArtifactoryVirtualRepositoryWithRetrievalCachePeriodSecs("npm"), "npm-default", false},
+		"generic":             {virtual.ResourceArtifactoryVirtualGenericRepository("generic"), "simple-default", false},
+		"generic non-default": {virtual.ResourceArtifactoryVirtualGenericRepository("generic"), "maven-2-default", true},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			state := &terraform.InstanceState{
+				ID: "foo-virtual",
+				Attributes: map[string]string{
+					"id":              "foo-virtual",
+					"key":             "foo-virtual",
+					"repo_layout_ref": testCase.serverLayout,
+				},
+			}
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":             "foo-virtual",
+				"repo_layout_ref": "",
+			})
+
+			diff, err := testCase.repoResource.Diff(context.Background(), state, config, acctest.NewMockClient(t, http.NotFoundHandler()))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			_, drift := diff.Attributes["repo_layout_ref"]
+			if drift != testCase.expectedDrift {
+				t.Fatalf("expected repo_layout_ref drift to be %t, got %v", testCase.expectedDrift, diff)
+			}
+		})
+	}
+}
+
+func TestValidateRepoLayoutRef(t *testing.T) {
+	testCases := map[string]bool{
+		"":                         false,
+		repository.NoRepoLayoutRef: false,
+		"maven-2-default":          false,
+		"custom.layout_1":          false,
+		"maven 2 default":          true,
+		"layouts/custom":           true,
+		"-leading-dash":            true,
+	}
+
+	for layoutRef, expectedError := range testCases {
+		t.Run(layoutRef, func(t *testing.T) {
+			diags := repository.ValidateRepoLayoutRef(layoutRef, cty.GetAttrPath("repo_layout_ref"))
+			if diags.HasError() != expectedError {
+				t.Fatalf("expected error: %t, got: %v", expectedError, diags)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryNoRepoLayoutRef(t *testing.T) {
+	restyClient, sent := mockRepositories(t, map[string]string{})
+	repoResource := virtual.ResourceArtifactoryVirtualRepositoryWithRetrievalCachePeriodSecs("npm")
+	config := map[string]interface{}{
+		"key":             "foo-npm",
+		"repo_layout_ref": repository.NoRepoLayoutRef,
+	}
+
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, config)
+	if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if sent["foo-npm"]["repoLayoutRef"] != repository.NoRepoLayoutRef {
+		t.Fatalf("expected %s to be sent, got %v", repository.NoRepoLayoutRef, sent["foo-npm"]["repoLayoutRef"])
+	}
+	if layoutRef := d.Get("repo_layout_ref"); layoutRef != repository.NoRepoLayoutRef {
+		t.Fatalf("expected %s to be read back, got %v", repository.NoRepoLayoutRef, layoutRef)
+	}
+
+	planned, err := repoResource.Diff(context.Background(), d.State(), terraform.NewResourceConfigRaw(config), restyClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if planned != nil && !planned.Empty() {
+		t.Fatalf("expected a clean second plan, got %v", planned.Attributes)
+	}
+}
+
+func TestVirtualRepositoryExplicitRepoLayoutRef(t *testing.T) {
+	testCases := map[string]struct {
+		repoResource  *schema.Resource
+		explicit      string
+		defaultLayout string
+	}{
+		"generic":  {virtual.ResourceArtifactoryVirtualGenericRepository("generic"), "maven-2-default", "simple-default"},
+		"maven":    {virtual.ResourceArtifactoryVirtualJavaRepository("maven"), "simple-default", "maven-2-default"},
+		"npm":      {virtual.ResourceArtifactoryVirtualNpmRepository(), "simple-default", "npm-default"},
+		"composer": {virtual.ResourceArtifactoryVirtualComposerRepository(), "simple-default", "composer-default"},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, sent := mockRepositories(t, map[string]string{})
+			config := map[string]interface{}{"key": "foo", "repo_layout_ref": testCase.explicit}
+			d := schema.TestResourceDataRaw(t, testCase.repoResource.Schema, config)
+			if diags := testCase.repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if sent["foo"]["repoLayoutRef"] != testCase.explicit {
+				t.Fatalf("expected the create to send %s, got %v", testCase.explicit, sent["foo"]["repoLayoutRef"])
+			}
+			if d.Get("repo_layout_ref") != testCase.explicit {
+				t.Fatalf("expected %s to be read back, got %v", testCase.explicit, d.Get("repo_layout_ref"))
+			}
+
+			// the package type is computed, the layout must not be planned back to the default of the package type
+			state := d.State()
+			planned, err := testCase.repoResource.Diff(context.Background(), state, terraform.NewResourceConfigRaw(config), restyClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if planned != nil && !planned.Empty() {
+				t.Fatalf("expected a clean plan, got %v", planned)
+			}
+
+			// omitted, the layout falls back to the default of the package type
+			planned, err = testCase.repoResource.Diff(context.Background(), state, terraform.NewResourceConfigRaw(map[string]interface{}{"key": "foo"}), restyClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if attribute, ok := planned.Attributes["repo_layout_ref"]; !ok || attribute.New != testCase.defaultLayout {
+				t.Fatalf("expected repo_layout_ref to change to %s, got %v", testCase.defaultLayout, planned.Attributes["repo_layout_ref"])
+			}
+			d, err = schema.InternalMap(testCase.repoResource.Schema).Data(state, planned)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diags := testCase.repoResource.UpdateContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if sent["foo"]["repoLayoutRef"] != testCase.defaultLayout {
+				t.Fatalf("expected the update to send %s, got %v", testCase.defaultLayout, sent["foo"]["repoLayoutRef"])
+			}
+		})
+	}
+}
+
+func TestAccVirtualRepository_explicitRepoLayoutRef(t *testing.T) {
+	_, fqrn, name := acctest.MkNames("virtual-npm-repo", "artifactory_virtual_npm_repository")
+	config := acctest.ExecuteTemplate("TestAccVirtualRepository_explicitRepoLayoutRef", `
+		resource "artifactory_virtual_npm_repository" "{{ .name }}" {
+		  key             = "{{ .name }}"
+		  repo_layout_ref = "simple-default"
+		}
+	`, map[string]interface{}{"name": name})
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { acctest.PreCheck(t) },
+		ProviderFactories: acctest.ProviderFactories,
+		CheckDestroy:      acctest.CheckRepoDestroy(t, fqrn),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(fqrn, "package_type", "npm"),
+					resource.TestCheckResourceAttr(fqrn, "repo_layout_ref", "simple-default"),
+				),
+			},
+			{
+				Config:   config,
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestVirtualRepositoryLayoutImmutablePackageTypes(t *testing.T) {
+	testCases := map[string]struct {
+		repoResource  *schema.Resource
+		packageType   string
+		expectedError bool
+	}{
+		"layout-immutable": {virtual.ResourceArtifactoryVirtualDockerRepository(), "docker", true},
+		"mutable":          {virtual.ResourceArtifactoryVirtualGenericRepository("generic"), "generic", false},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, sent := mockRepositories(t, map[string]string{
+				"foo": fmt.Sprintf(`{"key":"foo","rclass":"virtual","packageType":%q,"repoLayoutRef":"simple-default"}`, testCase.packageType),
+			})
+			state := &terraform.InstanceState{ID: "foo", Attributes: map[string]string{
+				"id":              "foo",
+				"key":             "foo",
+				"package_type":    testCase.packageType,
+				"repo_layout_ref": "simple-default",
+			}}
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":             "foo",
+				"repo_layout_ref": "custom-layout",
+			})
+
+			planned, err := testCase.repoResource.Diff(context.Background(), state, config, restyClient)
+			if testCase.expectedError {
+				if err == nil || !strings.Contains(err.Error(), "repo_layout_ref of the docker virtual repository foo can't change from simple-default to custom-layout") {
+					t.Fatalf("expected the plan to fail, got %v and %v", err, planned)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if planned.RequiresNew() {
+				t.Fatalf("expected no replacement, got %v", planned)
+			}
+
+			d, err := schema.InternalMap(testCase.repoResource.Schema).Data(state, planned)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diags := testCase.repoResource.UpdateContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if layout := sent["foo"]["repoLayoutRef"]; layout != "custom-layout" {
+				t.Fatalf("expected the layout to be updated in place, got %v", layout)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package virtual_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hashicorp/go-cty/cty"
+	ctyjson "github.com/hashicorp/go-cty/cty/json"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+func TestValidateRetrievalCachePeriodSecs(t *testing.T) {
+	path := cty.GetAttrPath("retrieval_cache_period_seconds")
+
+	testCases := []struct {
+		period      int
+		hasError    bool
+		hasWarnings bool
+	}{
+		{period: -1, hasError: true},
+		{period: 0},
+		{period: 1, hasWarnings: true},
+		{period: virtual.MinEffectiveRetrievalCachePeriodSecs - 1, hasWarnings: true},
+		{period: virtual.MinEffectiveRetrievalCachePeriodSecs},
+		{period: 7200},
+		{period: virtual.DefaultMaxRetrievalCachePeriodSecs + 1},
+		{period: math.MaxInt32},
+		{period: math.MaxInt32 + 1, hasError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%d", tc.period), func(t *testing.T) {
+			diags := virtual.ValidateRetrievalCachePeriodSecs(tc.period, path)
+
+			if diags.HasError() != tc.hasError {
+				t.Fatalf("expected error: %t, got: %v", tc.hasError, diags)
+			}
+
+			hasWarnings := false
+			for _, d := range diags {
+				if d.Severity == diag.Warning {
+					hasWarnings = true
+				}
+			}
+			if hasWarnings != tc.hasWarnings {
+				t.Fatalf("expected warning: %t, got: %v", tc.hasWarnings, diags)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryZeroRetrievalCachePeriod(t *testing.T) {
+	restyClient, sent := mockRepositories(t, map[string]string{})
+	repoResource := virtual.ResourceArtifactoryVirtualRepositoryWithRetrievalCachePeriodSecs("npm")
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+		"key":                            "foo-npm",
+		"retrieval_cache_period_seconds": 0,
+	})
+
+	if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	// json numbers decode to float64
+	if period, ok := sent["foo-npm"]["virtualRetrievalCachePeriodSecs"]; !ok || period != float64(0) {
+		t.Fatalf("expected virtualRetrievalCachePeriodSecs 0 to be sent, got %v", sent["foo-npm"])
+	}
+	if period := d.Get("retrieval_cache_period_seconds"); period != 0 {
+		t.Fatalf("expected retrieval_cache_period_seconds to be read back as 0, got %v", period)
+	}
+}
+
+func TestAccVirtualRepository_zeroRetrievalCachePeriod(t *testing.T) {
+	_, fqrn, name := acctest.MkNames("virtual-npm-repo", "artifactory_virtual_npm_repository")
+	config := acctest.ExecuteTemplate("TestAccVirtualRepository_zeroRetrievalCachePeriod", `
+		resource "artifactory_virtual_npm_repository" "{{ .name }}" {
+		  key                            = "{{ .name }}"
+		  retrieval_cache_period_seconds = 0
+		}
+	`, map[string]interface{}{"name": name})
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { acctest.PreCheck(t) },
+		ProviderFactories: acctest.ProviderFactories,
+		CheckDestroy:      acctest.VerifyDeleted(fqrn, acctest.CheckRepo),
+
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check:  resource.TestCheckResourceAttr(fqrn, "retrieval_cache_period_seconds", "0"),
+			},
+			{
+				Config:   config,
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestVirtualRepositoryMaxRetrievalCachePeriod(t *testing.T) {
+	const configured = 24 * 60 * 60
+	testCases := map[string]struct {
+		limit         int
+		period        int
+		expectedError bool
+	}{
+		"zero":                {0, 0, false},
+		"normal":              {0, 7200, false},
+		"at the default max":  {0, virtual.DefaultMaxRetrievalCachePeriodSecs, false},
+		"over the default":    {0, virtual.DefaultMaxRetrievalCachePeriodSecs + 1, true},
+		"at the configured":   {configured, configured, false},
+		"over the configured": {configured, configured + 1, true},
+		"raised max":          {2 * virtual.DefaultMaxRetrievalCachePeriodSecs, virtual.DefaultMaxRetrievalCachePeriodSecs + 1, false},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient := resty.New()
+			repository.SetProviderSettings(restyClient, repository.ProviderSettings{MaxRetrievalCachePeriodSecs: testCase.limit})
+			repoResource := virtual.ResourceArtifactoryVirtualRepositoryWithRetrievalCachePeriodSecs("conda")
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":                            "foo-conda",
+				"retrieval_cache_period_seconds": testCase.period,
+			})
+
+			_, err := repoResource.Diff(context.Background(), nil, config, restyClient)
+			if testCase.expectedError && (err == nil || !strings.Contains(err.Error(), fmt.Sprintf("is %d seconds, the provider allows at most", testCase.period))) {
+				t.Fatalf("expected an error naming the period, got %v", err)
+			}
+			if !testCase.expectedError && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryRetrievalCachePeriodUpdatesInPlace(t *testing.T) {
+	testCases := map[string]*schema.Resource{
+		"chef":  virtual.ResourceArtifactoryVirtualChefRepository(),
+		"conda": virtual.ResourceArtifactoryVirtualRepositoryWithRetrievalCachePeriodSecs("conda"),
+		"cran":  virtual.ResourceArtifactoryVirtualCranRepository(),
+		"npm":   virtual.ResourceArtifactoryVirtualNpmRepository(),
+	}
+
+	for name, repoResource := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, sent := mockRepositories(t, map[string]string{})
+			key := fmt.Sprintf("foo-%s", name)
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+				"key":                            key,
+				"retrieval_cache_period_seconds": 7200,
+			})
+			if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			state := d.State()
+			planned, err := repoResource.Diff(context.Background(), state, terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":                            key,
+				"retrieval_cache_period_seconds": 600,
+			}), restyClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if planned.RequiresNew() {
+				t.Fatalf("expected an in-place update, got a replacement: %v", planned)
+			}
+			if attribute, ok := planned.Attributes["retrieval_cache_period_seconds"]; !ok || attribute.New != "600" {
+				t.Fatalf("expected retrieval_cache_period_seconds to change to 600, got %v", planned.Attributes)
+			}
+
+			d, err = schema.InternalMap(repoResource.Schema).Data(state, planned)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diags := repoResource.UpdateContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if sent[key]["virtualRetrievalCachePeriodSecs"] != float64(600) {
+				t.Fatalf("expected the update to send 600, got %v", sent[key]["virtualRetrievalCachePeriodSecs"])
+			}
+			if d.Get("retrieval_cache_period_seconds") != 600 {
+				t.Fatalf("expected 600 in state, got %v", d.Get("retrieval_cache_period_seconds"))
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryRejectsUnsupportedRetrievalCachePeriod(t *testing.T) {
+	testCases := map[string]struct {
+		repoResource  *schema.Resource
+		period        interface{}
+		expectedError string
+	}{
+		"generic":         {virtual.ResourceArtifactoryVirtualGenericRepository("generic"), 600, "retrieval_cache_period_seconds is set to 600, but it isn't supported for generic virtual repositories"},
+		"generic zero":    {virtual.ResourceArtifactoryVirtualGenericRepository("generic"), 0, "retrieval_cache_period_seconds is set to 0, but it isn't supported for generic virtual repositories"},
+		"generic default": {virtual.ResourceArtifactoryVirtualGenericRepository("generic"), virtual.DefaultRetrievalCachePeriodSecs, ""},
+		"generic unset":   {virtual.ResourceArtifactoryVirtualGenericRepository("generic"), nil, ""},
+		"npm":             {virtual.ResourceArtifactoryVirtualNpmRepository(), 600, ""},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			config := map[string]interface{}{"key": "foo"}
+			if testCase.period != nil {
+				config["retrieval_cache_period_seconds"] = testCase.period
+			}
+			encoded, err := json.Marshal(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			state := &terraform.InstanceState{}
+			state.RawConfig, err = ctyjson.Unmarshal(encoded, testCase.repoResource.CoreConfigSchema().ImpliedType())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = testCase.repoResource.SimpleDiff(context.Background(), state, terraform.NewResourceConfigRaw(config), acctest.NewMockClient(t, http.NotFoundHandler()))
+			if testCase.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+					t.Fatalf("expected error %q, got %v", testCase.expectedError, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryInheritsRetrievalCachePeriod(t *testing.T) {
+	inherited := 3600
+	testCases := map[string]struct {
+		repoResource   *schema.Resource
+		providerPeriod *int
+		config         map[string]interface{}
+		expectedPeriod int
+		expectedSent   interface{}
+	}{
+		"inherited":                  {virtual.ResourceArtifactoryVirtualNpmRepository(), &inherited, map[string]interface{}{}, 3600, float64(3600)},
+		"overridden":                 {virtual.ResourceArtifactoryVirtualNpmRepository(), &inherited, map[string]interface{}{"retrieval_cache_period_seconds": 600}, 600, float64(600)},
+		"overridden with 0":          {virtual.ResourceArtifactoryVirtualNpmRepository(), &inherited, map[string]interface{}{"retrieval_cache_period_seconds": 0}, 0, float64(0)},
+		"provider default unset":     {virtual.ResourceArtifactoryVirtualNpmRepository(), nil, map[string]interface{}{}, virtual.DefaultRetrievalCachePeriodSecs, float64(virtual.DefaultRetrievalCachePeriodSecs)},
+		"doesn't cache metadata":     {virtual.ResourceArtifactoryVirtualGenericRepository("generic"), &inherited, map[string]interface{}{}, virtual.DefaultRetrievalCachePeriodSecs, nil},
+		"generic like with a period": {virtual.ResourceArtifactoryVirtualRepositoryWithRetrievalCachePeriodSecs("conda"), &inherited, map[string]interface{}{}, 3600, float64(3600)},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, sent := mockRepositories(t, map[string]string{})
+			repository.SetProviderSettings(restyClient, repository.ProviderSettings{DefaultRetrievalCachePeriodSecs: testCase.providerPeriod})
+			config := map[string]interface{}{"key": "foo"}
+			for attribute, value := range testCase.config {
+				config[attribute] = value
+			}
+
+			d, planned := planWithRawConfig(t, testCase.repoResource, nil, config, restyClient)
+			if period := planned.Attributes["retrieval_cache_period_seconds"]; period == nil || period.NewComputed || period.New != fmt.Sprint(testCase.expectedPeriod) {
+				t.Fatalf("expected retrieval_cache_period_seconds %d to be planned, got %v", testCase.expectedPeriod, period)
+			}
+			if diags := testCase.repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			if sent["foo"]["virtualRetrievalCachePeriodSecs"] != testCase.expectedSent {
+				t.Fatalf("expected virtualRetrievalCachePeriodSecs %v to be sent, got %v", testCase.expectedSent, sent["foo"])
+			}
+			if period := d.Get("retrieval_cache_period_seconds"); period != testCase.expectedPeriod {
+				t.Fatalf("expected retrieval_cache_period_seconds %d in the state, got %v", testCase.expectedPeriod, period)
+			}
+
+			_, planned = planWithRawConfig(t, testCase.repoResource, d.State(), config, restyClient)
+			if planned != nil && !planned.Empty() {
+				t.Fatalf("expected a clean plan, got %v", planned)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryInheritedRetrievalCachePeriodChange(t *testing.T) {
+	restyClient, _ := mockRepositories(t, map[string]string{})
+	repoResource := virtual.ResourceArtifactoryVirtualNpmRepository()
+	config := map[string]interface{}{"key": "foo"}
+	d, _ := planWithRawConfig(t, repoResource, nil, config, restyClient)
+	if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	inherited := 3600
+	repository.SetProviderSettings(restyClient, repository.ProviderSettings{DefaultRetrievalCachePeriodSecs: &inherited})
+	_, planned := planWithRawConfig(t, repoResource, d.State(), config, restyClient)
+	if planned == nil || planned.RequiresNew() {
+		t.Fatalf("expected an in-place update, got %v", planned)
+	}
+	if period := planned.Attributes["retrieval_cache_period_seconds"]; period == nil || period.Old != "7200" || period.New != "3600" {
+		t.Fatalf("expected retrieval_cache_period_seconds to change from 7200 to 3600, got %v", period)
+	}
+}
@@ -0,0 +1,355 @@
+package virtual_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+// assertParamsRoundTrip checks a repository params struct against the schema of its resource and the configuration
+// document of Artifactory: every `hcl` tag names an attribute, and the JSON fields without one are in unmapped, with the
+// attribute they're unpacked from or "" when no attribute manages them. params, populated with non-zero values, must
+// encode to document and survive a JSON round trip, and a read (pack) of document followed by an update (unpack)
+// through the resource must send every field back unchanged.
+func assertParamsRoundTrip(t *testing.T, repoResource *schema.Resource, params interface{}, document string, unmapped map[string]string) {
+	t.Helper()
+
+	var checkTags func(reflect.Type)
+	checkTags = func(paramsType reflect.Type) {
+		for i := 0; i < paramsType.NumField(); i++ {
+			field := paramsType.Field(i)
+			if field.Anonymous {
+				checkTags(field.Type)
+				continue
+			}
+			jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+			if jsonName == "-" {
+				continue
+			}
+			attribute, untagged := unmapped[jsonName]
+			if hcl := field.Tag.Get("hcl"); hcl != "" {
+				attribute = hcl
+			} else if !untagged {
+				t.Errorf("field %s has no hcl tag and isn't listed as unmapped", field.Name)
+				continue
+			}
+			if _, ok := repoResource.Schema[attribute]; attribute != "" && !ok {
+				t.Errorf("field %s maps to %s, which isn't an attribute of the resource", field.Name, attribute)
+			}
+		}
+	}
+	checkTags(reflect.TypeOf(params))
+
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := reflect.New(reflect.TypeOf(params))
+	if err := json.Unmarshal(encoded, decoded.Interface()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Elem().Interface(), params) {
+		t.Errorf("expected %+v after a JSON round trip, got %+v", params, decoded.Elem().Interface())
+	}
+
+	expected, actual := map[string]interface{}{}, map[string]interface{}{}
+	if err := json.Unmarshal([]byte(document), &expected); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(encoded, &actual); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v to encode to %s, got %s", params, document, encoded)
+	}
+	key := expected["key"].(string)
+	repos := map[string]string{key: document}
+	for _, member := range expected["repositories"].([]interface{}) {
+		repos[member.(string)] = fmt.Sprintf(`{"key":%q,"rclass":"local","packageType":%q}`, member, expected["packageType"])
+	}
+	restyClient, sent := mockRepositories(t, repos)
+
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": key})
+	d.SetId(key)
+	if diags := repoResource.ReadContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error on read: %v", diags)
+	}
+	if diags := repoResource.UpdateContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error on update: %v", diags)
+	}
+	for field, value := range expected {
+		if !reflect.DeepEqual(sent[key][field], value) {
+			t.Errorf("expected %s to be sent back as %v, got %v", field, value, sent[key][field])
+		}
+	}
+}
+
+func TestVirtualRepositoryParamsRoundTrip(t *testing.T) {
+	base := virtual.VirtualRepositoryBaseParams{
+		Key:                   "proj-foo",
+		ProjectKey:            "proj",
+		ProjectEnvironments:   []string{"DEV"},
+		Rclass:                "virtual",
+		PackageType:           "cran",
+		Description:           "description",
+		Notes:                 "notes",
+		IncludesPattern:       "com/acme/**",
+		ExcludesPattern:       "com/acme/internal/**",
+		RepoLayoutRef:         "simple-default",
+		Repositories:          []string{"foo-local"},
+		DefaultDeploymentRepo: "foo-local",
+		ArtifactoryRequestsCanRetrieveRemoteArtifacts: true,
+	}
+	document := `{
+		"key": "proj-foo",
+		"projectKey": "proj",
+		"environments": ["DEV"],
+		"rclass": "virtual",
+		"packageType": "cran",
+		"description": "description",
+		"notes": "notes",
+		"includesPattern": "com/acme/**",
+		"excludesPattern": "com/acme/internal/**",
+		"repoLayoutRef": "simple-default",
+		"repositories": ["foo-local"],
+		"artifactoryRequestsCanRetrieveRemoteArtifacts": true,
+		"defaultDeploymentRepo": "foo-local"`
+	unmapped := map[string]string{
+		"projectKey":   "project_key",
+		"environments": "project_environments",
+		"rclass":       "",
+	}
+
+	t.Run("base", func(t *testing.T) {
+		assertParamsRoundTrip(t, virtual.ResourceArtifactoryVirtualCranRepository(), base, document+"}", unmapped)
+	})
+	t.Run("retrieval cache period", func(t *testing.T) {
+		assertParamsRoundTrip(t, virtual.ResourceArtifactoryVirtualCranRepository(), virtual.VirtualRepositoryBaseParamsWithRetrievalCachePeriodSecs{
+			VirtualRepositoryBaseParams:     base,
+			VirtualRetrievalCachePeriodSecs: 3600,
+		}, document+`, "virtualRetrievalCachePeriodSecs": 3600}`, unmapped)
+	})
+}
+
+func TestVirtualRepositoryUpdatePreservesUnmodeledFields(t *testing.T) {
+	restyClient, sent := mockRepositories(t, map[string]string{
+		"foo": `{"key":"foo","rclass":"virtual","packageType":"generic","description":"old","notes":"stale","unmodeledSetting":true}`,
+	})
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+		"key":         "foo",
+		"description": "new",
+	})
+	d.SetId("foo")
+
+	if diags := repoResource.UpdateContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if sent["foo"]["unmodeledSetting"] != true {
+		t.Fatalf("expected unmodeledSetting to be preserved, got %v", sent["foo"])
+	}
+	if sent["foo"]["description"] != "new" {
+		t.Fatalf("expected description to be updated, got %v", sent["foo"]["description"])
+	}
+	if _, ok := sent["foo"]["notes"]; ok {
+		t.Fatalf("expected notes emptied in Terraform not to be taken from the server, got %v", sent["foo"]["notes"])
+	}
+}
+
+func TestVirtualRepositoryTimestamps(t *testing.T) {
+	const storage = `{"repo":"foo","path":"/","created":"2023-02-13T10:15:30.123Z","lastModified":"2023-03-01T08:00:00.000Z","lastUpdated":"2023-03-02T09:30:00.000Z"}`
+	testCases := map[string]struct {
+		storage             string
+		readTimestamps      bool
+		expectedCreated     string
+		expectedLastUpdated string
+		expectedLookups     int
+	}{
+		"timestamps":          {storage, true, "2023-02-13T10:15:30.123Z", "2023-03-02T09:30:00.000Z", 1},
+		"last modified only":  {`{"repo":"foo","path":"/","created":"2023-02-13T10:15:30.123Z","lastModified":"2023-03-01T08:00:00.000Z"}`, true, "2023-02-13T10:15:30.123Z", "2023-03-01T08:00:00.000Z", 1},
+		"storage unavailable": {"", true, "", "", 2},
+		"not opted in":        {storage, false, "", "", 0},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			lookups := 0
+			restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case "/" + repository.RepositoriesEndpoint + "foo":
+					_, _ = w.Write([]byte(`{"key":"foo","rclass":"virtual","packageType":"generic"}`))
+				case "/artifactory/api/storage/foo":
+					lookups++
+					if testCase.storage == "" {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					_, _ = w.Write([]byte(testCase.storage))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{"key": "foo", "read_timestamps": testCase.readTimestamps})
+			d.SetId("foo")
+
+			// the second refresh is served from the cache, failed lookups aren't cached
+			for i := 0; i < 2; i++ {
+				if diags := repoResource.ReadContext(context.Background(), d, restyClient); diags.HasError() {
+					t.Fatalf("unexpected error: %v", diags)
+				}
+			}
+			if lookups != testCase.expectedLookups {
+				t.Fatalf("expected %d storage lookups, got %d", testCase.expectedLookups, lookups)
+			}
+			if created := d.Get("created"); created != testCase.expectedCreated {
+				t.Fatalf("expected created %q, got %q", testCase.expectedCreated, created)
+			}
+			if lastUpdated := d.Get("last_updated"); lastUpdated != testCase.expectedLastUpdated {
+				t.Fatalf("expected last_updated %q, got %q", testCase.expectedLastUpdated, lastUpdated)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryExtraAttributes(t *testing.T) {
+	restyClient, sent := mockRepositories(t, map[string]string{})
+	repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+	config := map[string]interface{}{
+		"key": "foo",
+		"extra_attributes": map[string]interface{}{
+			"newServerFlag":  "true",
+			"newServerLabel": "blue",
+		},
+	}
+
+	d := schema.TestResourceDataRaw(t, repoResource.Schema, config)
+	if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if sent["foo"]["newServerFlag"] != true || sent["foo"]["newServerLabel"] != "blue" {
+		t.Fatalf("expected the extra attributes to be sent, got %v", sent["foo"])
+	}
+	if sent["foo"]["packageType"] != "generic" {
+		t.Fatalf("expected the managed fields to be sent along, got %v", sent["foo"])
+	}
+	expected := map[string]interface{}{"newServerFlag": "true", "newServerLabel": "blue"}
+	if effective := d.Get("effective_extra_attributes"); !reflect.DeepEqual(effective, expected) {
+		t.Fatalf("expected effective extra attributes %v, got %v", expected, effective)
+	}
+
+	delete(sent, "foo")
+	if diags := repoResource.UpdateContext(context.Background(), d, restyClient); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if sent["foo"]["newServerFlag"] != true {
+		t.Fatalf("expected the update to send the extra attributes, got %v", sent["foo"])
+	}
+
+	// the effective values come from the read response, the configuration isn't read twice
+	fake := &fakeRepositoryClient{repos: map[string][]byte{}}
+	d = schema.TestResourceDataRaw(t, repoResource.Schema, config)
+	if diags := repoResource.CreateContext(context.Background(), d, fake); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if !reflect.DeepEqual(fake.calls, []string{"create foo", "get foo"}) {
+		t.Fatalf("expected a single read after the create, got %v", fake.calls)
+	}
+	if effective := d.Get("effective_extra_attributes"); !reflect.DeepEqual(effective, expected) {
+		t.Fatalf("expected effective extra attributes %v, got %v", expected, effective)
+	}
+
+	d = schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+		"key":              "bar",
+		"extra_attributes": map[string]interface{}{"description": "shadowed"},
+	})
+	diags := repoResource.CreateContext(context.Background(), d, restyClient)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "extra attribute description is managed by the resource") {
+		t.Fatalf("expected managed fields to be rejected, got %v", diags)
+	}
+}
+
+func TestVirtualRepositoryRemovedAttributesAreCleared(t *testing.T) {
+	testCases := map[string]struct {
+		attribute string
+		field     string
+		value     string
+		removed   map[string]interface{}
+	}{
+		"description": {"description", "description", "a description", map[string]interface{}{}},
+		"notes":       {"notes", "notes", "some notes", map[string]interface{}{}},
+		// without repo_layout_ref the package type default is sent, an empty layout is sent empty
+		"repo_layout_ref": {"repo_layout_ref", "repoLayoutRef", "maven-2-default", map[string]interface{}{"repo_layout_ref": ""}},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			restyClient, sent := mockRepositories(t, map[string]string{})
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+				"key":              "foo",
+				testCase.attribute: testCase.value,
+			})
+			if diags := repoResource.CreateContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if sent["foo"][testCase.field] != testCase.value {
+				t.Fatalf("expected the create to send %s, got %v", testCase.value, sent["foo"][testCase.field])
+			}
+
+			config := map[string]interface{}{"key": "foo"}
+			for attribute, value := range testCase.removed {
+				config[attribute] = value
+			}
+			state := d.State()
+			planned, err := repoResource.Diff(context.Background(), state, terraform.NewResourceConfigRaw(config), restyClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d, err = schema.InternalMap(repoResource.Schema).Data(state, planned)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diags := repoResource.UpdateContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			if value, sentEmpty := sent["foo"][testCase.field]; !sentEmpty || value != "" {
+				t.Fatalf("expected the update to send %s empty, got %v", testCase.field, sent["foo"])
+			}
+			if d.Get(testCase.attribute) != "" {
+				t.Fatalf("expected %s to be cleared in state, got %q", testCase.attribute, d.Get(testCase.attribute))
+			}
+
+			// attributes left unset aren't sent empty on other updates
+			state = d.State()
+			planned, err = repoResource.Diff(context.Background(), state, terraform.NewResourceConfigRaw(map[string]interface{}{"key": "foo", "notes": "changed"}), restyClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d, err = schema.InternalMap(repoResource.Schema).Data(state, planned)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diags := repoResource.UpdateContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if value, sentDescription := sent["foo"]["description"]; sentDescription {
+				t.Fatalf("expected description to be omitted, got %v", value)
+			}
+		})
+	}
+}
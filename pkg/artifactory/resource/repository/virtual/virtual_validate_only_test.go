@@ -0,0 +1,149 @@
+package virtual_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/acctest"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository"
+	"github.com/jfrog/terraform-provider-artifactory/v6/pkg/artifactory/resource/repository/virtual"
+)
+
+func TestVirtualRepositoryValidateOnly(t *testing.T) {
+	testCases := map[string]struct {
+		createStatus    int
+		existingID      string
+		expectedError   string
+		expectedDeletes []string
+		expectedID      string
+	}{
+		"create accepted": {http.StatusOK, "", "", []string{"foo"}, "foo"},
+		"create rejected": {http.StatusBadRequest, "", "Repository layout simple-defualt does not exist", nil, ""},
+		"update accepted": {http.StatusOK, "foo", "", []string{"foo"}, "foo"},
+		"update rejected": {http.StatusBadRequest, "foo", "Repository layout simple-defualt does not exist", nil, "foo"},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var requests, deletes []string
+			var created string
+			restyClient := acctest.NewMockClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				key := strings.TrimPrefix(r.URL.Path, "/"+repository.RepositoriesEndpoint)
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == "/artifactory/api/repositories":
+					_, _ = w.Write([]byte(`[]`))
+				case r.Method == http.MethodPut:
+					if testCase.createStatus != http.StatusOK {
+						w.WriteHeader(testCase.createStatus)
+						_, _ = w.Write([]byte(`{"errors":[{"status":400,"message":"Repository layout simple-defualt does not exist"}]}`))
+						return
+					}
+					body, _ := io.ReadAll(r.Body)
+					created = string(body)
+				case r.Method == http.MethodGet && created != "":
+					_, _ = w.Write([]byte(created))
+				case r.Method == http.MethodDelete:
+					deletes = append(deletes, key)
+					created = ""
+				default:
+					w.WriteHeader(http.StatusBadRequest)
+				}
+			}))
+
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			d := schema.TestResourceDataRaw(t, repoResource.Schema, map[string]interface{}{
+				"key":           "foo",
+				"validate_only": true,
+			})
+			d.SetId(testCase.existingID)
+
+			var diags diag.Diagnostics
+			if testCase.existingID == "" {
+				diags = repoResource.CreateContext(context.Background(), d, restyClient)
+			} else {
+				diags = repoResource.UpdateContext(context.Background(), d, restyClient)
+			}
+
+			if testCase.expectedError != "" {
+				if !diags.HasError() || !strings.Contains(diags[len(diags)-1].Summary, testCase.expectedError) {
+					t.Fatalf("expected error to contain %s, got %v", testCase.expectedError, diags)
+				}
+			} else if diags.HasError() || diags[len(diags)-1].Summary != "Repository configuration validated" {
+				t.Fatalf("expected a validation warning, got %v", diags)
+			}
+			if !reflect.DeepEqual(deletes, testCase.expectedDeletes) {
+				t.Fatalf("expected deletes %v, got %v", testCase.expectedDeletes, deletes)
+			}
+			if d.Id() != testCase.expectedID {
+				t.Fatalf("expected id %q, got %q", testCase.expectedID, d.Id())
+			}
+			if created != "" {
+				t.Fatalf("expected no repository to be left behind, got %s", created)
+			}
+
+			// the repository doesn't exist, refreshing or destroying it must not reach Artifactory
+			requests = nil
+			if diags := repoResource.ReadContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected read error: %v", diags)
+			}
+			if diags := repoResource.DeleteContext(context.Background(), d, restyClient); diags.HasError() {
+				t.Fatalf("unexpected delete error: %v", diags)
+			}
+			if len(requests) != 0 {
+				t.Fatalf("expected no requests on read and delete, got %v", requests)
+			}
+		})
+	}
+}
+
+func TestVirtualRepositoryValidateOnlyChange(t *testing.T) {
+	testCases := map[string]struct {
+		state           map[string]string
+		configured      bool
+		expectedReplace bool
+		expectedError   string
+	}{
+		"state without validate_only": {map[string]string{}, false, false, ""},
+		"unset":                       {map[string]string{"validate_only": "true"}, false, true, ""},
+		"kept":                        {map[string]string{"validate_only": "true"}, true, false, ""},
+		"set on existing repository":  {map[string]string{"validate_only": "false"}, true, false, "validate_only can't be set on the existing repository foo-virtual"},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			repoResource := virtual.ResourceArtifactoryVirtualGenericRepository("generic")
+			attributes := map[string]string{"id": "foo-virtual", "key": "foo-virtual"}
+			for attribute, value := range testCase.state {
+				attributes[attribute] = value
+			}
+			state := &terraform.InstanceState{ID: "foo-virtual", Attributes: attributes}
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"key":           "foo-virtual",
+				"validate_only": testCase.configured,
+			})
+
+			diff, err := repoResource.Diff(context.Background(), state, config, acctest.NewMockClient(t, http.NotFoundHandler()))
+			if testCase.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+					t.Fatalf("expected error to contain %s, got %v", testCase.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if replace := diff != nil && diff.RequiresNew(); replace != testCase.expectedReplace {
+				t.Fatalf("expected replace to be %t, got %v", testCase.expectedReplace, diff)
+			}
+		})
+	}
+}